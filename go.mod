@@ -3,6 +3,7 @@ module hooktm
 go 1.22
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/charmbracelet/bubbletea v1.2.4
 	github.com/charmbracelet/lipgloss v1.0.0
 	github.com/evanphx/json-patch/v5 v5.9.0
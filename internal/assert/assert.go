@@ -0,0 +1,258 @@
+// Package assert checks replay responses against expectations, so CI can
+// fail on more than the status class.
+//
+//	status: 2xx,409               # codes, classes and ranges
+//	max_latency: 500ms
+//	headers:
+//	  Content-Type: application/json*   # glob, "*" crossing "/" ("*": present)
+//	body_contains: ['"received":true']
+//	body_matches: ['"id":"evt_\w+"']      # regular expressions
+//	json:
+//	  $.received: "true"                 # JSONPath → glob on the value ("*": exists)
+//
+// Globs match like the store's header filters (SQLite GLOB): "*" is any run
+// of characters, "/" included, "?" one character and [a-z] a class. Empty
+// fields check nothing.
+package assert
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"hooktm/internal/provider"
+	"hooktm/internal/replay"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a set of expectations about a response.
+type Spec struct {
+	Status       string            `yaml:"status" json:"status,omitempty"`
+	MaxLatency   time.Duration     `yaml:"max_latency" json:"max_latency,omitempty"`
+	Headers      map[string]string `yaml:"headers" json:"headers,omitempty"`
+	BodyContains []string          `yaml:"body_contains" json:"body_contains,omitempty"`
+	BodyMatches  []string          `yaml:"body_matches" json:"body_matches,omitempty"`
+	JSON         map[string]string `yaml:"json" json:"json,omitempty"`
+
+	status  replay.StatusSet
+	regexps []*regexp.Regexp
+	globs   map[string]*regexp.Regexp // pattern → compiled glob
+}
+
+// Response is what a replay got back.
+type Response struct {
+	Status  int
+	Latency time.Duration
+	Header  http.Header
+	Body    []byte
+}
+
+// FromResult returns the response a replay got.
+func FromResult(r replay.Result) Response {
+	return Response{
+		Status:  r.StatusCode,
+		Latency: time.Duration(r.DurationMS) * time.Millisecond,
+		Header:  r.ResponseHeader,
+		Body:    r.ResponseBody,
+	}
+}
+
+// Failure is an expectation the response didn't meet.
+type Failure struct {
+	Assertion string `json:"assertion"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s", f.Assertion, f.Expected, f.Actual)
+}
+
+// Load reads and compiles an assertion file.
+func Load(p string) (*Spec, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var s Spec
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	if err := s.Compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return &s, nil
+}
+
+// Compile validates the spec and prepares it for Check. It must be called
+// again after the fields change.
+func (s *Spec) Compile() error {
+	set, err := replay.ParseStatusSet(s.Status)
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+	s.status = set
+	if s.MaxLatency < 0 {
+		return fmt.Errorf("max_latency: negative duration")
+	}
+	s.regexps = s.regexps[:0]
+	for _, expr := range s.BodyMatches {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("body_matches: %w", err)
+		}
+		s.regexps = append(s.regexps, re)
+	}
+	s.globs = map[string]*regexp.Regexp{}
+	for field, m := range map[string]map[string]string{"headers": s.Headers, "json": s.JSON} {
+		for name, pattern := range m {
+			re, err := compileGlob(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: bad glob %q for %s: %w", field, pattern, name, err)
+			}
+			s.globs[pattern] = re
+		}
+	}
+	return nil
+}
+
+// Empty reports whether the spec checks nothing.
+func (s *Spec) Empty() bool {
+	return s == nil || (s.Status == "" && s.MaxLatency == 0 && len(s.Headers) == 0 &&
+		len(s.BodyContains) == 0 && len(s.BodyMatches) == 0 && len(s.JSON) == 0)
+}
+
+// Check returns the expectations r doesn't meet, in a stable order.
+func (s *Spec) Check(r Response) []Failure {
+	if s.Empty() {
+		return nil
+	}
+	var out []Failure
+	if len(s.status) > 0 && !s.status.Match(r.Status) {
+		out = append(out, Failure{Assertion: "status", Expected: s.status.String(), Actual: strconv.Itoa(r.Status)})
+	}
+	if s.MaxLatency > 0 && r.Latency > s.MaxLatency {
+		out = append(out, Failure{Assertion: "latency", Expected: "at most " + s.MaxLatency.String(), Actual: r.Latency.String()})
+	}
+	for _, name := range sortedKeys(s.Headers) {
+		pattern := s.Headers[name]
+		vs, ok := r.Header[http.CanonicalHeaderKey(name)]
+		switch {
+		case !ok:
+			out = append(out, Failure{Assertion: "header " + name, Expected: expected(pattern), Actual: "no header"})
+		case pattern != "*" && !s.glob(pattern, strings.Join(vs, ", ")):
+			out = append(out, Failure{Assertion: "header " + name, Expected: strconv.Quote(pattern), Actual: strconv.Quote(strings.Join(vs, ", "))})
+		}
+	}
+	for _, sub := range s.BodyContains {
+		if !strings.Contains(string(r.Body), sub) {
+			out = append(out, Failure{Assertion: "body contains", Expected: strconv.Quote(sub), Actual: excerpt(r.Body)})
+		}
+	}
+	for _, re := range s.regexps {
+		if !re.Match(r.Body) {
+			out = append(out, Failure{Assertion: "body matches", Expected: "/" + re.String() + "/", Actual: excerpt(r.Body)})
+		}
+	}
+	for _, p := range sortedKeys(s.JSON) {
+		pattern := s.JSON[p]
+		v, ok := provider.BodyValue(r.Header, r.Body, p)
+		switch {
+		case !ok:
+			out = append(out, Failure{Assertion: "json " + p, Expected: expected(pattern), Actual: "no value"})
+		case pattern != "*" && !s.glob(pattern, v):
+			out = append(out, Failure{Assertion: "json " + p, Expected: strconv.Quote(pattern), Actual: strconv.Quote(v)})
+		}
+	}
+	return out
+}
+
+func (s *Spec) glob(pattern, v string) bool {
+	re := s.globs[pattern]
+	return re != nil && re.MatchString(v)
+}
+
+// compileGlob turns a glob into an anchored regular expression. Unlike
+// path.Match, "*" also matches "/", so "*json*" matches application/json
+// and URLs can be globbed. [!a-z] and [^a-z] are negated classes; a
+// backslash escapes the next character.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i++; i == len(rs) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case '[':
+			j := i + 1
+			b.WriteString("[")
+			if j < len(rs) && (rs[j] == '!' || rs[j] == '^') {
+				b.WriteString("^")
+				j++
+			}
+			start := j
+			for ; j < len(rs) && (rs[j] != ']' || j == start); j++ {
+				if rs[j] == '-' {
+					b.WriteRune('-')
+				} else {
+					b.WriteString(regexp.QuoteMeta(string(rs[j])))
+				}
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("unclosed [")
+			}
+			b.WriteString("]")
+			i = j
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.Compile(b.String())
+}
+
+// expected describes a glob in failure messages.
+func expected(pattern string) string {
+	if pattern == "*" {
+		return "any value"
+	}
+	return strconv.Quote(pattern)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// excerpt quotes the start of a body for failure messages.
+func excerpt(b []byte) string {
+	const limit = 120
+	switch {
+	case len(b) == 0:
+		return "an empty body"
+	case !utf8.Valid(b):
+		return fmt.Sprintf("%d bytes of binary", len(b))
+	case len(b) > limit:
+		return strconv.Quote(strings.ToValidUTF8(string(b[:limit]), "")) + "…"
+	}
+	return strconv.Quote(string(b))
+}
@@ -0,0 +1,120 @@
+package assert
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "assert.yaml")
+	err := os.WriteFile(p, []byte(`
+status: 200
+max_latency: 500ms
+headers:
+  content-type: application/json*
+body_contains: ['"received":true']
+body_matches: ['"id":"evt_\w+"']
+json:
+  $.received: "true"
+  data.items[0]: "*"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s.Status != "200" || s.MaxLatency != 500*time.Millisecond || len(s.regexps) != 1 {
+		t.Fatalf("unexpected spec: %+v", s)
+	}
+
+	ok := Response{
+		Status:  200,
+		Latency: 120 * time.Millisecond,
+		Header:  http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		Body:    []byte(`{"received":true,"id":"evt_123","data":{"items":["a"]}}`),
+	}
+	if f := s.Check(ok); len(f) != 0 {
+		t.Fatalf("unexpected failures: %v", f)
+	}
+
+	bad := Response{Status: 503, Latency: time.Second, Header: http.Header{}, Body: []byte(`{"received":false}`)}
+	var got []string
+	for _, f := range s.Check(bad) {
+		got = append(got, f.String())
+	}
+	want := []string{
+		`status: expected 200, got 503`,
+		`latency: expected at most 500ms, got 1s`,
+		`header content-type: expected "application/json*", got no header`,
+		`body contains: expected "\"received\":true", got "{\"received\":false}"`,
+		`body matches: expected /"id":"evt_\w+"/, got "{\"received\":false}"`,
+		`json $.received: expected "true", got "false"`,
+		`json data.items[0]: expected any value, got no value`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("failures:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, s := range []Spec{
+		{Status: "2xx,abc"},
+		{BodyMatches: []string{"("}},
+		{Headers: map[string]string{"X": "["}},
+		{MaxLatency: -time.Second},
+	} {
+		if err := s.Compile(); err == nil {
+			t.Errorf("expected error for %+v", s)
+		}
+	}
+	var empty Spec
+	if err := empty.Compile(); err != nil || !empty.Empty() || empty.Check(Response{Status: 500}) != nil {
+		t.Fatalf("empty spec: %v", err)
+	}
+}
+
+func TestGlob_CrossesSlashes(t *testing.T) {
+	s := Spec{
+		Headers: map[string]string{"Content-Type": "*json*", "Location": "https://*/hooks/[0-9]?"},
+		JSON:    map[string]string{"url": "https://*.example.com/*", "id": "evt_[!x]*"},
+	}
+	if err := s.Compile(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	r := Response{
+		Status: 200,
+		Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}, "Location": {"https://api.test/hooks/42"}},
+		Body:   []byte(`{"url":"https://a.example.com/x/y","id":"evt_1"}`),
+	}
+	if f := s.Check(r); len(f) != 0 {
+		t.Fatalf("unexpected failures: %v", f)
+	}
+	r.Body = []byte(`{"url":"https://example.org/x","id":"evt_x1"}`)
+	if f := s.Check(r); len(f) != 2 {
+		t.Fatalf("expected two failures, got %v", f)
+	}
+	if re, err := compileGlob(`a\*b`); err != nil || re.MatchString("axb") || !re.MatchString("a*b") {
+		t.Fatalf("escaped star: %v", err)
+	}
+}
+
+func TestJSON_NullIsPresent(t *testing.T) {
+	s := Spec{JSON: map[string]string{"$.field": "*", "$.other": "null"}}
+	if err := s.Compile(); err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	r := Response{Status: 200, Header: http.Header{}, Body: []byte(`{"field":null,"other":null}`)}
+	if f := s.Check(r); len(f) != 0 {
+		t.Fatalf("unexpected failures: %v", f)
+	}
+	r.Body = []byte(`{"other":null}`)
+	if f := s.Check(r); len(f) != 1 || f[0].String() != `json $.field: expected any value, got no value` {
+		t.Fatalf("expected missing field failure, got %v", f)
+	}
+}
@@ -0,0 +1,163 @@
+// Package chaos decides which faults to inject while forwarding webhooks, to
+// exercise idempotency and retry handling in the app and at the provider.
+package chaos
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault names recorded with each webhook.
+const (
+	FaultDrop      = "drop"      // not forwarded; the caller's connection is closed
+	FaultError     = "error"     // not forwarded; the caller gets 502
+	FaultLatency   = "latency"   // forwarded after a delay
+	FaultReorder   = "reorder"   // held back so later requests overtake it
+	FaultDuplicate = "duplicate" // forwarded twice
+)
+
+// Policy configures fault injection. Rates are percentages (0-100) rolled
+// independently per request; drop and error exclude the others.
+//
+//	chaos:
+//	  drop: 5
+//	  error: 5
+//	  latency: 500ms
+//	  jitter: 250ms
+//	  duplicate: 10
+//	  reorder: 20
+//	  reorder_window: 2s
+//	  paths: [/stripe/*]
+type Policy struct {
+	Drop      float64       `yaml:"drop"`
+	Error     float64       `yaml:"error"`
+	Latency   time.Duration `yaml:"latency"`
+	Jitter    time.Duration `yaml:"jitter"`
+	Duplicate float64       `yaml:"duplicate"`
+	Reorder   float64       `yaml:"reorder"`
+	// ReorderWindow is how long reordered requests are held back (default 1s).
+	ReorderWindow time.Duration `yaml:"reorder_window"`
+	// Paths limits injection to request paths matching one of these globs.
+	Paths []string `yaml:"paths"`
+}
+
+// Enabled reports whether the policy injects anything at all.
+func (p Policy) Enabled() bool {
+	return p.Drop > 0 || p.Error > 0 || p.Latency > 0 || p.Jitter > 0 || p.Duplicate > 0 || p.Reorder > 0
+}
+
+func (p Policy) validate() error {
+	for name, v := range map[string]float64{"drop": p.Drop, "error": p.Error, "duplicate": p.Duplicate, "reorder": p.Reorder} {
+		if v < 0 || v > 100 {
+			return fmt.Errorf("chaos: %s must be a percentage between 0 and 100, got %v", name, v)
+		}
+	}
+	if p.Drop+p.Error > 100 {
+		return fmt.Errorf("chaos: drop and error add up to more than 100%%")
+	}
+	if p.Latency < 0 || p.Jitter < 0 || p.ReorderWindow < 0 {
+		return fmt.Errorf("chaos: durations must not be negative")
+	}
+	for _, g := range p.Paths {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("chaos: bad path glob %q", g)
+		}
+	}
+	return nil
+}
+
+// Decision is the set of faults chosen for one request.
+type Decision struct {
+	Drop      bool
+	Error     bool
+	Duplicate bool
+	// Delay is waited before forwarding (latency, jitter and reorder hold-back).
+	Delay  time.Duration
+	Faults []string
+}
+
+// String joins the applied faults, e.g. "latency+duplicate"; "" for none.
+func (d Decision) String() string {
+	return strings.Join(d.Faults, "+")
+}
+
+// Injector rolls faults according to a Policy. It is safe for concurrent use.
+type Injector struct {
+	policy Policy
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New validates p and returns an Injector for it.
+func New(p Policy) (*Injector, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.Reorder > 0 && p.ReorderWindow == 0 {
+		p.ReorderWindow = time.Second
+	}
+	return &Injector{policy: p, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}, nil
+}
+
+// Policy returns the effective policy.
+func (in *Injector) Policy() Policy { return in.policy }
+
+// Decide picks the faults for a request to urlPath. A nil Injector injects nothing.
+func (in *Injector) Decide(urlPath string) Decision {
+	var d Decision
+	if in == nil || !in.applies(urlPath) {
+		return d
+	}
+	p := in.policy
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	// One roll for the exclusive faults so drop+error never exceeds 100%.
+	switch roll := in.rand.Float64() * 100; {
+	case roll < p.Drop:
+		d.Drop = true
+		d.Faults = append(d.Faults, FaultDrop)
+		return d
+	case roll < p.Drop+p.Error:
+		d.Error = true
+		d.Faults = append(d.Faults, FaultError)
+		return d
+	}
+
+	if p.Latency > 0 || p.Jitter > 0 {
+		d.Delay = p.Latency
+		if p.Jitter > 0 {
+			d.Delay += time.Duration(in.rand.Int63n(int64(p.Jitter) + 1))
+		}
+		if d.Delay > 0 {
+			d.Faults = append(d.Faults, FaultLatency)
+		}
+	}
+	if in.rand.Float64()*100 < p.Reorder {
+		d.Delay += p.ReorderWindow
+		d.Faults = append(d.Faults, FaultReorder)
+	}
+	if in.rand.Float64()*100 < p.Duplicate {
+		d.Duplicate = true
+		d.Faults = append(d.Faults, FaultDuplicate)
+	}
+	return d
+}
+
+func (in *Injector) applies(urlPath string) bool {
+	if len(in.policy.Paths) == 0 {
+		return true
+	}
+	for _, g := range in.policy.Paths {
+		if ok, _ := path.Match(g, urlPath); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package chaos
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestNew_Validates(t *testing.T) {
+	bad := []Policy{
+		{Drop: 101},
+		{Error: -1},
+		{Drop: 60, Error: 50},
+		{Latency: -time.Second},
+		{Paths: []string{"[a"}},
+	}
+	for _, p := range bad {
+		if _, err := New(p); err == nil {
+			t.Errorf("expected error for %+v", p)
+		}
+	}
+}
+
+func TestDecide(t *testing.T) {
+	in, err := New(Policy{Drop: 100})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if d := in.Decide("/x"); !d.Drop || d.String() != FaultDrop {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+
+	in, _ = New(Policy{Error: 100})
+	if d := in.Decide("/x"); !d.Error || d.Drop || d.String() != FaultError {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+
+	in, _ = New(Policy{Latency: time.Second, Reorder: 100, Duplicate: 100})
+	d := in.Decide("/x")
+	if d.Delay != 2*time.Second || !d.Duplicate || d.String() != "latency+reorder+duplicate" {
+		t.Fatalf("unexpected decision: %+v", d)
+	}
+
+	var none *Injector
+	if d := none.Decide("/x"); d.String() != "" || d.Delay != 0 {
+		t.Fatalf("nil injector injected %+v", d)
+	}
+}
+
+func TestDecide_Rates(t *testing.T) {
+	in, _ := New(Policy{Drop: 20, Error: 30, Duplicate: 50})
+	in.rand = rand.New(rand.NewSource(1))
+
+	counts := map[string]int{}
+	const n = 10000
+	for i := 0; i < n; i++ {
+		d := in.Decide("/x")
+		switch {
+		case d.Drop:
+			counts[FaultDrop]++
+		case d.Error:
+			counts[FaultError]++
+		case d.Duplicate:
+			counts[FaultDuplicate]++
+		}
+	}
+	// Duplicates are only rolled for the 50% that weren't dropped or failed.
+	want := map[string]float64{FaultDrop: 0.20, FaultError: 0.30, FaultDuplicate: 0.25}
+	for k, frac := range want {
+		got := float64(counts[k]) / n
+		if got < frac-0.03 || got > frac+0.03 {
+			t.Errorf("%s rate %.3f, want ~%.2f", k, got, frac)
+		}
+	}
+}
+
+func TestDecide_Paths(t *testing.T) {
+	in, _ := New(Policy{Error: 100, Paths: []string{"/stripe/*"}})
+	if d := in.Decide("/github"); d.Error {
+		t.Fatal("fault injected outside configured paths")
+	}
+	if d := in.Decide("/stripe/live"); !d.Error {
+		t.Fatal("expected fault on matching path")
+	}
+}
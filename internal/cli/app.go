@@ -17,7 +17,15 @@ func NewApp() *cli.App {
 			newListCmd(),
 			newShowCmd(),
 			newReplayCmd(),
+			newReplaysCmd(),
+			newTestCmd(),
+			newVerifyCmd(),
 			newCodegenCmd(),
+			newDiffCmd(),
+			newExportCmd(),
+			newImportCmd(),
+			newDumpCmd(),
+			newRestoreCmd(),
 			newDeleteCmd(),
 			newUICmd(),
 		},
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 
 	"hooktm/internal/config"
+	"hooktm/internal/provider"
+	"hooktm/internal/signature"
 	"hooktm/internal/store"
 
 	"github.com/urfave/cli/v2"
@@ -34,6 +36,43 @@ func openStoreFromContext(c *cli.Context) (*store.Store, *config.Config, error)
 	return s, cfg, nil
 }
 
+// parseHeaderFlags parses repeated --header Name=Value filters.
+func parseHeaderFlags(values []string) ([]store.HeaderMatch, error) {
+	var out []store.HeaderMatch
+	for _, v := range values {
+		m, err := store.ParseHeaderMatch(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// loadProviders returns the built-in providers plus user definitions.
+func loadProviders(cfg *config.Config) (*provider.Registry, error) {
+	dir := cfg.ProvidersDir
+	if dir == "" {
+		dir = provider.DefaultDir()
+	}
+	return provider.LoadRegistry(dir)
+}
+
+// newVerifier builds a signature verifier from config.
+func newVerifier(cfg *config.Config, providers *provider.Registry) *signature.Verifier {
+	return &signature.Verifier{
+		Providers: providers,
+		Secrets:   cfg.Secrets,
+		Tolerance: cfg.SignatureTolerance,
+		PublicURL: cfg.PublicURL,
+	}
+}
+
+// newSigner builds a replay signer from config.
+func newSigner(cfg *config.Config, providers *provider.Registry) *signature.Signer {
+	return &signature.Signer{Providers: providers, Secrets: cfg.Secrets}
+}
+
 func defaultDBPath() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
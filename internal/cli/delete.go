@@ -23,11 +23,13 @@ Examples:
   hooktm delete --older-than 7d           # Delete older than 7 days
   hooktm delete --provider stripe         # Delete all Stripe webhooks
   hooktm delete --status 500              # Delete failed webhooks
+  hooktm delete --header 'User-Agent=curl/*' # Delete by request header
   hooktm delete --older-than 30d --yes    # Skip confirmation`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "older-than", Usage: "Delete webhooks older than duration (e.g., 1d, 7d, 30d)"},
 			&cli.StringFlag{Name: "provider", Usage: "Delete by provider name"},
 			&cli.IntFlag{Name: "status", Usage: "Delete by HTTP status code"},
+			&cli.StringSliceFlag{Name: "header", Usage: "Delete by request header Name=Value (repeatable, * globs)"},
 			&cli.BoolFlag{Name: "yes", Usage: "Skip confirmation prompt"},
 		},
 		Action: runDelete,
@@ -42,11 +44,11 @@ func runDelete(c *cli.Context) error {
 	defer s.Close()
 
 	id := strings.TrimSpace(c.Args().First())
-	hasFilter := c.IsSet("older-than") || c.IsSet("provider") || c.IsSet("status")
+	hasFilter := c.IsSet("older-than") || c.IsSet("provider") || c.IsSet("status") || c.IsSet("header")
 
 	// Validate arguments
 	if id == "" && !hasFilter {
-		return fmt.Errorf("specify an ID or at least one filter (--older-than, --provider, --status)")
+		return fmt.Errorf("specify an ID or at least one filter (--older-than, --provider, --status, --header)")
 	}
 	if id != "" && hasFilter {
 		return fmt.Errorf("cannot use ID and filters together")
@@ -78,6 +80,10 @@ func runDelete(c *cli.Context) error {
 	if c.IsSet("status") {
 		filter.StatusCode = intPtr(c.Int("status"))
 	}
+	filter.Headers, err = parseHeaderFlags(c.StringSlice("header"))
+	if err != nil {
+		return err
+	}
 	if c.IsSet("older-than") {
 		d, err := parseDuration(c.String("older-than"))
 		if err != nil {
@@ -118,6 +124,9 @@ func describeFilter(f store.DeleteFilter) string {
 	if f.StatusCode != nil {
 		parts = append(parts, fmt.Sprintf("status=%d", *f.StatusCode))
 	}
+	for _, m := range f.Headers {
+		parts = append(parts, "header "+m.String())
+	}
 	if len(parts) == 0 {
 		return "(all)"
 	}
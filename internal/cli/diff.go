@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"hooktm/internal/diff"
+	"hooktm/internal/replay"
+	"hooktm/internal/store"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newDiffCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Compare the payloads of two webhooks",
+		ArgsUsage: "<idA> <idB>",
+		Description: `Show a structural diff of two webhook bodies (added, removed and changed
+JSON paths, or form fields) and of their request headers.
+
+Either ID may also be a replay ID (see hooktm replays), which stands for the
+replayed webhook with the replay's patch applied.
+
+Examples:
+  hooktm diff abc123 def456          # Two deliveries of the same event
+  hooktm diff abc123 rpl789          # Original vs. its patched replay
+  hooktm diff abc123 def456 --json`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "json", Usage: "Output as JSON"},
+		},
+		Action: runDiff,
+	}
+}
+
+// diffSide is a webhook, or a replay of one, loaded for comparison.
+type diffSide struct {
+	diff.Side
+	ID          string
+	Description string
+}
+
+func runDiff(c *cli.Context) error {
+	idA, err := requireArg(c, 0, "idA")
+	if err != nil {
+		return err
+	}
+	idB, err := requireArg(c, 1, "idB")
+	if err != nil {
+		return err
+	}
+
+	s, _, err := openStoreFromContext(c)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	a, err := loadDiffSide(c.Context, s, strings.TrimSpace(idA))
+	if err != nil {
+		return err
+	}
+	b, err := loadDiffSide(c.Context, s, strings.TrimSpace(idB))
+	if err != nil {
+		return err
+	}
+	res := diff.Compare(a.Side, b.Side)
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			A string `json:"a"`
+			B string `json:"b"`
+			diff.Result
+		}{a.ID, b.ID, res})
+	}
+
+	_, _ = fmt.Fprintf(c.App.Writer, "--- %s  %s\n", a.ID, a.Description)
+	_, _ = fmt.Fprintf(c.App.Writer, "+++ %s  %s\n", b.ID, b.Description)
+	printChanges(c, "Headers", res.Headers)
+	printChanges(c, "Body", res.Body)
+	return nil
+}
+
+// loadDiffSide loads a webhook by ID, falling back to a replay ID.
+func loadDiffSide(ctx context.Context, s *store.Store, id string) (diffSide, error) {
+	wh, err := s.GetWebhook(ctx, id)
+	if err == nil {
+		side, err := webhookSide(ctx, s, wh)
+		if err != nil {
+			return diffSide{}, err
+		}
+		return diffSide{Side: side, ID: id, Description: describeWebhook(wh)}, nil
+	}
+	rp, rerr := s.GetReplay(ctx, id)
+	if rerr != nil {
+		// Report the webhook lookup; most IDs are webhooks.
+		return diffSide{}, err
+	}
+	wh, err = s.GetWebhook(ctx, rp.WebhookID)
+	if err != nil {
+		return diffSide{}, err
+	}
+	side, err := webhookSide(ctx, s, wh)
+	if err != nil {
+		return diffSide{}, err
+	}
+	// Replays record the method and headers they sent (overridden or
+	// re-signed); older ones sent the webhook's own.
+	if rp.Headers != nil {
+		side.Headers = rp.Headers
+	}
+	desc := "replay of " + wh.ID
+	if rp.Method != "" && rp.Method != wh.Method {
+		desc += " as " + rp.Method
+	}
+	if strings.TrimSpace(rp.Patch) != "" {
+		side.Body, err = replay.ApplyPatch(side.Headers, side.Body, []byte(rp.Patch))
+		if err != nil {
+			return diffSide{}, fmt.Errorf("replay %s: %w", id, err)
+		}
+		desc += " with patch " + rp.Patch
+	}
+	return diffSide{Side: side, ID: id, Description: desc + "  " + formatTimestamp(rp.CreatedAt)}, nil
+}
+
+// webhookSide returns the headers and decoded body of a webhook.
+func webhookSide(ctx context.Context, s *store.Store, wh store.Webhook) (diff.Side, error) {
+	body := wh.PlainBody()
+	if wh.BodySize > 0 {
+		var err error
+		if body, err = s.ReadBody(ctx, wh.ID); err != nil {
+			return diff.Side{}, err
+		}
+	}
+	return diff.Side{Headers: wh.Headers, Body: body}, nil
+}
+
+func describeWebhook(wh store.Webhook) string {
+	parts := []string{wh.Method + " " + wh.Path + formatQuery(wh.Query)}
+	if wh.Provider != "" {
+		parts = append(parts, strings.TrimSpace(wh.Provider+" "+wh.EventType))
+	}
+	parts = append(parts, formatTimestamp(wh.CreatedAt))
+	return strings.Join(parts, "  ")
+}
+
+func printChanges(c *cli.Context, title string, changes []diff.Change) {
+	_, _ = fmt.Fprintf(c.App.Writer, "\n%s:\n", title)
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(c.App.Writer, "  (no differences)")
+		return
+	}
+	for _, ch := range changes {
+		path := defaultString(ch.Path, "(body)")
+		switch ch.Kind {
+		case diff.Added:
+			_, _ = fmt.Fprintf(c.App.Writer, "  + %s: %s\n", path, diff.Format(ch.New))
+		case diff.Removed:
+			_, _ = fmt.Fprintf(c.App.Writer, "  - %s: %s\n", path, diff.Format(ch.Old))
+		default:
+			_, _ = fmt.Fprintf(c.App.Writer, "  ~ %s: %s -> %s\n", path, diff.Format(ch.Old), diff.Format(ch.New))
+		}
+	}
+}
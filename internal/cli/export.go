@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"hooktm/internal/export"
+	"hooktm/internal/store"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newExportCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Export webhooks to Postman, Insomnia, HAR or curl",
+		Description: `Export captured webhooks so they can be sent again without HookTM.
+
+Formats:
+  postman    Postman collection v2.1; the target is the {{baseUrl}} variable
+  insomnia   Insomnia export (v4); the target is _.base_url in the base environment
+  har        HAR 1.2 with the captured responses; binary bodies are base64
+  curl       Shell script, one curl command per webhook; BASE_URL overrides the target
+
+Method, path, query, headers and the raw body are kept. Postman and Insomnia
+can't carry binary bodies: compressed webhooks are exported decoded (without
+Content-Encoding), other binary bodies are left out with a note.
+
+Takes the same filters as list; without --limit every match is exported,
+oldest first.
+
+Examples:
+  hooktm export --format postman --out hooks.postman_collection.json
+  hooktm export --format curl --provider stripe --from 7d --out replay.sh
+  hooktm export --format har --where 'status >= 500' > failures.har
+  hooktm export --format insomnia --base-url https://staging.example.com`,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{Name: "format", Usage: "Export format: postman|insomnia|har|curl", Required: true},
+			&cli.StringFlag{Name: "out", Aliases: []string{"o"}, Usage: "Write to file instead of stdout"},
+			&cli.StringFlag{Name: "base-url", Usage: "Target base URL (default: forward from config, else " + export.DefaultBaseURL + ")"},
+			&cli.StringFlag{Name: "name", Value: "HookTM export", Usage: "Collection or workspace name"},
+			&cli.IntFlag{Name: "limit", Usage: "Maximum number of webhooks (default: all)"},
+		}, listFilterFlags()...),
+		Action: runExport,
+	}
+}
+
+func runExport(c *cli.Context) error {
+	s, cfg, err := openStoreFromContext(c)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	filter, err := listFilterFromFlags(c)
+	if err != nil {
+		return err
+	}
+	webhooks, err := loadWebhooks(c, s, filter, c.Int("limit"))
+	if err != nil {
+		return err
+	}
+
+	opts := export.Options{
+		Name:    c.String("name"),
+		BaseURL: strings.TrimSpace(c.String("base-url")),
+		Version: c.App.Version,
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = cfg.Forward
+	}
+	if opts.BaseURL != "" && !strings.Contains(opts.BaseURL, "://") {
+		opts.BaseURL = "http://" + opts.BaseURL
+	}
+
+	var w io.Writer = c.App.Writer
+	out := strings.TrimSpace(c.String("out"))
+	if out != "" {
+		mode := os.FileMode(0o644)
+		if strings.EqualFold(c.String("format"), "curl") {
+			mode = 0o755
+		}
+		f, err := os.OpenFile(out, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := export.Write(w, c.String("format"), opts, webhooks); err != nil {
+		return err
+	}
+	if out != "" {
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "Exported %d webhook(s) to %s\n", len(webhooks), out)
+	}
+	return nil
+}
+
+// loadWebhooks returns the full webhooks matching filter, oldest first, with
+// chunked bodies read into Body. limit <= 0 means all of them.
+func loadWebhooks(c *cli.Context, s *store.Store, filter store.ListFilter, limit int) ([]store.Webhook, error) {
+	rows, err := matching(c, s, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]store.Webhook, 0, len(rows))
+	for _, r := range rows {
+		wh, err := s.GetWebhook(c.Context, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		if wh.BodySize > 0 {
+			if wh.Body, err = s.ReadBody(c.Context, wh.ID); err != nil {
+				return nil, err
+			}
+			wh.BodySize = 0
+		}
+		out = append(out, wh)
+	}
+	return out, nil
+}
+
+// matching returns the newest limit webhooks matching filter (all of them if
+// limit <= 0), oldest first.
+func matching(c *cli.Context, s *store.Store, filter store.ListFilter, limit int) ([]store.WebhookSummary, error) {
+	var rows []store.WebhookSummary
+	filter.Limit = 500
+	for {
+		page, err := s.ListPage(c.Context, filter)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, page.Rows...)
+		if page.Next == nil || (limit > 0 && len(rows) >= limit) {
+			break
+		}
+		filter.After = page.Next
+	}
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	return rows, nil
+}
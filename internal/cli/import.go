@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hooktm/internal/importer"
+	"hooktm/internal/store"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newImportCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Import webhooks from HAR, curl, raw HTTP, NDJSON or another HookTM database",
+		ArgsUsage: "<file|-> [file...]",
+		Description: `Import requests captured elsewhere as webhooks. Each one goes through
+provider detection, body decoding and search indexing like a recorded
+webhook, and signatures are checked against the time it was received.
+
+Formats (detected from the file name and content unless --format is set):
+  har      HAR 1.2 log, with the recorded responses
+  curl     Shell script or pasted curl command lines (see hooktm export)
+  http     Raw HTTP/1.x requests; several per file separated by ### lines
+  ndjson   Webhooks as JSON, one per line
+  db       Another HookTM database (left unchanged)
+
+Webhooks whose method, path, query and body are already stored are
+skipped, so importing the same file twice is safe.
+
+Examples:
+  hooktm import capture.har
+  hooktm import --format curl - < replay.sh
+  hooktm import ~/backup/hooks.db request.http`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Value: "auto", Usage: "Input format: auto|" + strings.Join(importer.Formats, "|")},
+		},
+		Action: runImport,
+	}
+}
+
+func runImport(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("missing file (use - for stdin)")
+	}
+	s, cfg, err := openStoreFromContext(c)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	providers, err := loadProviders(cfg)
+	if err != nil {
+		return err
+	}
+	im := &importer.Importer{Store: s, Providers: providers, Verifier: newVerifier(cfg, providers)}
+
+	var total importer.Result
+	for _, name := range c.Args().Slice() {
+		webhooks, err := readImport(c, name, c.String("format"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		res, err := im.Import(c.Context, webhooks)
+		total.Imported += res.Imported
+		total.Duplicates += res.Duplicates
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if c.NArg() > 1 {
+			_, _ = fmt.Fprintf(c.App.Writer, "%s: imported %d, skipped %d duplicate(s)\n", name, res.Imported, res.Duplicates)
+		}
+	}
+	_, _ = fmt.Fprintf(c.App.Writer, "Imported %d webhook(s), skipped %d duplicate(s)\n", total.Imported, total.Duplicates)
+	return nil
+}
+
+// readImport reads the webhooks in one input file ("-" is stdin).
+func readImport(c *cli.Context, name, format string) ([]store.Webhook, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if name == "-" {
+		data, err = io.ReadAll(c.App.Reader)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || format == "auto" {
+		if format = importer.Detect(name, data); format == "" {
+			return nil, fmt.Errorf("can't tell the format, use --format")
+		}
+	}
+	if format == "db" {
+		return readDatabase(c, name, data)
+	}
+	return importer.Parse(format, data)
+}
+
+// readDatabase loads every webhook of a HookTM database. It works on a copy,
+// since opening a database upgrades its schema. The write-ahead log is
+// copied along, so webhooks a running listener hasn't checkpointed yet are
+// included.
+func readDatabase(c *cli.Context, name string, data []byte) ([]store.Webhook, error) {
+	dir, err := os.MkdirTemp("", "hooktm-import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "import.db")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, err
+	}
+	if name != "-" {
+		if wal, err := os.ReadFile(name + "-wal"); err == nil {
+			if err := os.WriteFile(path+"-wal", wal, 0o600); err != nil {
+				return nil, err
+			}
+		}
+	}
+	src, err := store.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return loadWebhooks(c, src, store.ListFilter{}, 0)
+}
@@ -23,6 +23,24 @@ Date formats for --from and --to:
   ISO 8601             Full timestamp (e.g., 2024-01-15T10:30:00Z)
   Relative             1d, 7d, 30d, 1h (e.g., --from 7d for last 7 days)
 
+--where takes a filter query: field predicates joined with and/or/not and
+parentheses; plain words and quoted strings are full-text terms.
+
+  Fields:     id method path query provider event fault mock status latency
+              created (2024-01-15, RFC 3339 or an age like 7d) signature
+              (valid, invalid, none)
+  Body:       body.<json.path> (e.g. body.data.object.amount, body.items[0].id)
+              form.<name> for URL-encoded and multipart fields
+  Headers:    header.<name> (case-insensitive, e.g. header.x-github-delivery)
+  Operators:  = != > >= < <= ~ (glob)
+
+--header Name=Value matches a request header exactly (* in the value is a
+glob; a bare Name matches any value) and may be repeated.
+
+--search combines with every other filter. Results are newest first; when
+there are more, the cursor for the next page is printed to stderr. Pass it
+back with --cursor, or use --all to walk the whole history.
+
 Examples:
   hooktm list                                    # Show recent 20 webhooks
   hooktm list --limit 50                         # Show 50 webhooks
@@ -30,33 +48,70 @@ Examples:
   hooktm list --from 7d                          # Last 7 days
   hooktm list --from 2024-01-01 --to 2024-01-31  # Date range
   hooktm list --search "payment"                 # Search body text
+  hooktm list --search refund --provider stripe --from 30d --all
+  hooktm list --header 'X-GitHub-Delivery=72d3162e-cc78-11e3-81ab-4c9367dc0958'
+  hooktm list --where 'provider = stripe and body.data.object.amount > 5000 and status != 200'
+  hooktm list --where 'event ~ "invoice.*" or (refund and created > 1d)'
   hooktm list --json                             # JSON output`,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			&cli.IntFlag{Name: "limit", Value: 20, Usage: "Maximum number of results"},
-			&cli.StringFlag{Name: "provider", Usage: "Filter by provider (stripe, github, etc.)"},
-			&cli.IntFlag{Name: "status", Usage: "Filter by HTTP status code"},
-			&cli.StringFlag{Name: "search", Usage: "Search in webhook body text"},
-			&cli.StringFlag{Name: "from", Usage: "Start date/time"},
-			&cli.StringFlag{Name: "to", Usage: "End date/time"},
+		}, append(listFilterFlags(),
+			&cli.StringFlag{Name: "cursor", Usage: "Continue after this cursor (printed when there are more results)"},
+			&cli.BoolFlag{Name: "all", Usage: "Follow cursors through every page (--limit is the page size)"},
 			&cli.BoolFlag{Name: "json", Usage: "Output as JSON"},
-		},
+		)...),
 		Action: runList,
 	}
 }
 
-func runList(c *cli.Context) error {
-	s, _, err := openStoreFromContext(c)
-	if err != nil {
-		return err
+// listFilterFlags are the filters shared by list and export.
+func listFilterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "provider", Usage: "Filter by provider (stripe, github, etc.)"},
+		&cli.IntFlag{Name: "status", Usage: "Filter by HTTP status code"},
+		&cli.StringFlag{Name: "search", Usage: "Search in webhook body text"},
+		&cli.StringFlag{Name: "where", Usage: "Filter query, e.g. 'provider = stripe and body.amount > 5000'"},
+		&cli.StringSliceFlag{Name: "header", Usage: "Filter by request header Name=Value (repeatable)"},
+		&cli.StringFlag{Name: "from", Usage: "Start date/time"},
+		&cli.StringFlag{Name: "to", Usage: "End date/time"},
 	}
-	defer s.Close()
+}
 
-	limit := c.Int("limit")
+// sequenceFilterFlags are listFilterFlags for replay, whose --to is the
+// target URL: the time window ends at --until instead.
+func sequenceFilterFlags() []cli.Flag {
+	flags := listFilterFlags()
+	for i, f := range flags {
+		if f.Names()[0] == "to" {
+			flags[i] = &cli.StringFlag{Name: "until", Usage: "End date/time"}
+		}
+	}
+	return flags
+}
+
+// hasListFilter reports whether any of flags is set.
+func hasListFilter(c *cli.Context, flags []cli.Flag) bool {
+	for _, f := range flags {
+		if c.IsSet(f.Names()[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// listFilterFromFlags builds a filter from listFilterFlags.
+func listFilterFromFlags(c *cli.Context) (store.ListFilter, error) {
+	return filterFromFlags(c, "to")
+}
+
+// filterFromFlags builds a filter from listFilterFlags, with the end of the
+// time window in toFlag.
+func filterFromFlags(c *cli.Context, toFlag string) (store.ListFilter, error) {
 	filter := store.ListFilter{
-		Limit:    limit,
 		Provider: strings.TrimSpace(c.String("provider")),
+		Search:   strings.TrimSpace(c.String("search")),
+		Where:    strings.TrimSpace(c.String("where")),
 	}
-
 	if c.IsSet("status") {
 		filter.StatusCode = intPtr(c.Int("status"))
 	}
@@ -65,39 +120,74 @@ func runList(c *cli.Context) error {
 	if c.IsSet("from") {
 		t, err := parseTime(c.String("from"), true)
 		if err != nil {
-			return fmt.Errorf("invalid --from: %w", err)
+			return filter, fmt.Errorf("invalid --from: %w", err)
 		}
 		filter.From = t
 	}
-
-	if c.IsSet("to") {
-		t, err := parseTime(c.String("to"), false)
+	if c.IsSet(toFlag) {
+		t, err := parseTime(c.String(toFlag), false)
 		if err != nil {
-			return fmt.Errorf("invalid --to: %w", err)
+			return filter, fmt.Errorf("invalid --%s: %w", toFlag, err)
 		}
 		filter.To = t
 	}
 
-	search := strings.TrimSpace(c.String("search"))
+	var err error
+	filter.Headers, err = parseHeaderFlags(c.StringSlice("header"))
+	return filter, err
+}
 
-	// Execute query
-	var rows []store.WebhookSummary
-	if search != "" {
-		rows, err = s.SearchSummaries(c.Context, search, limit)
-	} else {
-		rows, err = s.ListSummaries(c.Context, filter)
+func runList(c *cli.Context) error {
+	s, _, err := openStoreFromContext(c)
+	if err != nil {
+		return err
 	}
+	defer s.Close()
+
+	filter, err := listFilterFromFlags(c)
 	if err != nil {
 		return err
 	}
+	filter.Limit = c.Int("limit")
+	if c.IsSet("cursor") {
+		filter.After, err = store.ParseCursor(c.String("cursor"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Execute query; --all follows the cursor to the end, printing each page
+	// as it arrives (JSON output is collected into one array).
+	var rows []store.WebhookSummary
+	for {
+		page, err := s.ListPage(c.Context, filter)
+		if err != nil {
+			return err
+		}
+		if c.Bool("json") {
+			rows = append(rows, page.Rows...)
+		} else {
+			printSummaries(c, page.Rows)
+		}
+		if page.Next == nil {
+			break
+		}
+		if !c.Bool("all") {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "More results: --cursor %s\n", page.Next)
+			break
+		}
+		filter.After = page.Next
+	}
 
-	// Output
 	if c.Bool("json") {
 		enc := json.NewEncoder(c.App.Writer)
 		enc.SetIndent("", "  ")
 		return enc.Encode(rows)
 	}
+	return nil
+}
 
+func printSummaries(c *cli.Context, rows []store.WebhookSummary) {
 	for _, r := range rows {
 		ts := formatTimestamp(r.CreatedAt)
 		status := "-"
@@ -108,10 +198,13 @@ func runList(c *cli.Context) error {
 		if prov == "" {
 			prov = "unknown"
 		}
-		_, _ = fmt.Fprintf(c.App.Writer, "%s  %s  %s  %s  [%s]  %dms\n",
+		line := fmt.Sprintf("%s  %s  %s  %s  [%s]  %dms",
 			r.ID, ts, r.Method, r.Path, prov+"/"+status, r.ResponseMS)
+		if sig := signatureLabel(r.SignatureValid); sig != "" {
+			line += "  " + sig
+		}
+		_, _ = fmt.Fprintln(c.App.Writer, line)
 	}
-	return nil
 }
 
 func formatTimestamp(ms int64) string {
@@ -12,6 +12,9 @@ import (
 	"syscall"
 	"time"
 
+	"hooktm/internal/chaos"
+	"hooktm/internal/config"
+	"hooktm/internal/mock"
 	"hooktm/internal/proxy"
 
 	"github.com/urfave/cli/v2"
@@ -27,6 +30,46 @@ func newListenCmd() *cli.Command {
 The server records all incoming requests to the database for later inspection.
 Use --forward to proxy requests to your local development server.
 
+In record-only mode requests get 200 OK, unless a rule in the mock_rules
+file from config matches (status, headers, body template, delay).
+
+Routes in config send webhooks to several services by path prefix, provider
+or event type. Every target receives the webhook; the "respond" target (default
+the first) answers the caller, and each target's status and latency is stored.
+Unmatched webhooks go to --forward, or are recorded only (and answered by
+mock rules).
+
+  routes:
+    - provider: stripe
+      targets: [localhost:4000, localhost:4100]
+      respond: localhost:4000
+    - path: /github
+      event_type: workflow_*
+      targets: [localhost:5000]
+
+Request bodies are limited to 10MB by default. Raise the limit globally or per
+path prefix; larger bodies are streamed to the target while being spooled to
+disk, and stored in chunks so they can still be replayed.
+
+  max_body_size: 10MB
+  body_limits:
+    /exports: 2GB
+
+When forwarding, the chaos policy from config injects faults: dropped
+connections, 502s without forwarding, latency, duplicate deliveries and
+reordering. The faults applied are stored with each webhook.
+
+Config:
+  chaos:
+    drop: 5            # percent of requests
+    error: 5           # 502 without forwarding
+    latency: 500ms
+    jitter: 250ms
+    duplicate: 10
+    reorder: 20
+    reorder_window: 2s
+    paths: [/stripe/*]
+
 Examples:
   hooktm listen 8080                           # Record only
   hooktm listen 8080 --forward localhost:3000  # Proxy to local server
@@ -36,6 +79,10 @@ Examples:
 				Name:  "forward",
 				Usage: "Forward requests to this URL (e.g., localhost:3000 or http://host:port)",
 			},
+			&cli.BoolFlag{
+				Name:  "no-chaos",
+				Usage: "Ignore the chaos policy from config",
+			},
 		},
 		Action: runListen,
 	}
@@ -72,11 +119,48 @@ func runListen(c *cli.Context) error {
 		}
 	}
 
+	providers, err := loadProviders(cfg)
+	if err != nil {
+		return err
+	}
+	recorder := proxy.NewRecorderProxy(targetURL, s)
+	recorder.Providers = providers
+	recorder.Verifier = newVerifier(cfg, providers)
+	recorder.Routes, err = buildRoutes(cfg.Routes)
+	if err != nil {
+		return err
+	}
+	if cfg.MaxBodySize > 0 {
+		recorder.MaxBodySize = int64(cfg.MaxBodySize)
+	}
+	if len(cfg.BodyLimits) > 0 {
+		recorder.BodyLimits = make(map[string]int64, len(cfg.BodyLimits))
+		for prefix, n := range cfg.BodyLimits {
+			recorder.BodyLimits[prefix] = int64(n)
+		}
+	}
+	recorder.SpoolDir = cfg.SpoolDir
+	if cfg.MockRules != "" {
+		rules, err := mock.Load(cfg.MockRules)
+		if err != nil {
+			return err
+		}
+		recorder.Mocks = rules
+	}
+	forwarding := targetURL != nil || len(recorder.Routes) > 0
+	if forwarding && cfg.Chaos.Enabled() && !c.Bool("no-chaos") {
+		injector, err := chaos.New(cfg.Chaos)
+		if err != nil {
+			return err
+		}
+		recorder.Chaos = injector
+	}
+
 	// Start server
 	addr := net.JoinHostPort("", port)
 	srv := &http.Server{
 		Addr:              addr,
-		Handler:           proxy.NewRecorderProxy(targetURL, s),
+		Handler:           recorder,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
@@ -89,6 +173,24 @@ func runListen(c *cli.Context) error {
 	} else {
 		_, _ = fmt.Fprintf(c.App.Writer, "Listening on :%s (record-only)\n", port)
 	}
+	for _, rt := range cfg.Routes {
+		_, _ = fmt.Fprintf(c.App.Writer, "Route %s → %s\n", describeRoute(rt), strings.Join(rt.Targets, ", "))
+	}
+	// Mock rules answer webhooks that go to no target: none when --forward
+	// catches everything, only unrouted ones when routes are configured.
+	if n := recorder.Mocks.Len(); n > 0 {
+		switch {
+		case targetURL != nil:
+			_, _ = fmt.Fprintf(c.App.Writer, "Mock rules ignored while forwarding\n")
+		case len(recorder.Routes) > 0:
+			_, _ = fmt.Fprintf(c.App.Writer, "Mock rules: %d loaded from %s, for webhooks no route matches\n", n, cfg.MockRules)
+		default:
+			_, _ = fmt.Fprintf(c.App.Writer, "Mock rules: %d loaded from %s\n", n, cfg.MockRules)
+		}
+	}
+	if recorder.Chaos != nil {
+		_, _ = fmt.Fprintf(c.App.Writer, "Chaos: %s\n", describeChaos(recorder.Chaos.Policy()))
+	}
 	_, _ = fmt.Fprintf(c.App.Writer, "Press Ctrl+C to stop\n")
 
 	// Graceful shutdown
@@ -105,6 +207,76 @@ func runListen(c *cli.Context) error {
 	return nil
 }
 
+// buildRoutes parses the routing table from config.
+func buildRoutes(routes []config.Route) ([]proxy.Route, error) {
+	out := make([]proxy.Route, 0, len(routes))
+	for i, rt := range routes {
+		if len(rt.Targets) == 0 {
+			return nil, fmt.Errorf("route %d: no targets", i+1)
+		}
+		pr := proxy.Route{PathPrefix: rt.Path, Provider: rt.Provider, EventType: rt.EventType, Respond: -1}
+		for j, t := range rt.Targets {
+			u, err := parseForwardTarget(t)
+			if err != nil {
+				return nil, fmt.Errorf("route %d: %w", i+1, err)
+			}
+			pr.Targets = append(pr.Targets, u)
+			if strings.TrimSpace(rt.Respond) == strings.TrimSpace(t) {
+				pr.Respond = j
+			}
+		}
+		switch {
+		case strings.TrimSpace(rt.Respond) == "":
+			pr.Respond = 0
+		case pr.Respond < 0:
+			return nil, fmt.Errorf("route %d: respond target %q is not one of its targets", i+1, rt.Respond)
+		}
+		out = append(out, pr)
+	}
+	return out, nil
+}
+
+func describeRoute(rt config.Route) string {
+	var parts []string
+	if rt.Path != "" {
+		parts = append(parts, rt.Path+"*")
+	}
+	if rt.Provider != "" {
+		parts = append(parts, "provider="+rt.Provider)
+	}
+	if rt.EventType != "" {
+		parts = append(parts, "event="+rt.EventType)
+	}
+	if len(parts) == 0 {
+		return "(all)"
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeChaos summarises the active chaos policy for the startup banner.
+func describeChaos(p chaos.Policy) string {
+	var parts []string
+	if p.Drop > 0 {
+		parts = append(parts, fmt.Sprintf("drop %g%%", p.Drop))
+	}
+	if p.Error > 0 {
+		parts = append(parts, fmt.Sprintf("502 %g%%", p.Error))
+	}
+	if p.Latency > 0 || p.Jitter > 0 {
+		parts = append(parts, fmt.Sprintf("latency %s+%s", p.Latency, p.Jitter))
+	}
+	if p.Duplicate > 0 {
+		parts = append(parts, fmt.Sprintf("duplicate %g%%", p.Duplicate))
+	}
+	if p.Reorder > 0 {
+		parts = append(parts, fmt.Sprintf("reorder %g%% by %s", p.Reorder, p.ReorderWindow))
+	}
+	if len(p.Paths) > 0 {
+		parts = append(parts, "paths "+strings.Join(p.Paths, ","))
+	}
+	return strings.Join(parts, ", ")
+}
+
 func parseForwardTarget(s string) (*url.URL, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -19,6 +19,9 @@ func NormalizeArgs(argv []string) []string {
 			valueFlags: map[string]bool{
 				"--forward": true,
 			},
+			boolFlags: map[string]bool{
+				"--no-chaos": true,
+			},
 		})
 	case "show":
 		return normalizeCommand(argv, cmdFlags{
@@ -27,15 +30,71 @@ func NormalizeArgs(argv []string) []string {
 			},
 		})
 	case "replay":
+		return normalizeCommand(argv, cmdFlags{
+			valueFlags: map[string]bool{
+				"--to":                true,
+				"--patch":             true,
+				"--method":            true,
+				"--path":              true,
+				"--query":             true,
+				"--set-header":        true,
+				"--remove-header":     true,
+				"--last":              true,
+				"--speed":             true,
+				"--concurrency":       true,
+				"--rate":              true,
+				"--retry":             true,
+				"--retry-attempts":    true,
+				"--retry-delay":       true,
+				"--retry-factor":      true,
+				"--retry-max-delay":   true,
+				"--retry-jitter":      true,
+				"--retry-on":          true,
+				"--retry-speed":       true,
+				"--assert":            true,
+				"--expect-status":     true,
+				"--expect-latency":    true,
+				"--expect-header":     true,
+				"--expect-body":       true,
+				"--expect-body-regex": true,
+				"--expect-json":       true,
+				"--provider":          true,
+				"--status":            true,
+				"--search":            true,
+				"--where":             true,
+				"--header":            true,
+				"--from":              true,
+				"--until":             true,
+			},
+			boolFlags: map[string]bool{
+				"--dry-run":         true,
+				"--resign":          true,
+				"--decoded":         true,
+				"--json":            true,
+				"--ci":              true,
+				"--sequence":        true,
+				"--no-delay":        true,
+				"--stop-on-failure": true,
+			},
+		})
+	case "test":
 		return normalizeCommand(argv, cmdFlags{
 			valueFlags: map[string]bool{
 				"--to":    true,
-				"--patch": true,
-				"--last":  true,
+				"--junit": true,
+				"--tap":   true,
+			},
+			boolFlags: map[string]bool{
+				"--resign": true,
+			},
+		})
+	case "replays":
+		return normalizeCommand(argv, cmdFlags{
+			valueFlags: map[string]bool{
+				"--limit": true,
 			},
 			boolFlags: map[string]bool{
-				"--dry-run": true,
-				"--json":    true,
+				"--json": true,
 			},
 		})
 	case "list":
@@ -45,9 +104,75 @@ func NormalizeArgs(argv []string) []string {
 				"--provider": true,
 				"--status":   true,
 				"--search":   true,
+				"--where":    true,
+				"--header":   true,
+				"--cursor":   true,
+				"--from":     true,
+				"--to":       true,
 			},
 			boolFlags: map[string]bool{
 				"--json": true,
+				"--all":  true,
+			},
+		})
+	case "verify":
+		return normalizeCommand(argv, cmdFlags{
+			valueFlags: map[string]bool{
+				"--secret": true,
+			},
+			boolFlags: map[string]bool{
+				"--json": true,
+			},
+		})
+	case "diff":
+		return normalizeCommand(argv, cmdFlags{
+			boolFlags: map[string]bool{
+				"--json": true,
+			},
+		})
+	case "export":
+		return normalizeCommand(argv, cmdFlags{
+			valueFlags: map[string]bool{
+				"--format":   true,
+				"--out":      true,
+				"-o":         true,
+				"--base-url": true,
+				"--name":     true,
+				"--limit":    true,
+				"--provider": true,
+				"--status":   true,
+				"--search":   true,
+				"--where":    true,
+				"--header":   true,
+				"--from":     true,
+				"--to":       true,
+			},
+		})
+	case "import":
+		return normalizeCommand(argv, cmdFlags{
+			valueFlags: map[string]bool{
+				"--format": true,
+			},
+		})
+	case "dump":
+		return normalizeCommand(argv, cmdFlags{
+			valueFlags: map[string]bool{
+				"--out":      true,
+				"-o":         true,
+				"--provider": true,
+				"--status":   true,
+				"--search":   true,
+				"--where":    true,
+				"--header":   true,
+				"--from":     true,
+				"--to":       true,
+			},
+		})
+	case "restore":
+		return normalizeCommand(argv, cmdFlags{
+			boolFlags: map[string]bool{
+				"--merge":   true,
+				"--replace": true,
 			},
 		})
 	case "codegen":
@@ -4,11 +4,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net"
+	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"hooktm/internal/assert"
 	"hooktm/internal/replay"
 	"hooktm/internal/store"
 
@@ -19,28 +27,132 @@ func newReplayCmd() *cli.Command {
 	return &cli.Command{
 		Name:      "replay",
 		Usage:     "Replay webhooks to a target URL",
-		ArgsUsage: "[id]",
+		ArgsUsage: "[id...]",
 		Description: `Replay captured webhooks to a target URL.
 
 Exit codes (with --ci flag):
-  0  Success (2xx response)
+  0  Success (2xx response, or all assertions passed)
   1  Connection error
   2  HTTP error (4xx/5xx)
   3  Other error
+  4  Assertion failed (also without --ci)
+
+--expect-* flags and --assert <file> check each response: status codes or
+classes, a maximum latency, header globs, body substrings and regexes, and
+JSON paths (glob on the value, "*" for any). In globs "*" matches any
+characters, "/" included, so "*json*" matches application/json and URLs can
+be globbed; "?" is one character. With assertions a response
+passes or fails on them rather than on its status class, so an expected 409
+exits 0. Each failed assertion is printed under its replay and listed under
+"assertions" in --json. An assertion file is YAML:
+
+  status: 200
+  max_latency: 500ms
+  headers: {Content-Type: application/json*}
+  body_contains: ['"received":true']
+  body_matches: ['"id":"evt_\w+"']
+  json: {$.received: "true"}
+
+--patch also works on url-encoded and multipart form bodies: it sets or
+removes fields by name (null removes, a list sets repeated values) and keeps
+attachments as they are.
+
+Webhooks sent with a Content-Encoding (gzip, deflate, br) are replayed as
+captured. --patch and --resign work on the decoded body and compress it
+again; --decoded sends it uncompressed without the Content-Encoding header.
+
+--patch takes a JSON merge patch (an object: set fields, null deletes) or
+a JSON Patch (an array of operations, which can also edit array elements
+and remove fields holding null). --method, --path, --query, --set-header
+and --remove-header change the request itself. Patches and overrides can
+use template functions, rendered for each replay: {{uuid}}, {{nanoid}},
+{{now}} (RFC 3339, or {{now "2006-01-02"}}), {{unix}}, {{unixMilli}},
+{{randInt}} ({{randInt 1 100}}) and {{randHex 16}}. Use them to give
+replays fresh event IDs past idempotency checks.
+
+With --resign the provider signature is recomputed for the final (patched)
+body and the current time, using the secret from config. Supported for
+HMAC-based providers (Stripe, GitHub, Shopify, Slack, Twilio, Paddle,
+PagerDuty, Mailgun, ...); public-key schemes can't be re-signed.
+
+With --sequence the selected webhooks are replayed oldest first, with the
+time between them as captured. Select them with --last N or the list
+filters (--provider, --status, --search, --where, --header, and --from and
+--until for a time window). --speed 10x divides the gaps by ten, --no-delay
+sends each one as soon as the previous one is answered, and
+--stop-on-failure ends the run at the first error or non-2xx response.
+Ctrl+C ends a sequence and reports what was replayed.
+
+--retry sends a replay again after a connection error or a retryable
+status, with exponential backoff and jitter, and records every attempt.
+Presets: default (5 attempts from 1s, on 408, 429 and 5xx), stripe (any
+non-2xx, doubling from a minute for about three days), github (no retries,
+10s timeout: GitHub doesn't redeliver on its own) and none. The --retry-*
+flags adjust the preset (default when --retry is omitted); --retry-speed
+divides the delays, to run a provider's schedule in minutes. --ci exit codes
+reflect the last attempt.
+
+With --concurrency N or --rate R the selected webhooks (IDs, --last N or
+the list filters) are replayed as a load test: N at a time, at most R
+starting per second (50, 50/s or 3000/m). Only failures are printed, then a
+summary with success and failure counts and latency percentiles. Ctrl+C
+stops starting new replays and summarizes what completed.
 
 Examples:
   hooktm replay abc123 --to localhost:3000
+  hooktm replay abc123 def456 ghi789 --to localhost:3000
   hooktm replay abc123 --to http://api.example.com/webhook --dry-run
   hooktm replay --last 5 --to localhost:3000
-  hooktm replay abc123 --to localhost:3000 --ci --json`,
-		Flags: []cli.Flag{
+  hooktm replay --sequence --provider stripe --from 2h --until 1h --speed 10x
+  hooktm replay --sequence --last 20 --no-delay --stop-on-failure
+  hooktm replay --provider stripe --concurrency 20 --rate 50/s
+  hooktm replay --last 500 --concurrency 10 --json
+  hooktm replay abc123 --retry default --retry-on 5xx,429
+  hooktm replay abc123 --retry stripe --retry-speed 3600x --ci
+  hooktm replay abc123 --to localhost:3000 --patch '{"amount":0}' --resign
+  hooktm replay abc123 --patch '[{"op":"replace","path":"/items/0/qty","value":3}]'
+  hooktm replay abc123 --patch '{"id":"evt_{{nanoid}}"}' --set-header 'Idempotency-Key: {{uuid}}' --resign
+  hooktm replay abc123 --method PUT --path /v2/webhooks --query 'debug=1' --remove-header X-Forwarded-For
+  hooktm replay abc123 --to localhost:3000 --ci --json
+  hooktm replay abc123 --expect-status 200 --expect-latency 500ms --expect-body '"received":true'
+  hooktm replay abc123 --expect-status 409 --expect-json '$.error.code=duplicate*'
+  hooktm replay --last 10 --assert assertions.yaml --ci`,
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{Name: "to", Usage: "Target URL to replay to"},
-			&cli.StringFlag{Name: "patch", Usage: "JSON merge patch to apply (RFC 7396)"},
+			&cli.StringFlag{Name: "patch", Usage: "JSON merge patch (RFC 7396, an object) or JSON Patch (RFC 6902, an array) to apply"},
+			&cli.StringFlag{Name: "method", Usage: "Send with this HTTP method instead of the captured one"},
+			&cli.StringFlag{Name: "path", Usage: "Send to this path instead of the captured one"},
+			&cli.StringFlag{Name: "query", Usage: "Replace the query string (empty removes it)"},
+			&cli.StringSliceFlag{Name: "set-header", Usage: "Set a request header 'Name: value' (repeatable)"},
+			&cli.StringSliceFlag{Name: "remove-header", Usage: "Remove a request header (repeatable)"},
 			&cli.IntFlag{Name: "last", Usage: "Replay last N webhooks (newest first)"},
+			&cli.BoolFlag{Name: "resign", Usage: "Recompute the provider signature for the sent body"},
+			&cli.BoolFlag{Name: "decoded", Usage: "Send compressed webhooks decoded, without Content-Encoding"},
 			&cli.BoolFlag{Name: "dry-run", Usage: "Show what would be sent without sending"},
 			&cli.BoolFlag{Name: "json", Usage: "Output as JSON"},
 			&cli.BoolFlag{Name: "ci", Usage: "CI mode: return non-zero exit code on failure"},
-		},
+			&cli.BoolFlag{Name: "sequence", Usage: "Replay in captured order with the original timing"},
+			&cli.StringFlag{Name: "speed", Value: "1x", Usage: "Sequence speed factor, e.g. 10x or 0.5x"},
+			&cli.BoolFlag{Name: "no-delay", Usage: "Sequence without waiting between webhooks"},
+			&cli.BoolFlag{Name: "stop-on-failure", Usage: "End a sequence at the first error or non-2xx response"},
+			&cli.IntFlag{Name: "concurrency", Usage: "Replay N webhooks at a time and print a summary"},
+			&cli.StringFlag{Name: "rate", Usage: "Start at most this many replays per second, e.g. 50/s or 3000/m"},
+			&cli.StringFlag{Name: "retry", Usage: "Retry policy preset: " + strings.Join(replay.RetryPresets(), "|")},
+			&cli.IntFlag{Name: "retry-attempts", Usage: "Maximum attempts per replay, the first included"},
+			&cli.StringFlag{Name: "retry-delay", Usage: "Delay before the first retry, e.g. 500ms or 1m"},
+			&cli.Float64Flag{Name: "retry-factor", Usage: "Backoff multiplier between retries (1 keeps the delay constant)"},
+			&cli.StringFlag{Name: "retry-max-delay", Usage: "Cap on a single retry delay"},
+			&cli.Float64Flag{Name: "retry-jitter", Usage: "Random spread of each delay, as a fraction (0.2 = ±20%)"},
+			&cli.StringFlag{Name: "retry-on", Usage: "Retryable statuses, e.g. 5xx,429 or 500-504"},
+			&cli.StringFlag{Name: "assert", Usage: "Check responses against an assertion file (YAML; in globs * also matches /)"},
+			&cli.StringFlag{Name: "expect-status", Usage: "Expected status codes, e.g. 200, 2xx or 409,422"},
+			&cli.StringFlag{Name: "expect-latency", Usage: "Maximum response time, e.g. 500ms"},
+			&cli.StringSliceFlag{Name: "expect-header", Usage: "Expected response header 'Name: glob' (repeatable)"},
+			&cli.StringSliceFlag{Name: "expect-body", Usage: "Text the response body must contain (repeatable)"},
+			&cli.StringSliceFlag{Name: "expect-body-regex", Usage: "Regex the response body must match (repeatable)"},
+			&cli.StringSliceFlag{Name: "expect-json", Usage: "Expected JSON value 'path=glob', or 'path' to require it (repeatable)"},
+			&cli.StringFlag{Name: "retry-speed", Value: "1x", Usage: "Divide retry delays and timeouts, e.g. 60x"},
+		}, sequenceFilterFlags()...),
 		Action: runReplay,
 	}
 }
@@ -64,83 +176,615 @@ func runReplay(c *cli.Context) error {
 	// Setup replay engine
 	engine := replay.NewEngine(s)
 	engine.DryRun = c.Bool("dry-run")
+	engine.Decoded = c.Bool("decoded")
+	if c.Bool("resign") {
+		providers, err := loadProviders(cfg)
+		if err != nil {
+			return err
+		}
+		engine.Signer = newSigner(cfg, providers)
+	}
+
+	if engine.Overrides, err = overridesFromFlags(c); err != nil {
+		return err
+	}
+	if engine.Retry, err = retryPolicyFromFlags(c); err != nil {
+		return err
+	}
+	if engine.Retry.Enabled() && !c.Bool("json") && !c.IsSet("concurrency") && !c.IsSet("rate") {
+		engine.OnAttempt = func(_ string, a replay.Attempt) {
+			printAttempt(c, a, engine.Retry.MaxAttempts)
+		}
+	}
+
+	spec, err := assertSpecFromFlags(c)
+	if err != nil {
+		return err
+	}
 
 	patch := strings.TrimSpace(c.String("patch"))
-	ciMode := c.Bool("ci")
+	bulk := c.IsSet("concurrency") || c.IsSet("rate")
+	if c.Bool("sequence") {
+		if bulk {
+			return fmt.Errorf("--sequence replays one at a time: drop --concurrency and --rate")
+		}
+		return runSequence(c, s, engine, target, patch, spec)
+	}
+	if bulk {
+		return runBatch(c, s, engine, target, patch, spec)
+	}
+	if hasListFilter(c, sequenceFilterFlags()) {
+		return fmt.Errorf("filters need --sequence or --concurrency (without them, replay takes an ID or --last)")
+	}
 
 	// Collect results and errors
 	var results []replay.Result
 	var replayErrors []error
 
-	// Replay by --last or by ID
+	// Replay by --last or by ID, one after the other
+	var ids []string
 	if c.IsSet("last") && c.Int("last") > 0 {
-		n := c.Int("last")
-		rows, err := s.ListSummaries(c.Context, store.ListFilter{Limit: n})
+		rows, err := s.ListSummaries(c.Context, store.ListFilter{Limit: c.Int("last")})
 		if err != nil {
 			return err
 		}
 		for _, r := range rows {
-			res, err := engine.ReplayByID(c.Context, r.ID, target, patch)
-			if err != nil {
-				replayErrors = append(replayErrors, err)
-			} else {
-				results = append(results, res)
-			}
+			ids = append(ids, r.ID)
 		}
 	} else {
-		id, err := requireArg(c, 0, "id")
-		if err != nil {
+		if _, err := requireArg(c, 0, "id"); err != nil {
 			return err
 		}
-		res, err := engine.ReplayByID(c.Context, strings.TrimSpace(id), target, patch)
+		for _, id := range c.Args().Slice() {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	for _, id := range ids {
+		res, err := engine.ReplayByID(c.Context, id, target, patch)
 		if err != nil {
 			replayErrors = append(replayErrors, err)
-		} else {
+		}
+		if err == nil || len(res.Attempts) > 0 {
 			results = append(results, res)
 		}
 	}
 
 	// Output results
 	if c.Bool("json") {
+		out := make([]replayJSON, len(results))
+		for i, r := range results {
+			out[i] = replayJSON{Result: r, Assertions: checkResult(spec, r)}
+		}
 		enc := json.NewEncoder(c.App.Writer)
 		enc.SetIndent("", "  ")
-		if err := enc.Encode(results); err != nil {
+		if err := enc.Encode(out); err != nil {
 			return err
 		}
 	} else {
 		for _, r := range results {
-			if r.Sent {
-				_, _ = fmt.Fprintf(c.App.Writer, "Replayed %s → %s (%d)\n", r.WebhookID, r.URL, r.StatusCode)
-			} else {
+			switch {
+			case r.Sent:
+				_, _ = fmt.Fprintf(c.App.Writer, "Replayed %s → %s (%d)%s\n", r.WebhookID, r.URL, r.StatusCode, attemptsNote(r))
+				printFailures(c, checkResult(spec, r))
+			case engine.DryRun:
 				_, _ = fmt.Fprintf(c.App.Writer, "Dry-run %s → %s\n", r.WebhookID, r.URL)
 			}
 		}
 	}
+	return replayOutcome(c, results, replayErrors, spec)
+}
 
-	// Handle CI mode exit codes
-	if ciMode {
-		exitCode := 0
-		for _, err := range replayErrors {
-			code := getExitCodeFromError(err)
-			if code > exitCode {
-				exitCode = code
+// replayOutcome turns the results of a run into the command's error: the
+// exit code with --ci, else the first replay error. Failed assertions exit
+// with 4 either way, as the highest code; replay errors are then printed.
+func replayOutcome(c *cli.Context, results []replay.Result, errs []error, spec *assert.Spec) error {
+	if c.Bool("ci") {
+		if exitCode := ciExitCode(results, errs, spec); exitCode != 0 {
+			return cli.Exit("", exitCode)
+		}
+		return nil
+	}
+	for _, r := range results {
+		if len(checkResult(spec, r)) > 0 {
+			for _, err := range errs {
+				_, _ = fmt.Fprintf(c.App.ErrWriter, "%v\n", err)
 			}
+			return cli.Exit("", exitAssertion)
 		}
-		for _, r := range results {
-			if r.Sent {
-				code := getExitCodeFromStatus(r.StatusCode)
-				if code > exitCode {
-					exitCode = code
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// exitAssertion is the exit code for failed assertions.
+const exitAssertion = 4
+
+// ciExitCode is the highest exit code among the replay errors and responses.
+// With assertions a response exits 0 or exitAssertion on them instead of on
+// its status class.
+func ciExitCode(results []replay.Result, errs []error, spec *assert.Spec) int {
+	exitCode := 0
+	for _, err := range errs {
+		if code := getExitCodeFromError(err); code > exitCode {
+			exitCode = code
+		}
+	}
+	for _, r := range results {
+		if !r.Sent {
+			continue
+		}
+		code := getExitCodeFromStatus(r.StatusCode)
+		if !spec.Empty() {
+			code = 0
+			if len(checkResult(spec, r)) > 0 {
+				code = exitAssertion
+			}
+		}
+		if code > exitCode {
+			exitCode = code
+		}
+	}
+	return exitCode
+}
+
+// checkResult returns the assertions a sent replay fails.
+func checkResult(spec *assert.Spec, r replay.Result) []assert.Failure {
+	if !r.Sent {
+		return nil
+	}
+	return spec.Check(assert.FromResult(r))
+}
+
+// failed reports whether a replay failed: it got no response, or failed its
+// assertions, or without assertions got a non-2xx response.
+func failed(spec *assert.Spec, r replay.StepResult) bool {
+	if r.Err != nil || spec.Empty() {
+		return r.Failed()
+	}
+	return len(checkResult(spec, r.Result)) > 0
+}
+
+// printFailures prints failed assertions under their replay.
+func printFailures(c *cli.Context, fails []assert.Failure) {
+	for _, f := range fails {
+		_, _ = fmt.Fprintf(c.App.Writer, "  ✗ %s\n", f)
+	}
+}
+
+// replayJSON is one replay in --json output.
+type replayJSON struct {
+	replay.Result
+	Assertions []assert.Failure `json:"assertions,omitempty"`
+}
+
+// sequenceJSON is one step of a sequence or batch in --json output.
+type sequenceJSON struct {
+	replay.Result
+	OffsetMS   int64            `json:"offset_ms"`
+	Error      string           `json:"error,omitempty"`
+	Assertions []assert.Failure `json:"assertions,omitempty"`
+}
+
+// runSequence replays the selected webhooks in captured order, keeping their
+// spacing, and reports each step as it completes.
+func runSequence(c *cli.Context, s *store.Store, engine *replay.Engine, target, patch string, spec *assert.Spec) error {
+	if c.NArg() > 0 {
+		return fmt.Errorf("--sequence selects webhooks with --last or filters, not IDs")
+	}
+	if !c.IsSet("last") && !hasListFilter(c, sequenceFilterFlags()) {
+		return fmt.Errorf("--sequence needs --last or a filter (--provider, --from, --until, --where, ...)")
+	}
+	seq := &replay.Sequence{
+		Engine:        engine,
+		Target:        target,
+		Patch:         patch,
+		StopOnFailure: c.Bool("stop-on-failure"),
+		Failed:        func(r replay.StepResult) bool { return failed(spec, r) },
+	}
+	switch {
+	case c.Bool("no-delay") && c.IsSet("speed"):
+		return fmt.Errorf("use either --speed or --no-delay")
+	case !c.Bool("no-delay"):
+		speed, err := parseSpeed(c.String("speed"))
+		if err != nil {
+			return err
+		}
+		seq.Speed = speed
+	}
+
+	filter, err := filterFromFlags(c, "until")
+	if err != nil {
+		return err
+	}
+	rows, err := matching(c, s, filter, c.Int("last"))
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("no webhooks match")
+	}
+	steps := make([]replay.Step, len(rows))
+	for i, r := range rows {
+		steps[i] = replay.Step{WebhookID: r.ID, CreatedAt: r.CreatedAt}
+	}
+
+	jsonOut := c.Bool("json")
+	if !jsonOut {
+		seq.OnStep = func(r replay.StepResult) {
+			offset := fmt.Sprintf("+%.3fs", r.Offset.Seconds())
+			switch {
+			case r.Err != nil:
+				_, _ = fmt.Fprintf(c.App.Writer, "%s  Failed %s: %v\n", offset, r.WebhookID, r.Err)
+			case r.Sent:
+				_, _ = fmt.Fprintf(c.App.Writer, "%s  Replayed %s → %s (%d)%s\n", offset, r.WebhookID, r.URL, r.StatusCode, attemptsNote(r.Result))
+				printFailures(c, checkResult(spec, r.Result))
+			default:
+				_, _ = fmt.Fprintf(c.App.Writer, "%s  Dry-run %s → %s\n", offset, r.WebhookID, r.URL)
+			}
+		}
+	}
+	// Ctrl+C ends the sequence; what was replayed is still reported.
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	done, runErr := seq.Run(ctx, steps)
+	if runErr != nil && c.Context.Err() != nil {
+		return runErr
+	}
+
+	var (
+		results []replay.Result
+		errs    []error
+		out     []sequenceJSON
+	)
+	for _, r := range done {
+		j := sequenceJSON{Result: r.Result, OffsetMS: r.Offset.Milliseconds(), Assertions: checkResult(spec, r.Result)}
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			j.Error = r.Err.Error()
+		} else {
+			results = append(results, r.Result)
+		}
+		out = append(out, j)
+	}
+	if jsonOut {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	switch {
+	case runErr != nil:
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "Interrupted: %d of %d webhook(s) replayed\n", len(done), len(steps))
+	case len(done) < len(steps):
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "Stopped after a failure: %d of %d webhook(s) replayed\n", len(done), len(steps))
+	}
+	return replayOutcome(c, results, errs, spec)
+}
+
+// runBatch replays the selected webhooks concurrently, optionally rate
+// limited, and ends with a summary. Ctrl+C stops the run early.
+func runBatch(c *cli.Context, s *store.Store, engine *replay.Engine, target, patch string, spec *assert.Spec) error {
+	b := &replay.Batch{Engine: engine, Target: target, Patch: patch, Concurrency: 1}
+	if c.IsSet("concurrency") {
+		if b.Concurrency = c.Int("concurrency"); b.Concurrency < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+	}
+	if c.IsSet("rate") {
+		rate, err := parseRate(c.String("rate"))
+		if err != nil {
+			return err
+		}
+		b.Rate = rate
+	}
+
+	var ids []string
+	if c.NArg() > 0 {
+		if c.IsSet("last") || hasListFilter(c, sequenceFilterFlags()) {
+			return fmt.Errorf("select webhooks with IDs or with --last and filters, not both")
+		}
+		for _, id := range c.Args().Slice() {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	} else {
+		if !c.IsSet("last") && !hasListFilter(c, sequenceFilterFlags()) {
+			return fmt.Errorf("--concurrency and --rate need IDs, --last or a filter (--provider, --from, --where, ...)")
+		}
+		filter, err := filterFromFlags(c, "until")
+		if err != nil {
+			return err
+		}
+		rows, err := matching(c, s, filter, c.Int("last"))
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no webhooks match")
+	}
+
+	// Keep a connection per worker instead of the default two idle ones.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = b.Concurrency
+	engine.HTTP = &http.Client{Timeout: engine.HTTP.Timeout, Transport: transport}
+
+	jsonOut := c.Bool("json")
+	if !jsonOut {
+		b.OnResult = func(r replay.StepResult) {
+			switch {
+			case r.Err != nil:
+				_, _ = fmt.Fprintf(c.App.Writer, "Failed %s: %v\n", r.WebhookID, r.Err)
+			case failed(spec, r):
+				_, _ = fmt.Fprintf(c.App.Writer, "Replayed %s → %s (%d)%s\n", r.WebhookID, r.URL, r.StatusCode, attemptsNote(r.Result))
+				printFailures(c, checkResult(spec, r.Result))
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	start := time.Now()
+	done, runErr := b.Run(ctx, ids)
+	summary := replay.Summarize(done, time.Since(start))
+	if runErr != nil && c.Context.Err() != nil {
+		return runErr
+	}
+
+	var (
+		results []replay.Result
+		errs    []error
+		out     []sequenceJSON
+	)
+	for _, r := range done {
+		j := sequenceJSON{Result: r.Result, OffsetMS: r.Offset.Milliseconds(), Assertions: checkResult(spec, r.Result)}
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+			j.Error = r.Err.Error()
+		} else {
+			results = append(results, r.Result)
+		}
+		out = append(out, j)
+	}
+	if jsonOut {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Results []sequenceJSON `json:"results"`
+			Summary replay.Summary `json:"summary"`
+		}{out, summary}); err != nil {
+			return err
+		}
+	} else {
+		printSummary(c, summary)
+		if !spec.Empty() {
+			n := 0
+			for _, r := range results {
+				if len(checkResult(spec, r)) > 0 {
+					n++
 				}
 			}
+			_, _ = fmt.Fprintf(c.App.Writer, "Assertions: %d of %d response(s) failed\n", n, len(results))
 		}
-		if exitCode != 0 {
-			return cli.Exit("", exitCode)
+	}
+	if runErr != nil {
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "Interrupted: %d of %d webhook(s) replayed\n", len(done), len(ids))
+	}
+	return replayOutcome(c, results, errs, spec)
+}
+
+func printSummary(c *cli.Context, s replay.Summary) {
+	w := c.App.Writer
+	var retries string
+	if s.Retries > 0 {
+		retries = fmt.Sprintf(" (%d retries)", s.Retries)
+	}
+	_, _ = fmt.Fprintf(w, "\nReplayed %d in %.2fs (%.1f/s): %d ok, %d failed, %d errors%s\n",
+		s.Total, float64(s.DurationMS)/1000, s.Rate, s.Succeeded, s.Failed, s.Errors, retries)
+	if len(s.StatusCodes) > 0 {
+		codes := make([]int, 0, len(s.StatusCodes))
+		for code := range s.StatusCodes {
+			codes = append(codes, code)
 		}
-	} else if len(replayErrors) > 0 {
-		return replayErrors[0]
+		sort.Ints(codes)
+		parts := make([]string, len(codes))
+		for i, code := range codes {
+			parts[i] = fmt.Sprintf("%d×%d", code, s.StatusCodes[code])
+		}
+		_, _ = fmt.Fprintf(w, "Status:  %s\n", strings.Join(parts, "  "))
+		l := s.Latency
+		_, _ = fmt.Fprintf(w, "Latency: min %dms  mean %.1fms  p50 %dms  p90 %dms  p95 %dms  p99 %dms  max %dms\n",
+			l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
 	}
-	return nil
+}
+
+// overridesFromFlags builds the request overrides from --method, --path,
+// --query, --set-header and --remove-header.
+func overridesFromFlags(c *cli.Context) (replay.Overrides, error) {
+	o := replay.Overrides{
+		Method:        c.String("method"),
+		Path:          c.String("path"),
+		RemoveHeaders: c.StringSlice("remove-header"),
+	}
+	if c.IsSet("query") {
+		q := c.String("query")
+		o.Query = &q
+	}
+	for _, h := range c.StringSlice("set-header") {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return o, fmt.Errorf("invalid --set-header %q (use 'Name: value')", h)
+		}
+		if o.SetHeaders == nil {
+			o.SetHeaders = map[string]string{}
+		}
+		o.SetHeaders[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return o, nil
+}
+
+// retryPolicyFromFlags builds the retry policy from --retry and the
+// --retry-* flags. Without any of them replays aren't retried.
+func retryPolicyFromFlags(c *cli.Context) (replay.RetryPolicy, error) {
+	flags := []string{"retry", "retry-attempts", "retry-delay", "retry-factor", "retry-max-delay", "retry-jitter", "retry-on", "retry-speed"}
+	set := false
+	for _, f := range flags {
+		set = set || c.IsSet(f)
+	}
+	if !set {
+		return replay.RetryPolicy{}, nil
+	}
+	preset := "default"
+	if c.IsSet("retry") {
+		preset = c.String("retry")
+	}
+	p, err := replay.RetryPreset(preset)
+	if err != nil {
+		return p, err
+	}
+
+	if c.IsSet("retry-attempts") {
+		if p.MaxAttempts = c.Int("retry-attempts"); p.MaxAttempts < 1 {
+			return p, fmt.Errorf("--retry-attempts must be at least 1")
+		}
+	}
+	for flag, d := range map[string]*time.Duration{"retry-delay": &p.Initial, "retry-max-delay": &p.Max} {
+		if !c.IsSet(flag) {
+			continue
+		}
+		v, err := time.ParseDuration(strings.TrimSpace(c.String(flag)))
+		if err != nil || v < 0 {
+			return p, fmt.Errorf("invalid --%s %q (use e.g. 500ms, 2s or 1m)", flag, c.String(flag))
+		}
+		*d = v
+	}
+	if c.IsSet("retry-factor") {
+		if p.Multiplier = c.Float64("retry-factor"); p.Multiplier < 1 {
+			return p, fmt.Errorf("--retry-factor must be at least 1")
+		}
+	}
+	if c.IsSet("retry-jitter") {
+		if p.Jitter = c.Float64("retry-jitter"); p.Jitter < 0 || p.Jitter > 1 {
+			return p, fmt.Errorf("--retry-jitter must be between 0 and 1")
+		}
+	}
+	if c.IsSet("retry-on") {
+		if p.RetryOn, err = replay.ParseStatusSet(c.String("retry-on")); err != nil {
+			return p, err
+		}
+	}
+	speed, err := parseSpeed(c.String("retry-speed"))
+	if err != nil {
+		return p, fmt.Errorf("invalid --retry-speed %q (use e.g. 60x)", c.String("retry-speed"))
+	}
+	return p.Scaled(speed), nil
+}
+
+// printAttempt prints one attempt of a replay with retries.
+func printAttempt(c *cli.Context, a replay.Attempt, max int) {
+	outcome := fmt.Sprintf("%d in %dms", a.StatusCode, a.DurationMS)
+	if a.Error != "" {
+		outcome = a.Error
+	}
+	next := ""
+	if a.RetryInMS > 0 {
+		next = ", retrying in " + (time.Duration(a.RetryInMS) * time.Millisecond).String()
+	}
+	_, _ = fmt.Fprintf(c.App.Writer, "  attempt %d/%d: %s%s\n", a.N, max, outcome, next)
+}
+
+// attemptsNote tells how many attempts a replay took, when it was retried.
+func attemptsNote(r replay.Result) string {
+	if len(r.Attempts) < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" after %d attempts", len(r.Attempts))
+}
+
+// assertSpecFromFlags builds the response assertions from --assert and the
+// --expect-* flags, which add to the file. It returns nil without any.
+func assertSpecFromFlags(c *cli.Context) (*assert.Spec, error) {
+	spec := &assert.Spec{}
+	if p := strings.TrimSpace(c.String("assert")); p != "" {
+		var err error
+		if spec, err = assert.Load(p); err != nil {
+			return nil, err
+		}
+	}
+	if c.IsSet("expect-status") {
+		spec.Status = c.String("expect-status")
+	}
+	if c.IsSet("expect-latency") {
+		d, err := time.ParseDuration(strings.TrimSpace(c.String("expect-latency")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid --expect-latency %q (use e.g. 500ms or 2s)", c.String("expect-latency"))
+		}
+		spec.MaxLatency = d
+	}
+	for _, h := range c.StringSlice("expect-header") {
+		name, pattern, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --expect-header %q (use 'Name: glob')", h)
+		}
+		if spec.Headers == nil {
+			spec.Headers = map[string]string{}
+		}
+		spec.Headers[strings.TrimSpace(name)] = strings.TrimSpace(pattern)
+	}
+	spec.BodyContains = append(spec.BodyContains, c.StringSlice("expect-body")...)
+	spec.BodyMatches = append(spec.BodyMatches, c.StringSlice("expect-body-regex")...)
+	for _, j := range c.StringSlice("expect-json") {
+		p, pattern, ok := strings.Cut(j, "=")
+		if !ok {
+			pattern = "*"
+		}
+		if strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("invalid --expect-json %q (use 'path=glob' or 'path')", j)
+		}
+		if spec.JSON == nil {
+			spec.JSON = map[string]string{}
+		}
+		spec.JSON[strings.TrimSpace(p)] = strings.TrimSpace(pattern)
+	}
+	if err := spec.Compile(); err != nil {
+		return nil, err
+	}
+	if spec.Empty() {
+		return nil, nil
+	}
+	return spec, nil
+}
+
+// parseRate parses a replay rate such as "50", "50/s" or "3000/m" into
+// replays per second.
+func parseRate(s string) (float64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	per := 1.0
+	switch {
+	case strings.HasSuffix(v, "/s"):
+		v = strings.TrimSuffix(v, "/s")
+	case strings.HasSuffix(v, "/m"):
+		v, per = strings.TrimSuffix(v, "/m"), 60
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid --rate %q (use e.g. 50/s or 3000/m)", s)
+	}
+	return n / per, nil
+}
+
+// parseSpeed parses a speed factor such as "10x", "0.5x" or "2".
+func parseSpeed(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "x"), 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid --speed %q (use e.g. 10x or 0.5x)", s)
+	}
+	return v, nil
 }
 
 func getExitCodeFromStatus(statusCode int) int {
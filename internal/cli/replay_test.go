@@ -1,10 +1,26 @@
 package cli
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
+
+	"hooktm/internal/assert"
+	"hooktm/internal/replay"
+	"hooktm/internal/store"
+
+	"github.com/urfave/cli/v2"
 )
 
 func TestGetExitCodeFromStatus(t *testing.T) {
@@ -113,3 +129,176 @@ type testNetError struct {
 func (e *testNetError) Error() string   { return "test network error" }
 func (e *testNetError) Timeout() bool   { return e.timeout }
 func (e *testNetError) Temporary() bool { return e.temporary }
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"1x", 1, false},
+		{"10x", 10, false},
+		{"0.5X", 0.5, false},
+		{"3", 3, false},
+		{"0x", 0, true},
+		{"-2x", 0, true},
+		{"fast", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSpeed(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSpeed(%q) = %v, %v", tt.input, got, err)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"50", 50, false},
+		{"50/s", 50, false},
+		{"3000/m", 50, false},
+		{"0.5/S", 0.5, false},
+		{"0/s", 0, true},
+		{"50/h", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRate(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRate(%q) = %v, %v", tt.input, got, err)
+		}
+	}
+}
+
+func TestCIExitCode_Assertions(t *testing.T) {
+	conflict := replay.Result{Sent: true, StatusCode: 409, ResponseHeader: http.Header{}, ResponseBody: []byte(`{"error":"duplicate"}`)}
+	if got := ciExitCode([]replay.Result{conflict}, nil, nil); got != 2 {
+		t.Fatalf("without assertions: exit %d, want 2", got)
+	}
+	spec := &assert.Spec{Status: "409", JSON: map[string]string{"error": "dup*"}}
+	if err := spec.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ciExitCode([]replay.Result{conflict}, nil, spec); got != 0 {
+		t.Fatalf("passing assertions: exit %d, want 0", got)
+	}
+	ok := replay.Result{Sent: true, StatusCode: 200}
+	if got := ciExitCode([]replay.Result{conflict, ok}, nil, spec); got != exitAssertion {
+		t.Fatalf("failing assertions: exit %d, want %d", got, exitAssertion)
+	}
+}
+
+func TestReplayOutcome_AssertionsWithoutCI(t *testing.T) {
+	spec := &assert.Spec{Status: "200"}
+	if err := spec.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	app := cli.NewApp()
+	var stderr bytes.Buffer
+	app.ErrWriter = &stderr
+	c := cli.NewContext(app, flag.NewFlagSet("replay", flag.ContinueOnError), nil)
+
+	// A connection error doesn't hide failed assertions.
+	failed := replay.Result{Sent: true, StatusCode: 503}
+	err := replayOutcome(c, []replay.Result{failed}, []error{syscall.ECONNREFUSED}, spec)
+	var exit cli.ExitCoder
+	if !errors.As(err, &exit) || exit.ExitCode() != exitAssertion {
+		t.Fatalf("err=%v, want exit %d", err, exitAssertion)
+	}
+	if !strings.Contains(stderr.String(), "connection refused") {
+		t.Fatalf("replay error not printed: %q", stderr.String())
+	}
+
+	passed := replay.Result{Sent: true, StatusCode: 200}
+	if err := replayOutcome(c, []replay.Result{passed}, []error{syscall.ECONNREFUSED}, spec); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("err=%v, want the replay error", err)
+	}
+}
+
+// replayFixture is a store with the given webhooks in a temporary directory
+// and a target that counts requests per path and answers with status.
+type replayFixture struct {
+	db, config string
+	target     *httptest.Server
+	mu         sync.Mutex
+	hits       map[string]int
+}
+
+func newReplayFixture(t *testing.T, status int, ids ...string) *replayFixture {
+	t.Helper()
+	dir := t.TempDir()
+	f := &replayFixture{db: filepath.Join(dir, "hooktm.db"), config: filepath.Join(dir, "config.yaml"), hits: map[string]int{}}
+	if err := os.WriteFile(f.config, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.Open(f.db)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i, id := range ids {
+		if err := s.InsertWebhook(context.Background(), store.InsertParams{ID: id, CreatedAt: int64(i + 1), Method: "POST", Path: "/" + id, Headers: map[string][]string{}}); err != nil {
+			t.Fatalf("InsertWebhook: %v", err)
+		}
+	}
+	_ = s.Close()
+	f.target = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.hits[r.URL.Path]++
+		f.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(f.target.Close)
+	return f
+}
+
+// run runs hooktm with the fixture's store and config; args are the
+// command, its flags, then its arguments.
+func (f *replayFixture) run(args ...string) (string, error) {
+	app := NewApp()
+	var out, errOut bytes.Buffer
+	app.Writer, app.ErrWriter = &out, &errOut
+	app.ExitErrHandler = func(*cli.Context, error) {}
+	err := app.Run(append([]string{"hooktm", "--db", f.db, "--config", f.config}, args...))
+	return out.String() + errOut.String(), err
+}
+
+func TestReplay_MultipleIDs(t *testing.T) {
+	f := newReplayFixture(t, http.StatusOK, "a", "b", "c")
+	out, err := f.run("replay", "--to", f.target.URL, "a", "b", "c")
+	if err != nil {
+		t.Fatalf("replay: %v\n%s", err, out)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if f.hits["/"+id] != 1 {
+			t.Fatalf("hits %v\n%s", f.hits, out)
+		}
+	}
+
+	// An unknown ID fails the run after the others are sent.
+	out, err = f.run("replay", "--to", f.target.URL, "a", "missing", "c")
+	if err == nil || !strings.Contains(err.Error(), "not found: missing") || f.hits["/c"] != 2 {
+		t.Fatalf("err=%v hits=%v\n%s", err, f.hits, out)
+	}
+}
+
+func TestReplay_BatchAssertionsWithSendError(t *testing.T) {
+	f := newReplayFixture(t, http.StatusServiceUnavailable, "a", "b")
+	out, err := f.run("replay", "--to", f.target.URL, "--concurrency", "2", "--expect-status", "200", "a", "b", "missing")
+	var exit cli.ExitCoder
+	if !errors.As(err, &exit) || exit.ExitCode() != exitAssertion {
+		t.Fatalf("err=%v, want exit %d\n%s", err, exitAssertion, out)
+	}
+	if !strings.Contains(out, "not found: missing") {
+		t.Fatalf("send error not reported:\n%s", out)
+	}
+
+	// Without failed assertions the send error is the outcome.
+	out, err = f.run("replay", "--to", f.target.URL, "--concurrency", "2", "--expect-status", "503", "a", "missing")
+	if err == nil || errors.As(err, &exit) || !strings.Contains(err.Error(), "not found: missing") {
+		t.Fatalf("err=%v\n%s", err, out)
+	}
+}
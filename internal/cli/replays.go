@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newReplaysCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "replays",
+		Usage:     "Show replay history of a webhook",
+		ArgsUsage: "<id>",
+		Description: `List every recorded replay of a captured webhook, newest first.
+
+Each replay records the target URL, applied patch, response status,
+duration, response body and error.
+
+Examples:
+  hooktm replays abc123
+  hooktm replays abc123 --limit 50
+  hooktm replays abc123 --json`,
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "limit", Value: 20, Usage: "Maximum number of results"},
+			&cli.BoolFlag{Name: "json", Usage: "Output as JSON"},
+		},
+		Action: runReplays,
+	}
+}
+
+func runReplays(c *cli.Context) error {
+	id, err := requireArg(c, 0, "id")
+	if err != nil {
+		return err
+	}
+	id = strings.TrimSpace(id)
+
+	s, _, err := openStoreFromContext(c)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Fail clearly on unknown IDs instead of printing an empty history.
+	wh, err := s.GetWebhook(c.Context, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.ListReplays(c.Context, id, c.Int("limit"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	for _, r := range rows {
+		status := "-"
+		if r.StatusCode != nil {
+			status = fmt.Sprintf("%d", *r.StatusCode)
+		}
+		target := r.TargetURL
+		if r.Method != "" && r.Method != wh.Method {
+			target = r.Method + " " + target
+		}
+		line := fmt.Sprintf("%s  %s  → %s  [%s]  %dms", r.ID, formatTimestamp(r.CreatedAt), target, status, r.DurationMS)
+		if r.Patch != "" {
+			line += "  patched"
+		}
+		if r.Error != "" {
+			line += "  error: " + r.Error
+		}
+		_, _ = fmt.Fprintln(c.App.Writer, line)
+	}
+	return nil
+}
@@ -3,9 +3,12 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 	"time"
 
+	"hooktm/internal/contentenc"
 	"hooktm/internal/store"
 
 	"github.com/urfave/cli/v2"
@@ -48,7 +51,7 @@ func runShow(c *cli.Context) error {
 
 	switch strings.ToLower(c.String("format")) {
 	case "raw":
-		return showRaw(c, wh)
+		return showRaw(c, s, wh)
 	case "json":
 		return showJSON(c, wh)
 	default:
@@ -56,13 +59,28 @@ func runShow(c *cli.Context) error {
 	}
 }
 
-func showRaw(c *cli.Context, wh store.Webhook) error {
+func showRaw(c *cli.Context, s *store.Store, wh store.Webhook) error {
 	_, _ = fmt.Fprintf(c.App.Writer, "%s %s%s\n", wh.Method, wh.Path, formatQuery(wh.Query))
 	_, _ = fmt.Fprintf(c.App.Writer, "Time: %s\n", time.UnixMilli(wh.CreatedAt).UTC().Format(time.RFC3339))
 	_, _ = fmt.Fprintf(c.App.Writer, "Provider: %s\n", defaultString(wh.Provider, "unknown"))
 	_, _ = fmt.Fprintf(c.App.Writer, "Event: %s\n", wh.EventType)
+	if wh.Signature != "" {
+		_, _ = fmt.Fprintf(c.App.Writer, "Signature: %s (%s)\n", wh.Signature, signatureStatus(wh.SignatureValid))
+	}
 	_, _ = fmt.Fprintf(c.App.Writer, "Status: %v\n", wh.StatusCode)
 	_, _ = fmt.Fprintf(c.App.Writer, "Latency: %dms\n", wh.ResponseMS)
+	if wh.MockRule != "" {
+		_, _ = fmt.Fprintf(c.App.Writer, "Mock Rule: %s\n", wh.MockRule)
+	}
+	if wh.Fault != "" {
+		_, _ = fmt.Fprintf(c.App.Writer, "Fault: %s\n", wh.Fault)
+	}
+	if len(wh.Deliveries) > 1 {
+		_, _ = fmt.Fprintf(c.App.Writer, "\nDeliveries:\n")
+		for _, d := range wh.Deliveries {
+			_, _ = fmt.Fprintf(c.App.Writer, "  %s\n", describeDelivery(d))
+		}
+	}
 
 	_, _ = fmt.Fprintf(c.App.Writer, "\nHeaders:\n")
 	for k, vs := range wh.Headers {
@@ -71,8 +89,60 @@ func showRaw(c *cli.Context, wh store.Webhook) error {
 		}
 	}
 
-	_, _ = fmt.Fprintf(c.App.Writer, "\nBody (%d bytes):\n", len(wh.Body))
-	_, _ = c.App.Writer.Write(wh.Body)
+	switch {
+	case wh.BodySize > 0:
+		// Chunked bodies are copied straight from the store.
+		_, _ = fmt.Fprintf(c.App.Writer, "\nBody (%d bytes):\n", wh.BodySize)
+		rc, err := s.OpenBody(c.Context, wh.ID)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(c.App.Writer, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	case len(wh.Attachments) > 0:
+		// Attachments may be binary; the decoded parts are listed below.
+		_, _ = fmt.Fprintf(c.App.Writer, "\nBody (%d bytes, multipart; use --format json for the raw body)\n", len(wh.Body))
+	case wh.DecodedBody != nil:
+		codings := strings.Join(contentenc.Codings(http.Header(wh.Headers)), ", ")
+		_, _ = fmt.Fprintf(c.App.Writer, "\nBody (%d bytes %s, %d decoded):\n", len(wh.Body), codings, len(wh.DecodedBody))
+		_, _ = c.App.Writer.Write(wh.DecodedBody)
+	default:
+		_, _ = fmt.Fprintf(c.App.Writer, "\nBody (%d bytes):\n", len(wh.Body))
+		_, _ = c.App.Writer.Write(wh.Body)
+	}
+	_, _ = fmt.Fprintf(c.App.Writer, "\n")
+
+	if len(wh.Form) > 0 {
+		_, _ = fmt.Fprintf(c.App.Writer, "\nForm:\n")
+		for _, f := range wh.Form {
+			_, _ = fmt.Fprintf(c.App.Writer, "  %s: %s\n", f.Name, f.Value)
+		}
+	}
+	if len(wh.Attachments) > 0 {
+		_, _ = fmt.Fprintf(c.App.Writer, "\nAttachments:\n")
+		for _, a := range wh.Attachments {
+			_, _ = fmt.Fprintf(c.App.Writer, "  %s: %s (%s, %d bytes)\n", a.Field, a.Filename, defaultString(a.ContentType, "unknown type"), a.Size)
+		}
+	}
+
+	if wh.ResponseHeaders == nil && len(wh.ResponseBody) == 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintf(c.App.Writer, "\nResponse Headers:\n")
+	for k, vs := range wh.ResponseHeaders {
+		for _, v := range vs {
+			_, _ = fmt.Fprintf(c.App.Writer, "  %s: %s\n", k, v)
+		}
+	}
+	truncated := ""
+	if wh.ResponseTruncated {
+		truncated = ", truncated"
+	}
+	_, _ = fmt.Fprintf(c.App.Writer, "\nResponse Body (%d bytes%s):\n", len(wh.ResponseBody), truncated)
+	_, _ = c.App.Writer.Write(wh.ResponseBody)
 	_, _ = fmt.Fprintf(c.App.Writer, "\n")
 	return nil
 }
@@ -83,6 +153,17 @@ func showJSON(c *cli.Context, wh store.Webhook) error {
 	return enc.Encode(wh)
 }
 
+func signatureStatus(valid *bool) string {
+	switch {
+	case valid == nil:
+		return "not verified"
+	case *valid:
+		return "valid"
+	default:
+		return "invalid"
+	}
+}
+
 func formatQuery(q string) string {
 	if q == "" {
 		return ""
@@ -99,3 +180,17 @@ func defaultString(s, defaultVal string) string {
 	}
 	return s
 }
+
+// describeDelivery renders one forward target's outcome; * marks the target
+// that answered the caller.
+func describeDelivery(d store.Delivery) string {
+	mark := " "
+	if d.Primary {
+		mark = "*"
+	}
+	outcome := d.Error
+	if d.StatusCode != nil {
+		outcome = fmt.Sprintf("%d", *d.StatusCode)
+	}
+	return fmt.Sprintf("%s %s  %s  %dms", mark, d.TargetURL, outcome, d.DurationMS)
+}
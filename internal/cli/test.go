@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hooktm/internal/importer"
+	"hooktm/internal/replay"
+	"hooktm/internal/store"
+	"hooktm/internal/suite"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newTestCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "test",
+		Usage:     "Run replay test suites and write JUnit or TAP reports",
+		ArgsUsage: "<suite.yaml> [suite.yaml...]",
+		Description: `Replay the cases of YAML test suites against your app and check each
+response, for webhook contract tests in CI.
+
+  name: stripe
+  target: http://localhost:3000
+  resign: true                        # re-sign with the secrets from config
+  cases:
+    - name: invoice paid is acknowledged
+      webhook: abc123                 # a captured webhook ID
+      patch: {data: {object: {amount_paid: 0}}}
+      expect:
+        status: 200
+        max_latency: 500ms
+        json: {$.received: "true"}
+    - name: duplicate is rejected
+      fixture: fixtures/invoice.http  # HAR, curl, raw HTTP or NDJSON, one request
+      expect: {status: 409}
+    - name: refunds
+      webhook: def456
+      skip: not implemented yet
+
+expect takes the fields of a replay --assert file (status, max_latency,
+headers, body_contains, body_matches, json); a case without it passes on a
+2xx. Fixture paths are relative to the suite and are replayed from a
+temporary store, so they leave no trace in yours. The target is the case's,
+else --to, else the suite's, else forward from config.
+
+Exits 1 when a case fails.
+
+Examples:
+  hooktm test webhooks.yaml
+  hooktm test suites/*.yaml --to http://localhost:8080 --junit report.xml
+  hooktm test webhooks.yaml --tap -`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "to", Usage: "Target URL for suites and cases without one"},
+			&cli.StringFlag{Name: "junit", Usage: "Write a JUnit XML report to a file (- for stdout)"},
+			&cli.StringFlag{Name: "tap", Usage: "Write a TAP report to a file (- for stdout)"},
+			&cli.BoolFlag{Name: "resign", Usage: "Re-sign every replay, as if each suite set resign"},
+		},
+		Action: runTest,
+	}
+}
+
+func runTest(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("missing suite file")
+	}
+	if c.String("junit") == "-" && c.String("tap") == "-" {
+		return fmt.Errorf("only one of --junit and --tap can write to stdout")
+	}
+	var suites []*suite.Suite
+	for _, p := range c.Args().Slice() {
+		su, err := suite.Load(p)
+		if err != nil {
+			return err
+		}
+		if c.Bool("resign") {
+			su.Resign = true
+		}
+		if to := strings.TrimSpace(c.String("to")); to != "" {
+			su.Target = to
+		}
+		suites = append(suites, su)
+	}
+
+	s, cfg, err := openStoreFromContext(c)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	providers, err := loadProviders(cfg)
+	if err != nil {
+		return err
+	}
+
+	runner := &suite.Runner{
+		Engine: replay.NewEngine(s),
+		Signer: newSigner(cfg, providers),
+		Target: cfg.Forward,
+	}
+	for _, su := range suites {
+		if su.HasFixtures() {
+			scratch, cleanup, err := openScratchStore()
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			runner.Fixtures = &importer.Importer{Store: scratch, Providers: providers, Verifier: newVerifier(cfg, providers)}
+			break
+		}
+	}
+
+	// The text report goes to stderr when a machine report takes stdout.
+	w := c.App.Writer
+	if c.String("junit") == "-" || c.String("tap") == "-" {
+		w = c.App.ErrWriter
+	}
+	runner.OnCase = func(r suite.CaseResult) {
+		switch {
+		case r.Skipped != "":
+			_, _ = fmt.Fprintf(w, "SKIP  %s (%s)\n", r.Name, r.Skipped)
+		case r.Err != nil:
+			_, _ = fmt.Fprintf(w, "FAIL  %s\n        %v\n", r.Name, r.Err)
+		default:
+			status := "PASS"
+			if !r.Passed() {
+				status = "FAIL"
+			}
+			_, _ = fmt.Fprintf(w, "%s  %s (%d, %dms)\n", status, r.Name, r.Result.StatusCode, r.Result.DurationMS)
+			for _, f := range r.Failures {
+				_, _ = fmt.Fprintf(w, "        %s\n", f)
+			}
+		}
+	}
+
+	var (
+		reports []suite.Report
+		failed  int
+	)
+	for _, su := range suites {
+		_, _ = fmt.Fprintf(w, "%s\n", su.Name)
+		rep, err := runner.Run(c.Context, su)
+		if err != nil {
+			return err
+		}
+		p, f, sk := rep.Counts()
+		failed += f
+		_, _ = fmt.Fprintf(w, "%d passed, %d failed, %d skipped in %.2fs\n\n", p, f, sk, rep.Duration.Seconds())
+		reports = append(reports, rep)
+	}
+
+	if err := writeReport(c, c.String("junit"), func(out io.Writer) error { return suite.WriteJUnit(out, reports) }); err != nil {
+		return err
+	}
+	if err := writeReport(c, c.String("tap"), func(out io.Writer) error { return suite.WriteTAP(out, reports) }); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// writeReport writes a report to path, or stdout for "-". An empty path
+// writes nothing.
+func writeReport(c *cli.Context, path string, write func(io.Writer) error) error {
+	switch strings.TrimSpace(path) {
+	case "":
+		return nil
+	case "-":
+		return write(c.App.Writer)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// openScratchStore opens an empty store in a temporary directory, removed by
+// cleanup.
+func openScratchStore() (*store.Store, func(), error) {
+	dir, err := os.MkdirTemp("", "hooktm-test-")
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := store.Open(filepath.Join(dir, "fixtures.db"))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return s, func() {
+		_ = s.Close()
+		_ = os.RemoveAll(dir)
+	}, nil
+}
@@ -15,7 +15,10 @@ func newUICmd() *cli.Command {
 Navigation:
   ↑/↓ or j/k    Move up/down
   Enter         View details
-  /             Search
+  r             Replay selected webhook
+  R             Replay with a fresh signature (see replay --resign)
+  h             Toggle replay history
+  /             Search or filter (same query language as list --where)
   q             Quit`,
 		Action: runUI,
 	}
@@ -28,5 +31,9 @@ func runUI(c *cli.Context) error {
 	}
 	defer s.Close()
 
-	return tui.Run(c.Context, s, cfg.Forward)
+	providers, err := loadProviders(cfg)
+	if err != nil {
+		return err
+	}
+	return tui.Run(c.Context, s, cfg.Forward, newSigner(cfg, providers))
 }
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"hooktm/internal/signature"
+	"hooktm/internal/store"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newVerifyCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "verify",
+		Usage:     "Verify webhook signatures",
+		ArgsUsage: "<id> [id...]",
+		Description: `Check captured signatures against the provider secrets in config
+and store the result (shown by list, show and the TUI). An unverifiable
+result keeps the one stored at capture time.
+
+Timestamps (Stripe, Slack, Paddle) are checked against the time the webhook
+was captured, so old webhooks still verify.
+
+Config:
+  secrets:
+    stripe: whsec_...
+    github: my-secret
+  signature_tolerance: 5m
+  public_url: https://example.ngrok.app   # needed for Twilio
+
+Examples:
+  hooktm verify abc123
+  hooktm verify abc123 --secret whsec_test
+  hooktm verify abc123 def456 --json`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "secret", Usage: "Secret to use instead of the configured one"},
+			&cli.BoolFlag{Name: "json", Usage: "Output as JSON"},
+		},
+		Action: runVerify,
+	}
+}
+
+type verifyResult struct {
+	WebhookID string `json:"webhook_id"`
+	Provider  string `json:"provider"`
+	Valid     *bool  `json:"valid"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func runVerify(c *cli.Context) error {
+	if _, err := requireArg(c, 0, "id"); err != nil {
+		return err
+	}
+
+	s, cfg, err := openStoreFromContext(c)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	providers, err := loadProviders(cfg)
+	if err != nil {
+		return err
+	}
+	verifier := newVerifier(cfg, providers)
+	override := strings.TrimSpace(c.String("secret"))
+
+	var results []verifyResult
+	invalid := false
+	for _, id := range c.Args().Slice() {
+		wh, err := s.GetWebhook(c.Context, strings.TrimSpace(id))
+		if err != nil {
+			return err
+		}
+		if wh.BodySize > 0 {
+			if wh.Body, err = s.ReadBody(c.Context, wh.ID); err != nil {
+				return err
+			}
+		}
+		if override != "" {
+			verifier.Secrets = map[string]string{wh.Provider: override}
+		}
+		res := verifyWebhook(verifier, wh)
+		// An unverifiable result keeps what was recorded at capture time,
+		// when the proxy still knew the request host.
+		if res.Valid != nil {
+			if err := s.SetSignatureValid(c.Context, wh.ID, res.Valid); err != nil {
+				return err
+			}
+		}
+		if res.Valid != nil && !*res.Valid {
+			invalid = true
+		}
+		results = append(results, res)
+	}
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(c.App.Writer)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			_, _ = fmt.Fprintf(c.App.Writer, "%s  %s  %s\n", r.WebhookID, r.Provider, describeVerdict(r))
+		}
+	}
+
+	if invalid {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+func verifyWebhook(v *signature.Verifier, wh store.Webhook) verifyResult {
+	res := verifyResult{WebhookID: wh.ID, Provider: defaultString(wh.Provider, "unknown")}
+	valid, err := v.Verify(wh.Provider, signature.Request{
+		Method:     wh.Method,
+		Path:       wh.Path,
+		Query:      wh.Query,
+		Headers:    http.Header(wh.Headers),
+		Body:       wh.PlainBody(),
+		ReceivedAt: time.UnixMilli(wh.CreatedAt),
+		Host:       http.Header(wh.Headers).Get("Host"),
+	})
+	res.Valid = valid
+	if err != nil {
+		res.Reason = err.Error()
+	}
+	return res
+}
+
+func describeVerdict(r verifyResult) string {
+	switch {
+	case r.Valid == nil:
+		return "unverified: " + r.Reason
+	case *r.Valid:
+		return "valid"
+	default:
+		return "invalid: " + r.Reason
+	}
+}
+
+// signatureLabel renders a stored verification result for list output.
+func signatureLabel(valid *bool) string {
+	switch {
+	case valid == nil:
+		return ""
+	case *valid:
+		return "sig:ok"
+	default:
+		return "sig:bad"
+	}
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+
+	"hooktm/internal/provider"
+	"hooktm/internal/signature"
+	"hooktm/internal/store"
+)
+
+func TestVerifyWebhook_TwilioWithoutPublicURL(t *testing.T) {
+	v := &signature.Verifier{Providers: provider.Default(), Secrets: map[string]string{"twilio": "authtoken"}}
+	wh := store.Webhook{
+		ID:       "wh1",
+		Method:   "POST",
+		Path:     "/twilio",
+		Provider: "twilio",
+		Headers: map[string][]string{
+			"Content-Type":       {"application/x-www-form-urlencoded"},
+			"X-Twilio-Signature": {"c2lnbmF0dXJl"},
+		},
+		Body: []byte("CallSid=CA123"),
+	}
+
+	// The URL Twilio signed can't be rebuilt: unverified, not invalid, so
+	// the result stored at capture time is kept.
+	if res := verifyWebhook(v, wh); res.Valid != nil {
+		t.Fatalf("expected unverified, got %v (%s)", *res.Valid, res.Reason)
+	}
+
+	// An imported request carries its Host header.
+	wh.Headers["Host"] = []string{"example.ngrok.app"}
+	if res := verifyWebhook(v, wh); res.Valid == nil || *res.Valid {
+		t.Fatalf("expected invalid with a host, got %+v", res)
+	}
+}
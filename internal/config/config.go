@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"hooktm/internal/chaos"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,6 +18,77 @@ type Config struct {
 	Port    int    `yaml:"port"`
 	DBPath  string `yaml:"db"`
 	Lang    string `yaml:"lang"`
+
+	// ProvidersDir holds user provider definitions (default ~/.hooktm/providers).
+	ProvidersDir string `yaml:"providers_dir"`
+
+	// Secrets maps provider names to signing secrets (or public keys for
+	// Discord/SendGrid) used to verify captured signatures.
+	Secrets map[string]string `yaml:"secrets"`
+	// SignatureTolerance bounds signed timestamps (Stripe, Slack, Paddle).
+	SignatureTolerance time.Duration `yaml:"signature_tolerance"`
+	// PublicURL is the externally visible base URL providers call (Twilio).
+	PublicURL string `yaml:"public_url"`
+
+	// MockRules is a rules file deciding how record-only mode answers.
+	MockRules string `yaml:"mock_rules"`
+	// Chaos injects faults while forwarding (see chaos.Policy).
+	Chaos chaos.Policy `yaml:"chaos"`
+
+	// Routes send matching webhooks to one or more targets; the first match
+	// wins and unmatched webhooks go to Forward.
+	Routes []Route `yaml:"routes"`
+
+	// MaxBodySize is the largest request body listen accepts (default 10MB);
+	// BodyLimits overrides it per path prefix. Bodies over 10MB are spooled
+	// to SpoolDir (default: the OS temp dir) while they are captured.
+	MaxBodySize ByteSize            `yaml:"max_body_size"`
+	BodyLimits  map[string]ByteSize `yaml:"body_limits"`
+	SpoolDir    string              `yaml:"spool_dir"`
+}
+
+// ByteSize is a size in bytes, written in YAML as a number or with a unit:
+// 512KB, 10MB, 2GB (powers of 1024).
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalYAML(n *yaml.Node) error {
+	v, err := ParseByteSize(n.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	*b = ByteSize(v)
+	return nil
+}
+
+// ParseByteSize parses sizes such as "1048576", "512KB", "10MB" or "2GB".
+func ParseByteSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.mult
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(v * float64(mult)), nil
+}
+
+// Route maps webhooks to forward targets. Empty match fields match anything.
+type Route struct {
+	Path      string   `yaml:"path"` // path prefix
+	Provider  string   `yaml:"provider"`
+	EventType string   `yaml:"event_type"` // glob
+	Targets   []string `yaml:"targets"`
+	// Respond names the target whose response goes back to the caller
+	// (default: the first).
+	Respond string `yaml:"respond"`
 }
 
 func Load(path string) (*Config, error) {
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad_EmptyPath(t *testing.T) {
@@ -70,3 +71,110 @@ yaml content
 		t.Fatal("expected error for invalid YAML")
 	}
 }
+
+func TestLoad_Secrets(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `
+secrets:
+  stripe: whsec_test
+  github: s3cret
+signature_tolerance: 10m
+public_url: https://example.ngrok.app
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.Secrets["stripe"] != "whsec_test" || cfg.Secrets["github"] != "s3cret" {
+		t.Fatalf("Secrets=%v", cfg.Secrets)
+	}
+	if cfg.SignatureTolerance != 10*time.Minute {
+		t.Fatalf("SignatureTolerance=%v, want 10m", cfg.SignatureTolerance)
+	}
+	if cfg.PublicURL != "https://example.ngrok.app" {
+		t.Fatalf("PublicURL=%q", cfg.PublicURL)
+	}
+}
+
+func TestLoad_Chaos(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `
+mock_rules: /tmp/mock.yaml
+chaos:
+  drop: 5
+  latency: 500ms
+  duplicate: 12.5
+  paths: [/stripe/*]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.MockRules != "/tmp/mock.yaml" {
+		t.Fatalf("MockRules=%q", cfg.MockRules)
+	}
+	c := cfg.Chaos
+	if c.Drop != 5 || c.Latency != 500*time.Millisecond || c.Duplicate != 12.5 || len(c.Paths) != 1 || !c.Enabled() {
+		t.Fatalf("Chaos=%+v", c)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":  1024,
+		"512KB": 512 << 10,
+		"10MB":  10 << 20,
+		"1.5gb": 3 << 29,
+		"2G":    2 << 30,
+		"100B":  100,
+	}
+	for in, want := range tests {
+		got, err := ParseByteSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1KB", "ten"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q): expected error", in)
+		}
+	}
+}
+
+func TestLoad_BodyLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `
+max_body_size: 20MB
+body_limits:
+  /exports: 2GB
+spool_dir: /var/tmp
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.MaxBodySize != 20<<20 || cfg.BodyLimits["/exports"] != 2<<30 || cfg.SpoolDir != "/var/tmp" {
+		t.Fatalf("MaxBodySize=%d BodyLimits=%v SpoolDir=%q", cfg.MaxBodySize, cfg.BodyLimits, cfg.SpoolDir)
+	}
+
+	if err := os.WriteFile(configPath, []byte("max_body_size: lots\n"), 0644); err != nil {
+		t.Fatalf("WriteFile error: %v", err)
+	}
+	if _, err := Load(configPath); err == nil {
+		t.Fatal("expected error for invalid size")
+	}
+}
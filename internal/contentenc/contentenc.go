@@ -0,0 +1,103 @@
+// Package contentenc decodes and re-encodes request bodies sent with a
+// Content-Encoding (gzip, deflate, br).
+package contentenc
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// ErrTooLarge is returned when a decoded body exceeds the limit.
+var ErrTooLarge = errors.New("decoded body too large")
+
+// Codings returns the content codings of h in the order they were applied,
+// without "identity". An empty result means the body isn't encoded.
+func Codings(h http.Header) []string {
+	var out []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, c := range strings.Split(v, ",") {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if c != "" && c != "identity" {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// Decode undoes codings (last applied first) and returns at most limit bytes;
+// longer bodies fail with ErrTooLarge.
+func Decode(codings []string, body []byte, limit int64) ([]byte, error) {
+	for i := len(codings) - 1; i >= 0; i-- {
+		r, err := newReader(codings[i], body)
+		if err != nil {
+			return nil, err
+		}
+		out, err := io.ReadAll(io.LimitReader(r, limit+1))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", codings[i], err)
+		}
+		if int64(len(out)) > limit {
+			return nil, ErrTooLarge
+		}
+		body = out
+	}
+	return body, nil
+}
+
+// Encode applies codings in order, the inverse of Decode.
+func Encode(codings []string, body []byte) ([]byte, error) {
+	for _, c := range codings {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch c {
+		case "gzip", "x-gzip":
+			w = gzip.NewWriter(&buf)
+		case "deflate":
+			w = zlib.NewWriter(&buf)
+		case "br":
+			w = brotli.NewWriter(&buf)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", c)
+		}
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+	}
+	return body, nil
+}
+
+func newReader(coding string, body []byte) (io.Reader, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return r, nil
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some senders use raw
+		// deflate streams.
+		if r, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			return r, nil
+		}
+		return flate.NewReader(bytes.NewReader(body)), nil
+	case "br":
+		return brotli.NewReader(bytes.NewReader(body)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", coding)
+	}
+}
@@ -0,0 +1,64 @@
+package contentenc
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCodings(t *testing.T) {
+	h := http.Header{}
+	h.Add("Content-Encoding", "identity, GZIP")
+	h.Add("Content-Encoding", "br")
+	if got := Codings(h); !reflect.DeepEqual(got, []string{"gzip", "br"}) {
+		t.Fatalf("Codings=%v", got)
+	}
+	if got := Codings(http.Header{}); got != nil {
+		t.Fatalf("Codings=%v", got)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	body := []byte(`{"type":"invoice.paid","data":{"id":"in_123"}}`)
+	for _, codings := range [][]string{{"gzip"}, {"deflate"}, {"br"}, {"gzip", "br"}} {
+		enc, err := Encode(codings, body)
+		if err != nil {
+			t.Fatalf("%v: Encode: %v", codings, err)
+		}
+		if bytes.Equal(enc, body) {
+			t.Fatalf("%v: body not encoded", codings)
+		}
+		dec, err := Decode(codings, enc, 1<<20)
+		if err != nil || !bytes.Equal(dec, body) {
+			t.Fatalf("%v: Decode=%q, %v", codings, dec, err)
+		}
+	}
+
+	if _, err := Decode([]string{"compress"}, body, 1<<20); err == nil {
+		t.Fatalf("expected error for unsupported coding")
+	}
+	if _, err := Decode([]string{"gzip"}, body, 1<<20); err == nil {
+		t.Fatalf("expected error for invalid gzip")
+	}
+}
+
+func TestDecode_RawDeflate(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	_, _ = w.Write([]byte("hello"))
+	_ = w.Close()
+	got, err := Decode([]string{"deflate"}, buf.Bytes(), 100)
+	if err != nil || string(got) != "hello" {
+		t.Fatalf("Decode=%q, %v", got, err)
+	}
+}
+
+func TestDecode_Limit(t *testing.T) {
+	enc, _ := Encode([]string{"gzip"}, bytes.Repeat([]byte("a"), 10_000))
+	if _, err := Decode([]string{"gzip"}, enc, 1000); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err=%v, want ErrTooLarge", err)
+	}
+}
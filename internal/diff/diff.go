@@ -0,0 +1,214 @@
+// Package diff compares two webhook payloads: a structural diff of JSON (or
+// form) bodies by path, and a diff of request headers.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"hooktm/internal/form"
+)
+
+// Kinds of change.
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is one difference. Path uses the `body.<path>` syntax of list
+// --where ("data.items[0].id"; "" is the whole body) or a header name.
+type Change struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// Result is the diff of two webhooks.
+type Result struct {
+	Headers []Change `json:"headers"`
+	Body    []Change `json:"body"`
+}
+
+// Empty reports whether there are no differences.
+func (r Result) Empty() bool { return len(r.Headers) == 0 && len(r.Body) == 0 }
+
+// Side is one webhook being compared.
+type Side struct {
+	Headers map[string][]string
+	Body    []byte
+}
+
+// Compare diffs the headers and bodies of a and b.
+func Compare(a, b Side) Result {
+	return Result{
+		Headers: Headers(a.Headers, b.Headers),
+		Body:    Body(a.Headers, a.Body, b.Headers, b.Body),
+	}
+}
+
+// Headers diffs two header sets. Names are compared canonically; repeated
+// values are joined with ", ".
+func Headers(a, b map[string][]string) []Change {
+	ha, hb := canonical(a), canonical(b)
+	names := make(map[string]bool)
+	for k := range ha {
+		names[k] = true
+	}
+	for k := range hb {
+		names[k] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out []Change
+	for _, k := range sorted {
+		va, okA := ha[k]
+		vb, okB := hb[k]
+		switch {
+		case !okA:
+			out = append(out, Change{Path: k, Kind: Added, New: vb})
+		case !okB:
+			out = append(out, Change{Path: k, Kind: Removed, Old: va})
+		case va != vb:
+			out = append(out, Change{Path: k, Kind: Changed, Old: va, New: vb})
+		}
+	}
+	return out
+}
+
+func canonical(h map[string][]string) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, vs := range h {
+		k = http.CanonicalHeaderKey(k)
+		if prev, ok := out[k]; ok {
+			vs = append([]string{prev}, vs...)
+		}
+		out[k] = strings.Join(vs, ", ")
+	}
+	return out
+}
+
+// Body diffs two bodies. JSON and form bodies are compared by path; other
+// bodies are compared whole.
+func Body(ha map[string][]string, a []byte, hb map[string][]string, b []byte) []Change {
+	return Values(decode(ha, a), decode(hb, b))
+}
+
+// decode returns a JSON value for a body: parsed JSON, form values, text or,
+// for binary bodies, a size note.
+func decode(h map[string][]string, b []byte) any {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		return v
+	}
+	ct := http.Header(h).Get("Content-Type")
+	if f, err := form.Parse(ct, b); err == nil && f != nil {
+		return f.Values()
+	}
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return fmt.Sprintf("(%d bytes binary)", len(b))
+}
+
+// Values diffs two decoded JSON values. Object keys are visited in sorted
+// order; arrays are compared by index.
+func Values(a, b any) []Change {
+	var out []Change
+	walk("", a, b, &out)
+	return out
+}
+
+func walk(path string, a, b any, out *[]Change) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*out = append(*out, Change{Path: path, Kind: Added, New: b})
+		return
+	case b == nil:
+		*out = append(*out, Change{Path: path, Kind: Removed, Old: a})
+		return
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walk(join(path, k), av[k], bv[k], out)
+		}
+		return
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(av), len(bv)); i++ {
+			var x, y any
+			if i < len(av) {
+				x = av[i]
+			}
+			if i < len(bv) {
+				y = bv[i]
+			}
+			walk(fmt.Sprintf("%s[%d]", path, i), x, y, out)
+		}
+		return
+	}
+	if !equal(a, b) {
+		*out = append(*out, Change{Path: path, Kind: Changed, Old: a, New: b})
+	}
+}
+
+func equal(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// join appends a key to a path, quoting keys that contain path separators.
+func join(path, key string) string {
+	if strings.ContainsAny(key, ".[]") || key == "" {
+		key = `"` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Format renders a value from a Change as one line of JSON.
+func Format(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBody_JSON(t *testing.T) {
+	h := map[string][]string{"Content-Type": {"application/json"}}
+	a := []byte(`{"type":"invoice.paid","data":{"amount":5000,"lines":[1,2],"old":true},"a.b":1}`)
+	b := []byte(`{"type":"invoice.paid","data":{"amount":7000,"lines":[1],"new":"x"},"a.b":1}`)
+
+	got := Body(h, a, h, b)
+	want := []Change{
+		{Path: "data.amount", Kind: Changed, Old: json.Number("5000"), New: json.Number("7000")},
+		{Path: "data.lines[1]", Kind: Removed, Old: json.Number("2")},
+		{Path: "data.new", Kind: Added, New: "x"},
+		{Path: "data.old", Kind: Removed, Old: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestBody_FormAndText(t *testing.T) {
+	h := map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}}
+	got := Body(h, []byte("Body=Hi&From=1"), h, []byte("Body=Bye&From=1"))
+	want := []Change{{Path: "Body", Kind: Changed, Old: "Hi", New: "Bye"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("form: got %#v", got)
+	}
+
+	got = Body(nil, []byte("ping"), nil, []byte("pong"))
+	want = []Change{{Path: "", Kind: Changed, Old: "ping", New: "pong"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("text: got %#v", got)
+	}
+	if got := Body(nil, []byte(`{"a":1}`), nil, []byte(`{ "a": 1 }`)); len(got) != 0 {
+		t.Fatalf("formatting only: %#v", got)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	a := map[string][]string{"x-github-delivery": {"d-1"}, "User-Agent": {"GitHub-Hookshot/abc"}, "X-Old": {"1"}}
+	b := map[string][]string{"X-Github-Delivery": {"d-2"}, "User-Agent": {"GitHub-Hookshot/abc"}, "Accept": {"a", "b"}}
+	got := Headers(a, b)
+	want := []Change{
+		{Path: "Accept", Kind: Added, New: "a, b"},
+		{Path: "X-Github-Delivery", Kind: Changed, Old: "d-1", New: "d-2"},
+		{Path: "X-Old", Kind: Removed, Old: "1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %#v\nwant %#v", got, want)
+	}
+}
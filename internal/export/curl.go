@@ -0,0 +1,49 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"hooktm/internal/store"
+)
+
+// writeCurl writes a POSIX shell script with one curl command per webhook.
+// Bodies are sent byte for byte: text inline, binary through base64 -d. So
+// are bodies curl or the shell can't take as an argument: a leading @ names
+// a file to curl, and a NUL ends the argument.
+func writeCurl(w io.Writer, opts Options, webhooks []store.Webhook) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "#!/bin/sh\n# %s: %d webhook(s) exported by hooktm.\n", opts.Name, len(webhooks))
+	fmt.Fprintf(bw, "# Override the target with BASE_URL=https://example.com ./script.sh\nset -e\n")
+	fmt.Fprintf(bw, "BASE_URL=\"${BASE_URL:-%s}\"\n", opts.BaseURL)
+	for _, wh := range webhooks {
+		fmt.Fprintf(bw, "\n# %s  %s\n", title(wh), time.UnixMilli(wh.CreatedAt).UTC().Format(time.RFC3339))
+		binary := len(wh.Body) > 0 && (!utf8.Valid(wh.Body) || wh.Body[0] == '@' || bytes.IndexByte(wh.Body, 0) >= 0)
+		if binary {
+			fmt.Fprintf(bw, "printf '%%s' %s | base64 -d | ", shellQuote(base64.StdEncoding.EncodeToString(wh.Body)))
+		}
+		fmt.Fprintf(bw, "curl -sS -X %s \"$BASE_URL\"%s", wh.Method, shellQuote(requestURL("", wh)))
+		for _, h := range headers(wh, false) {
+			fmt.Fprintf(bw, " \\\n  -H %s", shellQuote(h.Name+": "+h.Value))
+		}
+		switch {
+		case binary:
+			fmt.Fprintf(bw, " \\\n  --data-binary @-")
+		case len(wh.Body) > 0:
+			fmt.Fprintf(bw, " \\\n  --data-binary %s", shellQuote(string(wh.Body)))
+		}
+		fmt.Fprintf(bw, "\necho\n")
+	}
+	return bw.Flush()
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,142 @@
+// Package export writes captured webhooks in formats other tools can send
+// again: Postman collections, Insomnia exports, HAR files and curl scripts.
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"hooktm/internal/store"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{"postman", "insomnia", "har", "curl"}
+
+// DefaultBaseURL is used when no base URL is given.
+const DefaultBaseURL = "http://localhost:3000"
+
+// Options apply to every format.
+type Options struct {
+	// Name of the collection or workspace.
+	Name string
+	// BaseURL is prepended to each webhook's path. Postman, Insomnia and curl
+	// keep it in a variable so it can be changed after import.
+	BaseURL string
+	// Version of hooktm, recorded as the HAR creator.
+	Version string
+}
+
+// Write exports webhooks in format. Chunked bodies must be loaded into Body
+// by the caller.
+func Write(w io.Writer, format string, opts Options, webhooks []store.Webhook) error {
+	if opts.Name == "" {
+		opts.Name = "HookTM export"
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = DefaultBaseURL
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	switch strings.ToLower(format) {
+	case "postman":
+		return writePostman(w, opts, webhooks)
+	case "insomnia":
+		return writeInsomnia(w, opts, webhooks)
+	case "har":
+		return writeHAR(w, opts, webhooks)
+	case "curl":
+		return writeCurl(w, opts, webhooks)
+	default:
+		return fmt.Errorf("unknown format: %s (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+type header struct {
+	Name  string
+	Value string
+}
+
+// skipHeaders are recomputed by the client sending the request.
+var skipHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Host":              true,
+	"Connection":        true,
+	"Transfer-Encoding": true,
+}
+
+// headers returns the request headers to send, sorted by name. With decoded
+// set, Content-Encoding is dropped because the body is sent decompressed.
+func headers(wh store.Webhook, decoded bool) []header {
+	names := make([]string, 0, len(wh.Headers))
+	for k := range wh.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var out []header
+	for _, k := range names {
+		ck := http.CanonicalHeaderKey(k)
+		if skipHeaders[ck] || (decoded && ck == "Content-Encoding") {
+			continue
+		}
+		for _, v := range wh.Headers[k] {
+			out = append(out, header{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+// textBody returns the body as text for formats that can't carry bytes:
+// compressed webhooks are sent decoded. ok is false for binary bodies.
+func textBody(wh store.Webhook) (body string, decoded, ok bool) {
+	b := wh.PlainBody()
+	if !utf8.Valid(b) {
+		return "", wh.DecodedBody != nil, false
+	}
+	return string(b), wh.DecodedBody != nil, true
+}
+
+// queryPairs splits a raw query in order, keeping repeated keys.
+func queryPairs(raw string) []header {
+	var out []header
+	for _, pair := range strings.Split(strings.TrimPrefix(raw, "?"), "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		out = append(out, header{Name: k, Value: v})
+	}
+	return out
+}
+
+// requestURL joins base, path and the raw query.
+func requestURL(base string, wh store.Webhook) string {
+	u := base + "/" + strings.TrimLeft(wh.Path, "/")
+	if q := strings.TrimPrefix(wh.Query, "?"); q != "" {
+		u += "?" + q
+	}
+	return u
+}
+
+// title names an exported request after its event, falling back to the
+// request line.
+func title(wh store.Webhook) string {
+	name := wh.Method + " " + wh.Path
+	if wh.Provider != "" && wh.EventType != "" {
+		name = wh.Provider + " " + wh.EventType
+	}
+	return name + " (" + wh.ID + ")"
+}
+
+func contentType(wh store.Webhook) string {
+	return http.Header(wh.Headers).Get("Content-Type")
+}
@@ -0,0 +1,226 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"hooktm/internal/contentenc"
+	"hooktm/internal/store"
+)
+
+func testWebhooks(t *testing.T) []store.Webhook {
+	t.Helper()
+	gz, err := contentenc.Encode([]string{"gzip"}, []byte(`{"zipped":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := 200
+	return []store.Webhook{
+		{
+			ID: "wh1", CreatedAt: 1705312200000, Method: "POST", Path: "/hooks/stripe", Query: "a=1&a=2&b=x%20y",
+			Headers: map[string][]string{
+				"Content-Type":     {"application/json"},
+				"Content-Length":   {"40"},
+				"Stripe-Signature": {"t=1,v1=abc"},
+			},
+			Body:       []byte(`{"type":"invoice.paid","note":"it's"}`),
+			Provider:   "stripe",
+			EventType:  "invoice.paid",
+			StatusCode: &status,
+			ResponseMS: 12,
+			ResponseHeaders: map[string][]string{
+				"Content-Type": {"text/plain"},
+			},
+			ResponseBody: []byte("ok"),
+		},
+		{
+			ID: "wh2", CreatedAt: 1705312260000, Method: "POST", Path: "/gz",
+			Headers: map[string][]string{
+				"Content-Type":     {"application/json"},
+				"Content-Encoding": {"gzip"},
+			},
+			Body:        gz,
+			DecodedBody: []byte(`{"zipped":true}`),
+		},
+	}
+}
+
+func TestWrite_Postman(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "postman", Options{BaseURL: "http://app:3000/"}, testWebhooks(t)); err != nil {
+		t.Fatal(err)
+	}
+	var c postmanCollection
+	if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Info.Schema != postmanSchema || len(c.Item) != 2 || c.Variable[0].Value != "http://app:3000" {
+		t.Fatalf("unexpected collection: %+v", c)
+	}
+	r := c.Item[0].Request
+	if c.Item[0].Name != "stripe invoice.paid (wh1)" || r.URL.Raw != "{{baseUrl}}/hooks/stripe?a=1&a=2&b=x%20y" {
+		t.Fatalf("unexpected item: %+v", c.Item[0])
+	}
+	if len(r.URL.Query) != 3 || r.URL.Query[2] != (postmanKeyVal{Key: "b", Value: "x y"}) {
+		t.Fatalf("query: %+v", r.URL.Query)
+	}
+	// Content-Length is left to the client.
+	if len(r.Header) != 2 || r.Header[1].Key != "Stripe-Signature" || r.Body.Raw != `{"type":"invoice.paid","note":"it's"}` {
+		t.Fatalf("request: %+v", r)
+	}
+	// Compressed bodies go out decoded, without Content-Encoding.
+	r = c.Item[1].Request
+	if r.Body.Raw != `{"zipped":true}` || len(r.Header) != 1 {
+		t.Fatalf("compressed request: %+v", r)
+	}
+}
+
+func TestWrite_Insomnia(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "insomnia", Options{}, testWebhooks(t)); err != nil {
+		t.Fatal(err)
+	}
+	var ex insomniaExport
+	if err := json.Unmarshal(buf.Bytes(), &ex); err != nil {
+		t.Fatal(err)
+	}
+	if ex.ExportFormat != 4 || len(ex.Resources) != 4 || ex.Resources[1].Data["base_url"] != DefaultBaseURL {
+		t.Fatalf("unexpected export: %+v", ex)
+	}
+	req := ex.Resources[2]
+	if req.Type != "request" || req.URL != "{{ _.base_url }}/hooks/stripe" || len(req.Parameters) != 3 || req.Body.MimeType != "application/json" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestWrite_HAR(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "har", Options{BaseURL: "http://app:3000", Version: "0.2.0"}, testWebhooks(t)); err != nil {
+		t.Fatal(err)
+	}
+	var f harFile
+	if err := json.Unmarshal(buf.Bytes(), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Log.Version != "1.2" || f.Log.Creator.Version != "0.2.0" || len(f.Log.Entries) != 2 {
+		t.Fatalf("unexpected log: %+v", f.Log)
+	}
+	e := f.Log.Entries[0]
+	if e.Request.URL != "http://app:3000/hooks/stripe?a=1&a=2&b=x%20y" || e.StartedDateTime != "2024-01-15T09:50:00.000Z" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e.Response.Status != 200 || e.Response.Content.Text != "ok" || e.Request.PostData.Encoding != "" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	// The compressed body is kept as sent, base64-encoded.
+	e = f.Log.Entries[1]
+	if e.Request.PostData.Encoding != "base64" || e.Request.Headers[0].Name != "Content-Encoding" {
+		t.Fatalf("unexpected compressed entry: %+v", e.Request)
+	}
+}
+
+func TestWrite_CurlSendsBytesAsCaptured(t *testing.T) {
+	if _, err := exec.LookPath("curl"); err != nil {
+		t.Skip("curl not installed")
+	}
+	whs := testWebhooks(t)
+	var (
+		mu  sync.Mutex
+		got []*http.Request
+		raw [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got, raw = append(got, r), append(raw, b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "curl", Options{}, whs); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(t.TempDir(), "replay.sh")
+	if err := os.WriteFile(script, buf.Bytes(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", script)
+	cmd.Env = append(os.Environ(), "BASE_URL="+srv.URL)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("script: %v\n%s\n%s", err, out, buf.String())
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d requests", len(got))
+	}
+	for i, wh := range whs {
+		if !bytes.Equal(raw[i], wh.Body) || got[i].URL.Path != wh.Path || got[i].URL.RawQuery != wh.Query {
+			t.Fatalf("request %d: %s %q", i, got[i].URL, raw[i])
+		}
+		for k, vs := range wh.Headers {
+			if k != "Content-Length" && got[i].Header.Get(k) != vs[0] {
+				t.Fatalf("request %d header %s=%q", i, k, got[i].Header.Get(k))
+			}
+		}
+	}
+	if !strings.HasPrefix(buf.String(), "#!/bin/sh\n") {
+		t.Fatalf("missing shebang:\n%s", buf.String())
+	}
+}
+
+func TestWrite_CurlAtSignAndNULBodies(t *testing.T) {
+	whs := []store.Webhook{
+		{ID: "at", Method: "POST", Path: "/a", Headers: map[string][]string{"Content-Type": {"text/plain"}}, Body: []byte("@/etc/passwd")},
+		{ID: "nul", Method: "POST", Path: "/b", Headers: map[string][]string{"Content-Type": {"text/plain"}}, Body: []byte("a\x00b")},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "curl", Options{}, whs); err != nil {
+		t.Fatal(err)
+	}
+	script := buf.String()
+	// Both go through base64, never as a curl argument.
+	if strings.Contains(script, "--data-binary '") || strings.Count(script, "--data-binary @-") != 2 || strings.Contains(script, "\x00") {
+		t.Fatalf("bodies passed inline:\n%s", script)
+	}
+
+	if _, err := exec.LookPath("curl"); err != nil {
+		return
+	}
+	var raw [][]byte
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		raw = append(raw, b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "replay.sh")
+	if err := os.WriteFile(path, buf.Bytes(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", path)
+	cmd.Env = append(os.Environ(), "BASE_URL="+srv.URL)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("script: %v\n%s", err, out)
+	}
+	if len(raw) != 2 || !bytes.Equal(raw[0], whs[0].Body) || !bytes.Equal(raw[1], whs[1].Body) {
+		t.Fatalf("bodies sent: %q", raw)
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(io.Discard, "xml", Options{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"hooktm/internal/store"
+)
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int64       `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harPostData carries the body as sent. Binary (including compressed)
+// bodies are base64 with encoding set, as HAR does for response content.
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
+}
+
+// writeHAR writes a HAR 1.2 log with absolute URLs and the captured
+// response of each webhook. Request bodies are the bytes as sent.
+func writeHAR(w io.Writer, opts Options, webhooks []store.Webhook) error {
+	f := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "hooktm", Version: opts.Version},
+		Entries: []harEntry{},
+	}}
+	for _, wh := range webhooks {
+		e := harEntry{
+			StartedDateTime: time.UnixMilli(wh.CreatedAt).UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Time:            wh.ResponseMS,
+			Request: harRequest{
+				Method:      wh.Method,
+				URL:         requestURL(opts.BaseURL, wh),
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harPair{},
+				Headers:     []harPair{},
+				QueryString: []harPair{},
+				HeadersSize: -1,
+				BodySize:    len(wh.Body),
+			},
+			Response: harResponse{
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []harPair{},
+				Headers:     []harPair{},
+				HeadersSize: -1,
+				BodySize:    len(wh.ResponseBody),
+				Content: harContent{
+					Size:     len(wh.ResponseBody),
+					MimeType: http.Header(wh.ResponseHeaders).Get("Content-Type"),
+				},
+			},
+			Timings: harTimings{Wait: wh.ResponseMS},
+			Comment: wh.ID,
+		}
+		for _, h := range headers(wh, false) {
+			e.Request.Headers = append(e.Request.Headers, harPair(h))
+		}
+		for _, q := range queryPairs(wh.Query) {
+			e.Request.QueryString = append(e.Request.QueryString, harPair(q))
+		}
+		if len(wh.Body) > 0 {
+			e.Request.PostData = &harPostData{MimeType: contentType(wh)}
+			e.Request.PostData.Text, e.Request.PostData.Encoding = harText(wh.Body)
+		}
+		if wh.StatusCode != nil {
+			e.Response.Status = *wh.StatusCode
+			e.Response.StatusText = http.StatusText(*wh.StatusCode)
+		}
+		for _, h := range headers(store.Webhook{Headers: wh.ResponseHeaders}, false) {
+			e.Response.Headers = append(e.Response.Headers, harPair(h))
+		}
+		e.Response.Content.Text, e.Response.Content.Encoding = harText(wh.ResponseBody)
+		f.Log.Entries = append(f.Log.Entries, e)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+func harText(b []byte) (text, encoding string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"hooktm/internal/store"
+)
+
+type insomniaExport struct {
+	Type         string             `json:"_type"`
+	ExportFormat int                `json:"__export_format"`
+	ExportDate   string             `json:"__export_date"`
+	ExportSource string             `json:"__export_source"`
+	Resources    []insomniaResource `json:"resources"`
+}
+
+// insomniaResource is a workspace, environment or request; unused fields
+// are omitted.
+type insomniaResource struct {
+	ID          string            `json:"_id"`
+	Type        string            `json:"_type"`
+	ParentID    *string           `json:"parentId"`
+	Name        string            `json:"name"`
+	Scope       string            `json:"scope,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	Method      string            `json:"method,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Parameters  []insomniaPair    `json:"parameters,omitempty"`
+	Headers     []insomniaPair    `json:"headers,omitempty"`
+	Body        *insomniaBody     `json:"body,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+type insomniaPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type insomniaBody struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// writeInsomnia writes an export (format 4) with one workspace; the base URL
+// lives in its base environment as _.base_url.
+func writeInsomnia(w io.Writer, opts Options, webhooks []store.Webhook) error {
+	workspace := "wrk_hooktm"
+	ex := insomniaExport{
+		Type:         "export",
+		ExportFormat: 4,
+		ExportDate:   time.Now().UTC().Format(time.RFC3339),
+		ExportSource: "hooktm",
+		Resources: []insomniaResource{
+			{ID: workspace, Type: "workspace", Name: opts.Name, Scope: "collection"},
+			{ID: "env_hooktm", Type: "environment", ParentID: &workspace, Name: "Base Environment", Data: map[string]string{"base_url": opts.BaseURL}},
+		},
+	}
+	for _, wh := range webhooks {
+		body, decoded, ok := textBody(wh)
+		r := insomniaResource{
+			ID:          "req_" + wh.ID,
+			Type:        "request",
+			ParentID:    &workspace,
+			Name:        title(wh),
+			Method:      wh.Method,
+			URL:         requestURL("{{ _.base_url }}", store.Webhook{Path: wh.Path}),
+			Description: description(wh, ok),
+		}
+		for _, q := range queryPairs(wh.Query) {
+			r.Parameters = append(r.Parameters, insomniaPair(q))
+		}
+		for _, h := range headers(wh, decoded) {
+			r.Headers = append(r.Headers, insomniaPair(h))
+		}
+		if ok && body != "" {
+			r.Body = &insomniaBody{MimeType: contentType(wh), Text: body}
+		}
+		ex.Resources = append(ex.Resources, r)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ex)
+}
@@ -0,0 +1,106 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"hooktm/internal/store"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanCollection struct {
+	Info     postmanInfo     `json:"info"`
+	Item     []postmanItem   `json:"item"`
+	Variable []postmanKeyVal `json:"variable"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name    string         `json:"name"`
+	Request postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method      string          `json:"method"`
+	Header      []postmanKeyVal `json:"header"`
+	Body        *postmanBody    `json:"body,omitempty"`
+	URL         postmanURL      `json:"url"`
+	Description string          `json:"description,omitempty"`
+}
+
+type postmanKeyVal struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type postmanBody struct {
+	Mode    string         `json:"mode"`
+	Raw     string         `json:"raw"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+type postmanURL struct {
+	Raw   string          `json:"raw"`
+	Host  []string        `json:"host"`
+	Path  []string        `json:"path,omitempty"`
+	Query []postmanKeyVal `json:"query,omitempty"`
+}
+
+// writePostman writes a v2.1 collection; {{baseUrl}} is a collection variable.
+func writePostman(w io.Writer, opts Options, webhooks []store.Webhook) error {
+	c := postmanCollection{
+		Info:     postmanInfo{Name: opts.Name, Schema: postmanSchema},
+		Item:     []postmanItem{},
+		Variable: []postmanKeyVal{{Key: "baseUrl", Value: opts.BaseURL}},
+	}
+	for _, wh := range webhooks {
+		body, decoded, ok := textBody(wh)
+		req := postmanRequest{
+			Method:      wh.Method,
+			Header:      []postmanKeyVal{},
+			Description: description(wh, ok),
+			URL: postmanURL{
+				Raw:  requestURL("{{baseUrl}}", wh),
+				Host: []string{"{{baseUrl}}"},
+			},
+		}
+		for _, h := range headers(wh, decoded) {
+			req.Header = append(req.Header, postmanKeyVal{Key: h.Name, Value: h.Value})
+		}
+		for _, seg := range strings.Split(strings.Trim(wh.Path, "/"), "/") {
+			if seg != "" {
+				req.URL.Path = append(req.URL.Path, seg)
+			}
+		}
+		for _, q := range queryPairs(wh.Query) {
+			req.URL.Query = append(req.URL.Query, postmanKeyVal{Key: q.Name, Value: q.Value})
+		}
+		if ok && body != "" {
+			req.Body = &postmanBody{Mode: "raw", Raw: body}
+			if strings.Contains(contentType(wh), "json") {
+				req.Body.Options = map[string]any{"raw": map[string]string{"language": "json"}}
+			}
+		}
+		c.Item = append(c.Item, postmanItem{Name: title(wh), Request: req})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// description notes where a request came from, and a body that couldn't be
+// exported as text.
+func description(wh store.Webhook, textOK bool) string {
+	d := "Captured by hooktm as " + wh.ID
+	if !textOK {
+		d += fmt.Sprintf(". The %d-byte binary body is not included; use the HAR or curl export", len(wh.PlainBody()))
+	}
+	return d
+}
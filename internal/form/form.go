@@ -0,0 +1,281 @@
+// Package form decodes url-encoded and multipart/form-data webhook bodies
+// into fields and file attachments, and encodes them back for replays.
+package form
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	URLEncoded = "application/x-www-form-urlencoded"
+	Multipart  = "multipart/form-data"
+)
+
+// Field is one decoded form value. Repeated names keep one Field per value.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Attachment is a multipart part sent as a file.
+type Attachment struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	// Data is only set by Parse; stored attachments keep metadata only since
+	// the raw body already holds the content.
+	Data []byte `json:"-"`
+}
+
+// Form is a decoded body, in the order fields appeared.
+type Form struct {
+	Fields      []Field
+	Attachments []Attachment
+}
+
+// IsForm reports whether contentType is url-encoded or multipart/form-data.
+func IsForm(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == URLEncoded || mt == Multipart)
+}
+
+// Parse decodes a form body. It returns nil, nil for other content types.
+func Parse(contentType string, body []byte) (*Form, error) {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil
+	}
+	switch mt {
+	case URLEncoded:
+		return parseURLEncoded(body)
+	case Multipart:
+		return parseMultipart(params["boundary"], body)
+	default:
+		return nil, nil
+	}
+}
+
+func parseURLEncoded(body []byte) (*Form, error) {
+	f := &Form{}
+	// url.ParseQuery loses the order, so split pairs by hand.
+	for _, pair := range strings.Split(string(body), "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(k)
+		if err != nil {
+			return nil, fmt.Errorf("form: %w", err)
+		}
+		value, err := url.QueryUnescape(v)
+		if err != nil {
+			return nil, fmt.Errorf("form: %w", err)
+		}
+		f.Fields = append(f.Fields, Field{Name: name, Value: value})
+	}
+	return f, nil
+}
+
+func parseMultipart(boundary string, body []byte) (*Form, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("form: multipart body without boundary")
+	}
+	f := &Form{}
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return f, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("form: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("form: %w", err)
+		}
+		if part.FileName() != "" {
+			f.Attachments = append(f.Attachments, Attachment{
+				Field:       part.FormName(),
+				Filename:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Size:        int64(len(data)),
+				Data:        data,
+			})
+		} else {
+			f.Fields = append(f.Fields, Field{Name: part.FormName(), Value: string(data)})
+		}
+		_ = part.Close()
+	}
+}
+
+// Values returns the fields as a map; repeated names map to a list.
+func (f *Form) Values() map[string]any {
+	out := make(map[string]any, len(f.Fields))
+	for _, fl := range f.Fields {
+		switch cur := out[fl.Name].(type) {
+		case nil:
+			out[fl.Name] = fl.Value
+		case string:
+			out[fl.Name] = []any{cur, fl.Value}
+		case []any:
+			out[fl.Name] = append(cur, fl.Value)
+		}
+	}
+	return out
+}
+
+// Text renders the form for full-text search: one "name: value" line per
+// field and one line per attachment with its file name and type.
+func (f *Form) Text() string {
+	var b strings.Builder
+	for _, fl := range f.Fields {
+		fmt.Fprintf(&b, "%s: %s\n", fl.Name, fl.Value)
+	}
+	for _, a := range f.Attachments {
+		fmt.Fprintf(&b, "%s: %s %s\n", a.Field, a.Filename, a.ContentType)
+	}
+	return b.String()
+}
+
+// Encode writes the form back as contentType. Multipart bodies reuse the
+// boundary from contentType, so the original Content-Type header stays valid;
+// fields are written before attachments.
+func Encode(contentType string, f *Form) ([]byte, error) {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("form: %w", err)
+	}
+	switch mt {
+	case URLEncoded:
+		pairs := make([]string, 0, len(f.Fields))
+		for _, fl := range f.Fields {
+			pairs = append(pairs, url.QueryEscape(fl.Name)+"="+url.QueryEscape(fl.Value))
+		}
+		return []byte(strings.Join(pairs, "&")), nil
+	case Multipart:
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		if err := mw.SetBoundary(params["boundary"]); err != nil {
+			return nil, fmt.Errorf("form: %w", err)
+		}
+		for _, fl := range f.Fields {
+			if err := mw.WriteField(fl.Name, fl.Value); err != nil {
+				return nil, err
+			}
+		}
+		for _, a := range f.Attachments {
+			h := make(textproto.MIMEHeader)
+			h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": a.Field, "filename": a.Filename}))
+			if a.ContentType != "" {
+				h.Set("Content-Type", a.ContentType)
+			}
+			w, err := mw.CreatePart(h)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(a.Data); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("form: unsupported content type %q", mt)
+	}
+}
+
+// MergePatch applies a JSON merge patch (RFC 7396) to the fields: null
+// removes a field, a scalar sets it and a list sets repeated values. Fields
+// keep their position; new ones are appended in name order. Attachments are
+// left as they are.
+func (f *Form) MergePatch(patch []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.UseNumber()
+	var p map[string]any
+	if err := dec.Decode(&p); err != nil {
+		return fmt.Errorf("form patch must be a JSON object: %w", err)
+	}
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var values []string
+		switch v := p[name].(type) {
+		case nil:
+		case []any:
+			for _, item := range v {
+				s, err := scalar(name, item)
+				if err != nil {
+					return err
+				}
+				values = append(values, s)
+			}
+		default:
+			s, err := scalar(name, v)
+			if err != nil {
+				return err
+			}
+			values = []string{s}
+		}
+		f.set(name, values)
+	}
+	return nil
+}
+
+// set replaces every value of name with values, at the first field's position.
+func (f *Form) set(name string, values []string) {
+	at := len(f.Fields)
+	kept := f.Fields[:0]
+	for _, fl := range f.Fields {
+		if fl.Name == name {
+			if at == len(f.Fields) {
+				at = len(kept)
+			}
+			continue
+		}
+		kept = append(kept, fl)
+	}
+	if at > len(kept) {
+		at = len(kept)
+	}
+	repl := make([]Field, 0, len(values))
+	for _, v := range values {
+		repl = append(repl, Field{Name: name, Value: v})
+	}
+	out := make([]Field, 0, len(kept)+len(repl))
+	out = append(out, kept[:at]...)
+	out = append(out, repl...)
+	f.Fields = append(out, kept[at:]...)
+}
+
+func scalar(name string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		return "", fmt.Errorf("form field %q: patch values must be strings, numbers, booleans or lists of them", name)
+	}
+}
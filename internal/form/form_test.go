@@ -0,0 +1,109 @@
+package form
+
+import (
+	"bytes"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_URLEncoded(t *testing.T) {
+	f, err := Parse("application/x-www-form-urlencoded; charset=utf-8", []byte("Body=Hello+world%21&From=%2B15551234567&To=a&To=b"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []Field{{"Body", "Hello world!"}, {"From", "+15551234567"}, {"To", "a"}, {"To", "b"}}
+	if !reflect.DeepEqual(f.Fields, want) {
+		t.Fatalf("fields=%+v", f.Fields)
+	}
+	if v := f.Values()["To"]; !reflect.DeepEqual(v, []any{"a", "b"}) {
+		t.Fatalf("To=%v", v)
+	}
+	if !strings.Contains(f.Text(), "Body: Hello world!\n") {
+		t.Fatalf("text=%q", f.Text())
+	}
+
+	if f, err := Parse("application/json", []byte(`{}`)); f != nil || err != nil {
+		t.Fatalf("json parsed as form: %v %v", f, err)
+	}
+}
+
+func multipartBody(t *testing.T) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("sender", "bob@example.com")
+	_ = mw.WriteField("subject", "Invoice")
+	w, err := mw.CreateFormFile("attachment-1", "invoice.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("%PDF-1.4"))
+	_ = mw.Close()
+	return mw.FormDataContentType(), buf.Bytes()
+}
+
+func TestParse_Multipart(t *testing.T) {
+	ct, body := multipartBody(t)
+	f, err := Parse(ct, body)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(f.Fields) != 2 || f.Fields[1] != (Field{"subject", "Invoice"}) {
+		t.Fatalf("fields=%+v", f.Fields)
+	}
+	if len(f.Attachments) != 1 {
+		t.Fatalf("attachments=%+v", f.Attachments)
+	}
+	a := f.Attachments[0]
+	if a.Field != "attachment-1" || a.Filename != "invoice.pdf" || a.Size != 8 || string(a.Data) != "%PDF-1.4" {
+		t.Fatalf("attachment=%+v", a)
+	}
+	if strings.Contains(f.Text(), "%PDF") || !strings.Contains(f.Text(), "invoice.pdf") {
+		t.Fatalf("text=%q", f.Text())
+	}
+
+	if _, err := Parse("multipart/form-data", body); err == nil {
+		t.Fatalf("expected error without boundary")
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	ct, body := multipartBody(t)
+	f, _ := Parse(ct, body)
+	if err := f.MergePatch([]byte(`{"subject":"Receipt","sender":null,"count":2,"tag":["a","b"]}`)); err != nil {
+		t.Fatalf("MergePatch: %v", err)
+	}
+	want := []Field{{"subject", "Receipt"}, {"count", "2"}, {"tag", "a"}, {"tag", "b"}}
+	if !reflect.DeepEqual(f.Fields, want) {
+		t.Fatalf("fields=%+v", f.Fields)
+	}
+
+	out, err := Encode(ct, f)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	back, err := Parse(ct, out)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !reflect.DeepEqual(back.Fields, want) || len(back.Attachments) != 1 || string(back.Attachments[0].Data) != "%PDF-1.4" {
+		t.Fatalf("round trip: %+v", back)
+	}
+
+	if err := f.MergePatch([]byte(`{"subject":{"nested":true}}`)); err == nil {
+		t.Fatalf("expected error for object value")
+	}
+}
+
+func TestEncode_URLEncoded(t *testing.T) {
+	f := &Form{Fields: []Field{{"Body", "Hi there"}, {"From", "+1555"}}}
+	out, err := Encode(URLEncoded, f)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if string(out) != "Body=Hi+there&From=%2B1555" {
+		t.Fatalf("out=%s", out)
+	}
+}
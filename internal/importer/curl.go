@@ -0,0 +1,406 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"hooktm/internal/store"
+)
+
+// formBoundary is fixed so the same -F command line always imports the
+// same body, and de-duplicates.
+const formBoundary = "hooktm-import-boundary"
+
+// ParseCurl reads every curl command in a shell script or pasted command
+// line. Bodies piped into curl (--data-binary @-) are followed through
+// printf, echo, cat and base64 -d, which covers the scripts written by
+// `hooktm export --format curl`. @file arguments are read relative to the
+// working directory.
+func ParseCurl(data []byte) ([]store.Webhook, error) {
+	l := newShellLexer(string(data))
+	var (
+		out      []store.Webhook
+		pipeline [][]string
+		cmd      []string
+	)
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.op == "" {
+			cmd = append(cmd, tok.word)
+			continue
+		}
+		if len(cmd) > 0 {
+			pipeline = append(pipeline, cmd)
+			cmd = nil
+		}
+		if tok.op == "|" {
+			continue
+		}
+		whs, err := runPipeline(l, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, whs...)
+		pipeline = nil
+		if tok.op == "eof" {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no curl command found")
+	}
+	return out, nil
+}
+
+// runPipeline evaluates the commands of one pipeline that matter for curl,
+// passing each one's output to the next.
+func runPipeline(l *shellLexer, pipeline [][]string) ([]store.Webhook, error) {
+	var (
+		out   []store.Webhook
+		stdin []byte
+	)
+	for _, cmd := range pipeline {
+		if len(cmd) > 1 && cmd[0] == "export" {
+			cmd = cmd[1:]
+		}
+		// Leading NAME=value words are assignments.
+		for len(cmd) > 0 && l.assign(cmd[0]) {
+			cmd = cmd[1:]
+		}
+		if len(cmd) == 0 {
+			continue
+		}
+		var next []byte
+		switch filepath.Base(cmd[0]) {
+		case "curl":
+			wh, err := parseCurlArgs(cmd[1:], stdin)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, wh)
+		case "printf":
+			switch {
+			case len(cmd) > 2 && cmd[1] == "%s":
+				next = []byte(strings.Join(cmd[2:], ""))
+			case len(cmd) > 1:
+				next = []byte(printfEscapes.Replace(cmd[1]))
+			}
+		case "echo":
+			args, newline := cmd[1:], true
+			if len(args) > 0 && args[0] == "-n" {
+				args, newline = args[1:], false
+			}
+			next = []byte(strings.Join(args, " "))
+			if newline {
+				next = append(next, '\n')
+			}
+		case "cat":
+			for _, name := range cmd[1:] {
+				b, err := os.ReadFile(name)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, b...)
+			}
+			if len(cmd) == 1 {
+				next = stdin
+			}
+		case "base64":
+			if len(cmd) > 1 && (cmd[1] == "-d" || cmd[1] == "-D" || cmd[1] == "--decode") {
+				b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(stdin)), ""))
+				if err != nil {
+					return nil, fmt.Errorf("base64 -d: %w", err)
+				}
+				next = b
+			} else {
+				next = []byte(base64.StdEncoding.EncodeToString(stdin))
+			}
+		}
+		stdin = next
+	}
+	return out, nil
+}
+
+var printfEscapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\r`, "\r", `\\`, `\`, "%%", "%")
+
+// curlValueFlags take a value that doesn't affect the request sent.
+var curlValueFlags = map[string]bool{
+	"o": true, "output": true, "m": true, "max-time": true, "connect-timeout": true,
+	"x": true, "proxy": true, "w": true, "write-out": true, "retry": true, "retry-delay": true,
+	"retry-max-time": true, "cacert": true, "capath": true, "E": true, "cert": true, "key": true,
+	"c": true, "cookie-jar": true, "K": true, "config": true, "resolve": true, "connect-to": true,
+	"limit-rate": true, "r": true, "range": true, "unix-socket": true, "interface": true,
+	"D": true, "dump-header": true, "trace": true, "trace-ascii": true, "stderr": true,
+	"max-redirs": true, "proto": true, "proto-redir": true, "ciphers": true, "y": true,
+	"speed-time": true, "Y": true, "speed-limit": true, "C": true, "continue-at": true,
+}
+
+// curlShortValue lists the single-letter flags that take a value.
+const curlShortValue = "XHdFuAebTomxwEcKrDyYC"
+
+// parseCurlArgs builds the request a curl command line would send. stdin
+// is what @- reads.
+func parseCurlArgs(args []string, stdin []byte) (store.Webhook, error) {
+	var (
+		method, rawURL string
+		h              = http.Header{}
+		data           [][]byte
+		jsonData       bool
+		upload         []byte
+		get, head      bool
+		user           string
+		fw             *multipart.Writer
+		formBuf        bytes.Buffer
+	)
+	readArg := func(name string) ([]byte, error) {
+		if name == "-" {
+			return stdin, nil
+		}
+		return os.ReadFile(name)
+	}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		var flag, value string
+		hasValue := false
+		switch {
+		case strings.HasPrefix(a, "--") && len(a) > 2:
+			flag = a[2:]
+			if f, v, ok := strings.Cut(flag, "="); ok && curlTakesValue(f) {
+				flag, value, hasValue = f, v, true
+			}
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			// Short flags combine (-sSL); a value may be attached (-XPOST).
+			for j, r := range a[1:] {
+				if strings.ContainsRune(curlShortValue, r) {
+					flag = string(r)
+					if rest := a[2+j:]; rest != "" {
+						value, hasValue = rest, true
+					}
+					break
+				}
+				switch r {
+				case 'G':
+					get = true
+				case 'I':
+					head = true
+				}
+			}
+			if flag == "" {
+				continue
+			}
+		default:
+			if rawURL == "" {
+				rawURL = a
+			}
+			continue
+		}
+		if curlTakesValue(flag) && !hasValue {
+			if i+1 >= len(args) {
+				return store.Webhook{}, fmt.Errorf("curl: %s needs a value", a)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "X", "request":
+			method = value
+		case "url":
+			rawURL = value
+		case "H", "header":
+			name, v, ok := strings.Cut(value, ":")
+			if !ok {
+				if name, ok = strings.CutSuffix(value, ";"); ok {
+					h.Add(name, "")
+				}
+				continue
+			}
+			if v = strings.TrimSpace(v); v != "" {
+				h.Add(strings.TrimSpace(name), v)
+			}
+		case "d", "data", "data-ascii", "data-binary", "data-raw", "json":
+			b := []byte(value)
+			if strings.HasPrefix(value, "@") && flag != "data-raw" {
+				var err error
+				if b, err = readArg(value[1:]); err != nil {
+					return store.Webhook{}, err
+				}
+				if flag == "d" || flag == "data" || flag == "data-ascii" {
+					b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r"), nil), []byte("\n"), nil)
+				}
+			}
+			data = append(data, b)
+			jsonData = jsonData || flag == "json"
+		case "data-urlencode":
+			b, err := urlencodeData(value, readArg)
+			if err != nil {
+				return store.Webhook{}, err
+			}
+			data = append(data, b)
+		case "F", "form", "form-string":
+			if fw == nil {
+				fw = multipart.NewWriter(&formBuf)
+				_ = fw.SetBoundary(formBoundary)
+			}
+			if err := addFormPart(fw, value, flag == "form-string", readArg); err != nil {
+				return store.Webhook{}, err
+			}
+		case "T", "upload-file":
+			b, err := readArg(value)
+			if err != nil {
+				return store.Webhook{}, err
+			}
+			upload = b
+		case "u", "user":
+			user = value
+		case "A", "user-agent":
+			h.Set("User-Agent", value)
+		case "e", "referer":
+			h.Set("Referer", value)
+		case "b", "cookie":
+			if strings.Contains(value, "=") {
+				h.Set("Cookie", value)
+			}
+		case "G", "get":
+			get = true
+		case "I", "head":
+			head = true
+		}
+	}
+	if rawURL == "" {
+		return store.Webhook{}, fmt.Errorf("curl command without URL")
+	}
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "/") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return store.Webhook{}, fmt.Errorf("curl: %w", err)
+	}
+	wh := store.Webhook{Path: u.EscapedPath(), Query: u.RawQuery, Headers: h}
+	if wh.Path == "" {
+		wh.Path = "/"
+	}
+	if user != "" && h.Get("Authorization") == "" {
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+	}
+
+	sep := []byte("&")
+	if jsonData {
+		sep = nil
+	}
+	switch {
+	case get && len(data) > 0:
+		wh.Query = strings.TrimPrefix(wh.Query+"&"+string(bytes.Join(data, sep)), "&")
+	case len(data) > 0:
+		wh.Body = bytes.Join(data, sep)
+		if jsonData {
+			setDefault(h, "Content-Type", "application/json")
+			setDefault(h, "Accept", "application/json")
+		}
+		setDefault(h, "Content-Type", "application/x-www-form-urlencoded")
+	case fw != nil:
+		if err := fw.Close(); err != nil {
+			return store.Webhook{}, err
+		}
+		wh.Body = formBuf.Bytes()
+		setDefault(h, "Content-Type", fw.FormDataContentType())
+	case upload != nil:
+		wh.Body = upload
+	}
+
+	switch {
+	case method != "":
+		wh.Method = strings.ToUpper(method)
+	case head:
+		wh.Method = http.MethodHead
+	case upload != nil:
+		wh.Method = http.MethodPut
+	case wh.Body != nil:
+		wh.Method = http.MethodPost
+	default:
+		wh.Method = http.MethodGet
+	}
+	return wh, nil
+}
+
+func curlTakesValue(flag string) bool {
+	if len(flag) == 1 {
+		return strings.Contains(curlShortValue, flag)
+	}
+	switch flag {
+	case "request", "url", "header", "data", "data-ascii", "data-binary", "data-raw", "json",
+		"data-urlencode", "form", "form-string", "upload-file", "user", "user-agent", "referer", "cookie":
+		return true
+	}
+	return curlValueFlags[flag]
+}
+
+func setDefault(h http.Header, key, value string) {
+	if h.Get(key) == "" {
+		h.Set(key, value)
+	}
+}
+
+// urlencodeData implements --data-urlencode: "content", "=content",
+// "name=content", "@file" and "name@file".
+func urlencodeData(value string, readArg func(string) ([]byte, error)) ([]byte, error) {
+	if i := strings.IndexAny(value, "=@"); i >= 0 {
+		name, content := value[:i], value[i+1:]
+		if value[i] == '@' {
+			b, err := readArg(content)
+			if err != nil {
+				return nil, err
+			}
+			content = string(b)
+		}
+		if name == "" {
+			return []byte(url.QueryEscape(content)), nil
+		}
+		return []byte(name + "=" + url.QueryEscape(content)), nil
+	}
+	return []byte(url.QueryEscape(value)), nil
+}
+
+// addFormPart implements -F: "name=value", "name=@file" (a file part),
+// "name=<file" (the file as the value) and a ";type=" suffix.
+func addFormPart(fw *multipart.Writer, value string, literal bool, readArg func(string) ([]byte, error)) error {
+	name, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("curl: bad form field %q", value)
+	}
+	if literal || (!strings.HasPrefix(v, "@") && !strings.HasPrefix(v, "<")) {
+		return fw.WriteField(name, v)
+	}
+	file, ctype, _ := strings.Cut(v[1:], ";type=")
+	b, err := readArg(file)
+	if err != nil {
+		return err
+	}
+	if v[0] == '<' {
+		return fw.WriteField(name, string(b))
+	}
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	ph := textproto.MIMEHeader{}
+	ph.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, name, filepath.Base(file)))
+	ph.Set("Content-Type", ctype)
+	w, err := fw.CreatePart(ph)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"hooktm/internal/store"
+)
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Headers  []harPair `json:"headers"`
+	PostData *struct {
+		MimeType string    `json:"mimeType"`
+		Text     string    `json:"text"`
+		Encoding string    `json:"encoding"`
+		Params   []harPair `json:"params"`
+	} `json:"postData"`
+}
+
+type harResponse struct {
+	Status  int       `json:"status"`
+	Headers []harPair `json:"headers"`
+	Content struct {
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ParseHAR reads the requests of a HAR 1.2 log, with their responses.
+// Browser-only entries (GET requests without a body) are kept too: the
+// caller filters what it wants.
+func ParseHAR(data []byte) ([]store.Webhook, error) {
+	var f harFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse HAR: %w", err)
+	}
+	out := make([]store.Webhook, 0, len(f.Log.Entries))
+	for i, e := range f.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		wh := store.Webhook{
+			Method:  e.Request.Method,
+			Path:    u.EscapedPath(),
+			Query:   u.RawQuery,
+			Headers: harHeaders(e.Request.Headers),
+		}
+		if wh.Path == "" {
+			wh.Path = "/"
+		}
+		if t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime); err == nil {
+			wh.CreatedAt = t.UnixMilli()
+		}
+		if pd := e.Request.PostData; pd != nil {
+			switch {
+			case pd.Text != "":
+				if wh.Body, err = harBody(pd.Text, pd.Encoding); err != nil {
+					return nil, fmt.Errorf("entry %d: %w", i, err)
+				}
+			case len(pd.Params) > 0:
+				v := url.Values{}
+				for _, p := range pd.Params {
+					v.Add(p.Name, p.Value)
+				}
+				wh.Body = []byte(v.Encode())
+			}
+			if _, ok := wh.Headers["Content-Type"]; !ok && pd.MimeType != "" {
+				wh.Headers["Content-Type"] = []string{pd.MimeType}
+			}
+		}
+		if e.Response.Status > 0 {
+			status := e.Response.Status
+			wh.StatusCode = &status
+			wh.ResponseMS = int64(e.Time)
+			wh.ResponseHeaders = harHeaders(e.Response.Headers)
+			if wh.ResponseBody, err = harBody(e.Response.Content.Text, e.Response.Content.Encoding); err != nil {
+				return nil, fmt.Errorf("entry %d response: %w", i, err)
+			}
+		}
+		out = append(out, wh)
+	}
+	return out, nil
+}
+
+// harHeaders drops the HTTP/2 pseudo-headers (":authority" and friends).
+func harHeaders(pairs []harPair) map[string][]string {
+	h := make(map[string][]string, len(pairs))
+	for _, p := range pairs {
+		if strings.HasPrefix(p.Name, ":") {
+			continue
+		}
+		k := http.CanonicalHeaderKey(p.Name)
+		h[k] = append(h[k], p.Value)
+	}
+	return h
+}
+
+func harBody(text, encoding string) ([]byte, error) {
+	if text == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(encoding, "base64") {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"hooktm/internal/store"
+)
+
+// requestSeparator splits .http files (VS Code REST Client, JetBrains) into
+// requests.
+var requestSeparator = regexp.MustCompile(`(?m)^###.*$`)
+
+// ParseHTTP reads raw HTTP/1.x requests, as captured with nc or tcpdump or
+// written for the REST Client editor plugins. The request line may
+// leave out the protocol version. A body without Content-Length runs to the
+// end of the request, less one trailing newline; requests with a length may
+// follow each other directly, others are separated by ### lines.
+func ParseHTTP(data []byte) ([]store.Webhook, error) {
+	var out []store.Webhook
+	for _, block := range requestSeparator.Split(string(data), -1) {
+		rest := []byte(block)
+		for {
+			rest = bytes.TrimLeft(rest, " \t\r\n")
+			if len(rest) == 0 {
+				break
+			}
+			wh, n, err := parseHTTPRequest(rest)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, wh)
+			rest = rest[n:]
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no HTTP request found")
+	}
+	return out, nil
+}
+
+// parseHTTPRequest reads one request from the start of b and returns how
+// many bytes it used.
+func parseHTTPRequest(b []byte) (store.Webhook, int, error) {
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	if fields := bytes.Fields(line); len(fields) == 2 {
+		// Add the version http.ReadRequest insists on.
+		fixed := append(bytes.Join(fields, []byte(" ")), " HTTP/1.1\r"...)
+		wh, n, err := parseHTTPRequest(append(fixed, b[len(line):]...))
+		return wh, n - len(fixed) + len(line), err
+	}
+	r := &countingReader{r: bytes.NewReader(b)}
+	br := bufio.NewReader(r)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return store.Webhook{}, 0, fmt.Errorf("parse HTTP request: %w", err)
+	}
+	wh := store.Webhook{
+		Method:  req.Method,
+		Path:    req.URL.EscapedPath(),
+		Query:   req.URL.RawQuery,
+		Headers: req.Header,
+	}
+	if wh.Path == "" {
+		wh.Path = "/"
+	}
+	if req.Host != "" {
+		wh.Headers["Host"] = []string{req.Host}
+	}
+	if req.ContentLength > 0 || len(req.TransferEncoding) > 0 {
+		if wh.Body, err = io.ReadAll(req.Body); err != nil {
+			return store.Webhook{}, 0, fmt.Errorf("read HTTP body: %w", err)
+		}
+		// Chunked bodies are stored decoded, like the proxy sees them.
+		delete(wh.Headers, "Transfer-Encoding")
+		return wh, r.n - br.Buffered(), nil
+	}
+	rest, _ := io.ReadAll(br)
+	rest = bytes.TrimSuffix(rest, []byte("\n"))
+	rest = bytes.TrimSuffix(rest, []byte("\r"))
+	if len(rest) > 0 {
+		wh.Body = rest
+	}
+	return wh, len(b), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// ParseNDJSON reads webhooks as HookTM writes them as JSON: one object per
+// line, the indented output of `hooktm show --format json`, or a dump (whose
+// header and replay lines are skipped).
+func ParseNDJSON(data []byte) ([]store.Webhook, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var out []store.Webhook
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse NDJSON (webhook %d): %w", len(out)+1, err)
+		}
+		var rec struct {
+			Type   string `json:"type"`
+			Format string `json:"format"`
+		}
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return nil, fmt.Errorf("parse NDJSON (webhook %d): %w", len(out)+1, err)
+		}
+		if rec.Format == store.DumpFormat || (rec.Type != "" && rec.Type != "webhook") {
+			continue
+		}
+		var wh store.Webhook
+		if err := json.Unmarshal(raw, &wh); err != nil {
+			return nil, fmt.Errorf("parse NDJSON (webhook %d): %w", len(out)+1, err)
+		}
+		if wh.Method == "" || wh.Path == "" {
+			return nil, fmt.Errorf("parse NDJSON (webhook %d): missing method/path", len(out)+1)
+		}
+		out = append(out, wh)
+	}
+	return out, nil
+}
@@ -0,0 +1,216 @@
+// Package importer reads webhooks captured elsewhere (HAR files, curl
+// command lines, raw HTTP requests and HookTM NDJSON) and stores them as if
+// they had been recorded by the proxy.
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"hooktm/internal/provider"
+	"hooktm/internal/proxy"
+	"hooktm/internal/signature"
+	"hooktm/internal/store"
+
+	nanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// Formats lists the supported import formats. "db" (another HookTM
+// database) is read by the caller through the store.
+var Formats = []string{"har", "curl", "http", "ndjson", "db"}
+
+// Parse reads webhooks in format from data. Only method, path, query,
+// headers, body and (where the format has them) the time and response are
+// read; everything else is derived on import.
+func Parse(format string, data []byte) ([]store.Webhook, error) {
+	switch strings.ToLower(format) {
+	case "har":
+		return ParseHAR(data)
+	case "curl":
+		return ParseCurl(data)
+	case "http":
+		return ParseHTTP(data)
+	case "ndjson":
+		return ParseNDJSON(data)
+	default:
+		return nil, fmt.Errorf("unknown format: %s (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Detect guesses the format of a file from its extension, then from its
+// first bytes. It returns "" when neither gives it away.
+func Detect(name string, head []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".har":
+		return "har"
+	case ".sh", ".curl":
+		return "curl"
+	case ".http", ".rest":
+		return "http"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".db", ".sqlite", ".sqlite3":
+		return "db"
+	}
+	if bytes.HasPrefix(head, []byte("SQLite format 3\x00")) {
+		return "db"
+	}
+	trimmed := bytes.TrimSpace(head)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		if bytes.Contains(trimmed, []byte(`"log"`)) && bytes.Contains(trimmed, []byte(`"entries"`)) {
+			return "har"
+		}
+		return "ndjson"
+	case bytes.HasPrefix(trimmed, []byte("#!")), bytes.HasPrefix(trimmed, []byte("curl ")):
+		return "curl"
+	}
+	line, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if method, rest, ok := bytes.Cut(line, []byte(" ")); ok && len(rest) > 0 && isMethod(string(method)) {
+		return "http"
+	}
+	return ""
+}
+
+func isMethod(s string) bool {
+	switch s {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "TRACE", "CONNECT":
+		return true
+	}
+	return false
+}
+
+// Importer stores webhooks, skipping those already in the store.
+type Importer struct {
+	Store *store.Store
+	// Providers detects the sending provider; defaults to the built-ins.
+	Providers *provider.Registry
+	// Verifier checks signatures when set, against the time each webhook was
+	// received; nil keeps the result from the source, if any.
+	Verifier *signature.Verifier
+}
+
+// Result counts what an import did.
+type Result struct {
+	Imported   int
+	Duplicates int
+	// IDs of the imported webhooks, in input order.
+	IDs []string
+}
+
+// Import stores webhooks in order. A webhook whose method, path, query and
+// body match one already stored (see store.ContentHash) is skipped, which
+// also drops duplicates within webhooks. Imported webhooks keep their ID
+// unless it is taken.
+func (im *Importer) Import(ctx context.Context, webhooks []store.Webhook) (Result, error) {
+	var res Result
+	for _, wh := range webhooks {
+		id, err := im.Store.FindByHash(ctx, store.ContentHash(wh.Method, wh.Path, wh.Query, wh.Body))
+		if err != nil {
+			return res, err
+		}
+		if id != "" {
+			res.Duplicates++
+			continue
+		}
+		p, err := im.params(ctx, wh)
+		if err != nil {
+			return res, err
+		}
+		if err := im.Store.InsertWebhook(ctx, p); err != nil {
+			return res, fmt.Errorf("import %s %s: %w", wh.Method, wh.Path, err)
+		}
+		res.Imported++
+		res.IDs = append(res.IDs, p.ID)
+	}
+	return res, nil
+}
+
+// params builds the insert for wh the way the proxy would have recorded it.
+func (im *Importer) params(ctx context.Context, wh store.Webhook) (store.InsertParams, error) {
+	id := strings.TrimSpace(wh.ID)
+	if id != "" {
+		if _, err := im.Store.GetWebhook(ctx, id); err == nil {
+			id = ""
+		}
+	}
+	if id == "" {
+		var err error
+		if id, err = nanoid.New(); err != nil {
+			return store.InsertParams{}, err
+		}
+	}
+	if wh.CreatedAt == 0 {
+		wh.CreatedAt = time.Now().UnixMilli()
+	}
+
+	// Go keeps Host out of request headers, so the proxy never stores it.
+	h := http.Header{}
+	var host string
+	for k, vs := range wh.Headers {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			if len(vs) > 0 {
+				host = vs[0]
+			}
+			continue
+		}
+		h[k] = append([]string(nil), vs...)
+	}
+
+	// Bodies the proxy would have spooled are stored in chunks, and only
+	// their head is analyzed.
+	spooled := len(wh.Body) > proxy.MaxRequestBodySize
+	head := wh.Body
+	if spooled {
+		head = wh.Body[:proxy.MaxRequestBodySize]
+	}
+	a := proxy.Analyze(im.Providers, h, head, spooled)
+
+	p := store.InsertParams{
+		ID:              id,
+		CreatedAt:       wh.CreatedAt,
+		Method:          strings.ToUpper(wh.Method),
+		Path:            wh.Path,
+		Query:           wh.Query,
+		Headers:         h,
+		Body:            wh.Body,
+		SignatureValid:  wh.SignatureValid,
+		MockRule:        wh.MockRule,
+		Fault:           wh.Fault,
+		StatusCode:      wh.StatusCode,
+		ResponseMS:      wh.ResponseMS,
+		ResponseHeaders: wh.ResponseHeaders,
+		ResponseBody:    wh.ResponseBody,
+		Deliveries:      wh.Deliveries,
+	}
+	a.Fill(&p)
+	if len(p.ResponseBody) > proxy.MaxResponseBodySize {
+		p.ResponseBody = p.ResponseBody[:proxy.MaxResponseBodySize]
+		p.ResponseTruncated = true
+	} else {
+		p.ResponseTruncated = wh.ResponseTruncated
+	}
+	if spooled {
+		p.BodyReader = bytes.NewReader(wh.Body)
+		p.BodySize = int64(len(wh.Body))
+	} else if im.Verifier != nil && a.Provider != provider.Unknown {
+		valid, _ := im.Verifier.Verify(a.Provider, signature.Request{
+			Method:     p.Method,
+			Path:       p.Path,
+			Query:      p.Query,
+			Headers:    h,
+			Body:       a.Plain,
+			ReceivedAt: time.UnixMilli(wh.CreatedAt),
+			Host:       host,
+		})
+		if valid != nil {
+			p.SignatureValid = valid
+		}
+	}
+	return p, nil
+}
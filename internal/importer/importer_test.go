@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"hooktm/internal/contentenc"
+	"hooktm/internal/export"
+	"hooktm/internal/store"
+)
+
+func sampleWebhooks(t *testing.T) []store.Webhook {
+	t.Helper()
+	gz, err := contentenc.Encode([]string{"gzip"}, []byte(`{"zipped":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []store.Webhook{
+		{
+			ID: "wh1", CreatedAt: 1705312200000, Method: "POST", Path: "/hooks/stripe", Query: "a=1&b=x%20y",
+			Headers: map[string][]string{
+				"Content-Type":     {"application/json"},
+				"Stripe-Signature": {"t=1,v1=abc"},
+			},
+			Body: []byte(`{"type":"invoice.paid","note":"it's"}`),
+		},
+		{
+			ID: "wh2", CreatedAt: 1705312260000, Method: "PUT", Path: "/gz",
+			Headers: map[string][]string{
+				"Content-Type":     {"application/json"},
+				"Content-Encoding": {"gzip"},
+			},
+			Body: gz,
+		},
+	}
+}
+
+// sameRequest compares what an import keeps from every format.
+func sameRequest(t *testing.T, got, want store.Webhook) {
+	t.Helper()
+	if got.Method != want.Method || got.Path != want.Path || got.Query != want.Query || !bytes.Equal(got.Body, want.Body) {
+		t.Fatalf("got %s %s?%s %q, want %s %s?%s %q", got.Method, got.Path, got.Query, got.Body, want.Method, want.Path, want.Query, want.Body)
+	}
+	for k, vs := range want.Headers {
+		if !reflect.DeepEqual(got.Headers[k], vs) {
+			t.Fatalf("header %s=%q, want %q", k, got.Headers[k], vs)
+		}
+	}
+}
+
+func TestParse_ExportRoundTrip(t *testing.T) {
+	want := sampleWebhooks(t)
+	for _, format := range []string{"curl", "har"} {
+		var buf bytes.Buffer
+		if err := export.Write(&buf, format, export.Options{}, want); err != nil {
+			t.Fatal(err)
+		}
+		if got := Detect("", buf.Bytes()); got != format {
+			t.Fatalf("Detect = %q, want %q", got, format)
+		}
+		got, err := Parse(format, buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d webhooks", format, len(got))
+		}
+		for i := range want {
+			sameRequest(t, got[i], want[i])
+		}
+		if format == "har" && got[0].CreatedAt != want[0].CreatedAt {
+			t.Fatalf("har: CreatedAt=%d", got[0].CreatedAt)
+		}
+	}
+}
+
+func TestParseCurl_CommandLine(t *testing.T) {
+	script := `# pasted from the docs
+curl -sS -XPOST 'https://api.example.com/hooks/github?x=1' \
+  -H "X-GitHub-Event: push" -H 'Content-Type: application/json' \
+  -u bot:secret \
+  --data-raw $'{"ref":"refs/heads/main",\n"it\'s":true}' > /dev/null 2>&1 && echo done
+URL=http://localhost:3000; curl "$URL/form" -d a=1 -d 'b=two words' --data-urlencode 'c=x&y'
+curl -G localhost:3000/search --data-urlencode q=hello -d page=2
+curl --json '{"a":1}' http://localhost:3000/json
+`
+	got, err := ParseCurl([]byte(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d webhooks: %+v", len(got), got)
+	}
+	sameRequest(t, got[0], store.Webhook{
+		Method: "POST", Path: "/hooks/github", Query: "x=1",
+		Headers: map[string][]string{
+			"X-Github-Event": {"push"},
+			"Authorization":  {"Basic Ym90OnNlY3JldA=="},
+		},
+		Body: []byte("{\"ref\":\"refs/heads/main\",\n\"it's\":true}"),
+	})
+	sameRequest(t, got[1], store.Webhook{
+		Method: "POST", Path: "/form",
+		Headers: map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}},
+		Body:    []byte("a=1&b=two words&c=x%26y"),
+	})
+	sameRequest(t, got[2], store.Webhook{Method: "GET", Path: "/search", Query: "q=hello&page=2"})
+	sameRequest(t, got[3], store.Webhook{
+		Method: "POST", Path: "/json",
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    []byte(`{"a":1}`),
+	})
+
+	if _, err := ParseCurl([]byte("echo hi\n")); err == nil {
+		t.Fatal("expected error without curl commands")
+	}
+}
+
+func TestParseHTTP(t *testing.T) {
+	raw := "POST /hooks/a?x=1 HTTP/1.1\r\nHost: example.com\r\nContent-Type: text/plain\r\nContent-Length: 5\r\n\r\nhelloPOST /hooks/b HTTP/1.1\r\nContent-Length: 2\r\n\r\nhi\n" +
+		"###\nPOST /hooks/c\nContent-Type: application/json\n\n{\"c\":true}\n\n### last\nGET http://example.com/ping HTTP/1.1\n\n"
+	got, err := ParseHTTP([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d requests: %+v", len(got), got)
+	}
+	sameRequest(t, got[0], store.Webhook{Method: "POST", Path: "/hooks/a", Query: "x=1", Headers: map[string][]string{"Host": {"example.com"}}, Body: []byte("hello")})
+	sameRequest(t, got[1], store.Webhook{Method: "POST", Path: "/hooks/b", Body: []byte("hi")})
+	sameRequest(t, got[2], store.Webhook{Method: "POST", Path: "/hooks/c", Body: []byte("{\"c\":true}\n")})
+	sameRequest(t, got[3], store.Webhook{Method: "GET", Path: "/ping"})
+	if Detect("capture.txt", []byte(raw)) != "http" {
+		t.Fatal("Detect did not recognize a raw request")
+	}
+}
+
+func TestImport_DetectsAndDeduplicates(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	if err := s.InsertWebhook(ctx, store.InsertParams{ID: "wh2", Method: "POST", Path: "/other", Headers: map[string][]string{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	whs := sampleWebhooks(t)
+	im := &Importer{Store: s}
+	res, err := im.Import(ctx, append(whs, whs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imported != 2 || res.Duplicates != 1 || res.IDs[0] != "wh1" || res.IDs[1] == "wh2" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	wh, err := s.GetWebhook(ctx, "wh1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wh.Provider != "stripe" || wh.BodyText == "" || wh.CreatedAt != whs[0].CreatedAt {
+		t.Fatalf("webhook not analyzed: %+v", wh)
+	}
+	wh, err = s.GetWebhook(ctx, res.IDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wh.DecodedBody) != `{"zipped":true}` {
+		t.Fatalf("DecodedBody=%q", wh.DecodedBody)
+	}
+
+	// Importing the same file again adds nothing.
+	res, err = im.Import(ctx, whs)
+	if err != nil || res.Imported != 0 || res.Duplicates != 2 {
+		t.Fatalf("reimport: %+v, %v", res, err)
+	}
+}
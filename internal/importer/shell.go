@@ -0,0 +1,304 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// shellLexer splits shell scripts into words and operators, with the quoting
+// and expansion rules curl command lines rely on. Variables expand from
+// assignments seen earlier in the script (never the environment), so
+// "$BASE_URL"/path works in scripts from `hooktm export --format curl`.
+// Command substitution expands to nothing.
+type shellLexer struct {
+	src  []rune
+	pos  int
+	vars map[string]string
+}
+
+// shellToken is a word, or an operator when op is set: "|" for pipes, ";"
+// for anything that ends a pipeline (newline, ;, &, &&, ||) and "eof".
+type shellToken struct {
+	word string
+	op   string
+}
+
+func newShellLexer(src string) *shellLexer {
+	return &shellLexer{src: []rune(src), vars: map[string]string{}}
+}
+
+func (l *shellLexer) peek(n int) rune {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *shellLexer) next() (shellToken, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+			continue
+		case c == '\\' && l.peek(1) == '\n':
+			l.pos += 2
+			continue
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.src) {
+		return shellToken{op: "eof"}, nil
+	}
+	switch c := l.src[l.pos]; c {
+	case '\n', ';':
+		l.pos++
+		return shellToken{op: ";"}, nil
+	case '|', '&':
+		l.pos++
+		if l.peek(0) == c {
+			l.pos++
+			return shellToken{op: ";"}, nil
+		}
+		if c == '|' {
+			return shellToken{op: "|"}, nil
+		}
+		return shellToken{op: ";"}, nil
+	case '>', '<':
+		// Redirections don't matter here: drop them with their target.
+		for l.pos < len(l.src) && (l.src[l.pos] == '>' || l.src[l.pos] == '<') {
+			l.pos++
+		}
+		if l.peek(0) == '&' {
+			l.pos++
+		}
+		tok, err := l.next()
+		if err != nil || tok.op != "" {
+			return tok, err
+		}
+		return l.next()
+	}
+	return l.word()
+}
+
+func (l *shellLexer) word() (shellToken, error) {
+	var b strings.Builder
+	quoted := false
+loop:
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case strings.ContainsRune(" \t\r\n;|&", c):
+			break loop
+		case c == '>' || c == '<':
+			if !quoted && b.Len() > 0 && isDigits(b.String()) {
+				// A file descriptor, as in 2>/dev/null.
+				return l.next()
+			}
+			break loop
+		case c == '\\':
+			if l.peek(1) != '\n' && l.pos+1 < len(l.src) {
+				b.WriteRune(l.src[l.pos+1])
+			}
+			l.pos += 2
+		case c == '\'':
+			end := indexRune(l.src, l.pos+1, '\'')
+			if end < 0 {
+				return shellToken{}, fmt.Errorf("unterminated quote")
+			}
+			b.WriteString(string(l.src[l.pos+1 : end]))
+			l.pos = end + 1
+			quoted = true
+		case c == '$' && l.peek(1) == '\'':
+			l.pos += 2
+			if err := l.ansiC(&b); err != nil {
+				return shellToken{}, err
+			}
+			quoted = true
+		case c == '"':
+			l.pos++
+			if err := l.doubleQuoted(&b); err != nil {
+				return shellToken{}, err
+			}
+			quoted = true
+		case c == '$':
+			b.WriteString(l.expand())
+		default:
+			b.WriteRune(c)
+			l.pos++
+		}
+	}
+	if b.Len() == 0 && !quoted {
+		// An unquoted expansion to nothing is no word at all.
+		return l.next()
+	}
+	return shellToken{word: b.String()}, nil
+}
+
+func (l *shellLexer) doubleQuoted(b *strings.Builder) error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return nil
+		case c == '\\':
+			switch n := l.peek(1); n {
+			case '"', '\\', '$', '`':
+				b.WriteRune(n)
+				l.pos += 2
+			case '\n':
+				l.pos += 2
+			default:
+				b.WriteRune(c)
+				l.pos++
+			}
+		case c == '$':
+			b.WriteString(l.expand())
+		default:
+			b.WriteRune(c)
+			l.pos++
+		}
+	}
+	return fmt.Errorf("unterminated quote")
+}
+
+// ansiC reads the rest of a $'...' string.
+func (l *shellLexer) ansiC(b *strings.Builder) error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+		if c == '\'' {
+			return nil
+		}
+		if c != '\\' || l.pos >= len(l.src) {
+			b.WriteRune(c)
+			continue
+		}
+		e := l.src[l.pos]
+		l.pos++
+		switch e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '0':
+			b.WriteByte(0)
+		case 'e', 'E':
+			b.WriteByte(0x1b)
+		case '\\', '\'', '"':
+			b.WriteRune(e)
+		case 'x':
+			end := l.pos
+			for end < len(l.src) && end < l.pos+2 && isHex(l.src[end]) {
+				end++
+			}
+			n, err := strconv.ParseUint(string(l.src[l.pos:end]), 16, 8)
+			if err != nil {
+				b.WriteString(`\x`)
+				continue
+			}
+			b.WriteByte(byte(n))
+			l.pos = end
+		default:
+			b.WriteRune('\\')
+			b.WriteRune(e)
+		}
+	}
+	return fmt.Errorf("unterminated quote")
+}
+
+// expand reads a $ expansion: $NAME, ${NAME}, ${NAME:-default} or $(...).
+func (l *shellLexer) expand() string {
+	l.pos++ // $
+	c := l.peek(0)
+	switch {
+	case c == '{':
+		end := indexRune(l.src, l.pos, '}')
+		if end < 0 {
+			return "$"
+		}
+		expr := string(l.src[l.pos+1 : end])
+		l.pos = end + 1
+		name, def, ok := strings.Cut(expr, ":-")
+		if !ok {
+			name, def, _ = strings.Cut(expr, "-")
+		}
+		if v := l.vars[name]; v != "" {
+			return v
+		}
+		return def
+	case c == '(':
+		for depth := 0; l.pos < len(l.src); l.pos++ {
+			switch l.src[l.pos] {
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth == 0 {
+					l.pos++
+					return ""
+				}
+			}
+		}
+		return ""
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		start := l.pos
+		for l.pos < len(l.src) && isNameRune(l.src[l.pos]) {
+			l.pos++
+		}
+		return l.vars[string(l.src[start:l.pos])]
+	case c != 0 && strings.ContainsRune("0123456789?#@*$!-", c):
+		l.pos++
+		return ""
+	}
+	return "$"
+}
+
+// assign records NAME=value words; it reports false for anything else.
+func (l *shellLexer) assign(word string) bool {
+	name, value, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !isNameRune(r) || i == 0 && r >= '0' && r <= '9' {
+			return false
+		}
+	}
+	l.vars[name] = value
+	return true
+}
+
+func indexRune(src []rune, from int, r rune) int {
+	for i := from; i < len(src); i++ {
+		if src[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+func isHex(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,206 @@
+// Package mock answers webhooks in record-only mode according to a rules file,
+// so provider retry behaviour can be observed against a failing or slow endpoint.
+//
+//	rules:
+//	  - name: stripe-invoice-fails
+//	    match:
+//	      method: POST
+//	      path: /stripe/*                 # glob, as in path.Match
+//	      provider: stripe
+//	      event_type: invoice.*           # glob
+//	      body:
+//	        $.data.object.amount: "0"     # JSONPath → glob on the value ("*": exists)
+//	    response:
+//	      status: 500
+//	      headers: {Content-Type: application/json}
+//	      body: '{"error":"simulated","id":"{{.ID}}","object":"{{.Field "data.object.id"}}"}'
+//	      delay: 2s
+//
+// Rules are tried in order; the first match answers. Empty match fields match
+// anything.
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"hooktm/internal/provider"
+
+	"gopkg.in/yaml.v3"
+)
+
+type file struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule is one entry of the rules file.
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Match    Match    `yaml:"match"`
+	Response Response `yaml:"response"`
+
+	body *template.Template
+}
+
+type Match struct {
+	Method    string            `yaml:"method"`
+	Path      string            `yaml:"path"`
+	Provider  string            `yaml:"provider"`
+	EventType string            `yaml:"event_type"`
+	Body      map[string]string `yaml:"body"`
+}
+
+type Response struct {
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	Delay   time.Duration     `yaml:"delay"`
+}
+
+// Request is the captured webhook a rule is matched against and rendered for.
+type Request struct {
+	ID        string
+	Method    string
+	Path      string
+	Query     string
+	Header    http.Header
+	Body      []byte
+	Provider  string
+	EventType string
+}
+
+// Rules is a parsed rules file.
+type Rules struct {
+	rules []*Rule
+}
+
+// Load reads a rules file.
+func Load(p string) (*Rules, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return rs, nil
+}
+
+// Parse parses rules file contents, compiling body templates up front so
+// mistakes surface at startup rather than on the first webhook.
+func Parse(b []byte) (*Rules, error) {
+	var f file
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	for i, r := range f.Rules {
+		if r == nil {
+			return nil, fmt.Errorf("rule %d is empty", i+1)
+		}
+		if strings.TrimSpace(r.Name) == "" {
+			r.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if r.Response.Status == 0 {
+			r.Response.Status = http.StatusOK
+		}
+		if r.Response.Status < 100 || r.Response.Status > 599 {
+			return nil, fmt.Errorf("%s: invalid status %d", r.Name, r.Response.Status)
+		}
+		if r.Response.Delay < 0 {
+			return nil, fmt.Errorf("%s: negative delay", r.Name)
+		}
+		for _, pattern := range []string{r.Match.Path, r.Match.EventType} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s: bad glob %q", r.Name, pattern)
+			}
+		}
+		t, err := template.New(r.Name).Option("missingkey=zero").Parse(r.Response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: body template: %w", r.Name, err)
+		}
+		r.body = t
+	}
+	return &Rules{rules: f.Rules}, nil
+}
+
+// Len returns the number of rules.
+func (rs *Rules) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.rules)
+}
+
+// Match returns the first rule matching req, or nil. A nil *Rules matches nothing.
+func (rs *Rules) Match(req Request) *Rule {
+	if rs == nil {
+		return nil
+	}
+	for _, r := range rs.rules {
+		if r.matches(req) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(req Request) bool {
+	m := r.Match
+	if m.Method != "" && !strings.EqualFold(m.Method, req.Method) {
+		return false
+	}
+	if m.Path != "" && !glob(m.Path, req.Path) {
+		return false
+	}
+	if m.Provider != "" && !strings.EqualFold(m.Provider, req.Provider) {
+		return false
+	}
+	if m.EventType != "" && !glob(m.EventType, req.EventType) {
+		return false
+	}
+	for p, pattern := range m.Body {
+		v, ok := provider.BodyValue(req.Header, req.Body, p)
+		if !ok || !glob(pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func glob(pattern, s string) bool {
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+// Render executes the rule's body template for req.
+//
+// Templates see .ID, .Method, .Path, .Query, .Provider, .EventType, .Header
+// (http.Header), .Body (string) and .Now, plus .Field "a.b" for body values.
+func (r *Rule) Render(req Request) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := r.body.Execute(&buf, templateData{Request: req, Now: time.Now()}); err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+type templateData struct {
+	Request
+	Now time.Time
+}
+
+// Body shadows Request.Body so templates get text rather than bytes.
+func (d templateData) Body() string { return string(d.Request.Body) }
+
+// Field returns a body value by path, or "" when absent.
+func (d templateData) Field(p string) string {
+	v, _ := provider.BodyValue(d.Header, d.Request.Body, p)
+	return v
+}
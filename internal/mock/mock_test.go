@@ -0,0 +1,117 @@
+package mock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rulesYAML = `
+rules:
+  - name: zero-amount
+    match:
+      provider: stripe
+      body:
+        $.data.object.amount: "0"
+    response:
+      status: 402
+      headers: {Content-Type: application/json}
+      body: '{"id":"{{.ID}}","object":"{{.Field "data.object.id"}}","event":"{{.EventType}}"}'
+  - name: slow-invoices
+    match:
+      method: post
+      path: /stripe/*
+      event_type: invoice.*
+    response:
+      status: 503
+      delay: 2s
+  - match:
+      path: /github
+`
+
+func TestParse_Defaults(t *testing.T) {
+	rs, err := Parse([]byte(rulesYAML))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if rs.Len() != 3 {
+		t.Fatalf("Len=%d", rs.Len())
+	}
+	last := rs.rules[2]
+	if last.Name != "rule-3" || last.Response.Status != http.StatusOK {
+		t.Fatalf("unexpected defaults: %+v", last)
+	}
+	if rs.rules[1].Response.Delay != 2*time.Second {
+		t.Fatalf("delay=%v", rs.rules[1].Response.Delay)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rs, err := Parse([]byte(rulesYAML))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	h := http.Header{"Content-Type": {"application/json"}}
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"body path", Request{Method: "POST", Path: "/x", Provider: "stripe", Header: h, Body: []byte(`{"data":{"object":{"amount":0}}}`)}, "zero-amount"},
+		{"glob path and event", Request{Method: "POST", Path: "/stripe/live", EventType: "invoice.paid"}, "slow-invoices"},
+		{"method mismatch", Request{Method: "GET", Path: "/stripe/live", EventType: "invoice.paid"}, ""},
+		{"glob does not cross slash", Request{Method: "POST", Path: "/stripe/a/b", EventType: "invoice.paid"}, ""},
+		{"catch path", Request{Method: "POST", Path: "/github"}, "rule-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if r := rs.Match(tt.req); r != nil {
+				got = r.Name
+			}
+			if got != tt.want {
+				t.Fatalf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var none *Rules
+	if none.Match(Request{}) != nil || none.Len() != 0 {
+		t.Fatal("nil rules should match nothing")
+	}
+}
+
+func TestRender(t *testing.T) {
+	rs, err := Parse([]byte(rulesYAML))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	req := Request{
+		ID:        "abc",
+		Provider:  "stripe",
+		EventType: "charge.failed",
+		Header:    http.Header{"Content-Type": {"application/json"}},
+		Body:      []byte(`{"data":{"object":{"id":"ch_1","amount":0}}}`),
+	}
+	out, err := rs.Match(req).Render(req)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if string(out) != `{"id":"abc","object":"ch_1","event":"charge.failed"}` {
+		t.Fatalf("body=%s", out)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"status":   "rules:\n  - response: {status: 42}\n",
+		"template": "rules:\n  - response: {body: '{{.Nope'}\n",
+		"glob":     "rules:\n  - match: {path: '[a'}\n",
+	}
+	for name, in := range tests {
+		if _, err := Parse([]byte(in)); err == nil || !strings.Contains(err.Error(), "rule-1") {
+			t.Errorf("%s: expected error naming rule-1, got %v", name, err)
+		}
+	}
+}
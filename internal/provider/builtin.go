@@ -0,0 +1,79 @@
+package provider
+
+// builtins are checked in order. Providers identified by a dedicated header
+// come first; body-based detection (Mailgun) comes last as it is the weakest.
+var builtins = []Definition{
+	{
+		ID:      "stripe",
+		Detect:  DetectRule{Headers: []string{"Stripe-Signature"}},
+		Event:   EventTypeRule{JSON: []string{"type"}},
+		Signing: SignatureRule{Header: "Stripe-Signature", Scheme: SchemeStripe},
+	},
+	{
+		ID:      "github",
+		Detect:  DetectRule{Headers: []string{"X-GitHub-Event"}},
+		Event:   EventTypeRule{Header: "X-GitHub-Event"},
+		Signing: SignatureRule{Header: "X-Hub-Signature-256", Scheme: SchemeGitHub},
+	},
+	{
+		ID:      "shopify",
+		Detect:  DetectRule{Headers: []string{"X-Shopify-Hmac-SHA256"}},
+		Event:   EventTypeRule{Header: "X-Shopify-Topic"},
+		Signing: SignatureRule{Header: "X-Shopify-Hmac-SHA256", Scheme: SchemeHMACSHA256Base64},
+	},
+	{
+		ID:      "slack",
+		Detect:  DetectRule{Headers: []string{"X-Slack-Signature"}},
+		Event:   EventTypeRule{JSON: []string{"event.type", "type"}},
+		Signing: SignatureRule{Header: "X-Slack-Signature", Scheme: SchemeSlack},
+	},
+	{
+		ID:     "twilio",
+		Detect: DetectRule{Headers: []string{"X-Twilio-Signature"}},
+		// Form posts carry a status field; Event Streams send a JSON array.
+		Event:   EventTypeRule{JSON: []string{"MessageStatus", "SmsStatus", "CallStatus", "[0].type"}},
+		Signing: SignatureRule{Header: "X-Twilio-Signature", Scheme: SchemeTwilio},
+	},
+	{
+		ID:      "discord",
+		Detect:  DetectRule{Headers: []string{"X-Signature-Ed25519", "X-Signature-Timestamp"}},
+		Event:   EventTypeRule{JSON: []string{"event.type", "type"}},
+		Signing: SignatureRule{Header: "X-Signature-Ed25519", Scheme: SchemeEd25519},
+	},
+	{
+		ID:      "linear",
+		Detect:  DetectRule{Headers: []string{"Linear-Signature"}},
+		Event:   EventTypeRule{Header: "Linear-Event", JSON: []string{"type"}},
+		Signing: SignatureRule{Header: "Linear-Signature", Scheme: SchemeHMACSHA256Hex},
+	},
+	{
+		ID:      "paddle",
+		Detect:  DetectRule{Headers: []string{"Paddle-Signature"}},
+		Event:   EventTypeRule{JSON: []string{"event_type"}},
+		Signing: SignatureRule{Header: "Paddle-Signature", Scheme: SchemePaddle},
+	},
+	{
+		ID:      "lemonsqueezy",
+		Detect:  DetectRule{Headers: []string{"X-Event-Name", "X-Signature"}},
+		Event:   EventTypeRule{Header: "X-Event-Name", JSON: []string{"meta.event_name"}},
+		Signing: SignatureRule{Header: "X-Signature", Scheme: SchemeHMACSHA256Hex},
+	},
+	{
+		ID:      "sendgrid",
+		Detect:  DetectRule{Headers: []string{"X-Twilio-Email-Event-Webhook-Signature"}},
+		Event:   EventTypeRule{JSON: []string{"[0].event"}},
+		Signing: SignatureRule{Header: "X-Twilio-Email-Event-Webhook-Signature", Scheme: SchemeECDSA},
+	},
+	{
+		ID:      "pagerduty",
+		Detect:  DetectRule{Headers: []string{"X-PagerDuty-Signature"}},
+		Event:   EventTypeRule{JSON: []string{"event.event_type"}},
+		Signing: SignatureRule{Header: "X-PagerDuty-Signature", Scheme: SchemePagerDuty},
+	},
+	{
+		ID:      "mailgun",
+		Detect:  DetectRule{Body: []string{"signature.signature", "signature.token", "event-data"}},
+		Event:   EventTypeRule{JSON: []string{"event-data.event"}},
+		Signing: SignatureRule{JSON: "signature.signature", Scheme: SchemeMailgun},
+	},
+}
@@ -39,7 +39,7 @@ func TestDetect_GitHub(t *testing.T) {
 
 func TestDetect_Unknown(t *testing.T) {
 	h := http.Header{}
-	h.Set("X-Slack-Signature", "v0=abc123")
+	h.Set("X-Hub-Signature", "sha1=abc123")
 	prov, ev, sig := Detect(h, []byte(`{}`))
 	if prov != "unknown" {
 		t.Fatalf("prov=%q, want unknown", prov)
@@ -47,19 +47,111 @@ func TestDetect_Unknown(t *testing.T) {
 	if ev != "" {
 		t.Fatalf("ev=%q, want empty", ev)
 	}
-	if sig != "v0=abc123" {
-		t.Fatalf("sig=%q, want v0=abc123", sig)
+	if sig != "sha1=abc123" {
+		t.Fatalf("sig=%q, want sha1=abc123", sig)
 	}
 }
 
-func TestDetect_Shopify(t *testing.T) {
-	h := http.Header{}
-	h.Set("X-Shopify-Hmac-SHA256", "abc123")
-	prov, _, sig := Detect(h, []byte(`{}`))
-	if prov != "unknown" {
-		t.Fatalf("prov=%q, want unknown (shopify not yet implemented)", prov)
+func TestDetect_Builtins(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    string
+		prov    string
+		ev      string
+		sig     string
+	}{
+		{
+			name:    "shopify",
+			headers: map[string]string{"X-Shopify-Hmac-SHA256": "abc123", "X-Shopify-Topic": "orders/create"},
+			body:    `{}`,
+			prov:    "shopify",
+			ev:      "orders/create",
+			sig:     "abc123",
+		},
+		{
+			name:    "slack event callback",
+			headers: map[string]string{"X-Slack-Signature": "v0=abc", "X-Slack-Request-Timestamp": "1"},
+			body:    `{"type":"event_callback","event":{"type":"app_mention"}}`,
+			prov:    "slack",
+			ev:      "app_mention",
+			sig:     "v0=abc",
+		},
+		{
+			name:    "twilio form",
+			headers: map[string]string{"X-Twilio-Signature": "c2ln", "Content-Type": "application/x-www-form-urlencoded"},
+			body:    `MessageSid=SM1&MessageStatus=delivered`,
+			prov:    "twilio",
+			ev:      "delivered",
+			sig:     "c2ln",
+		},
+		{
+			name:    "discord",
+			headers: map[string]string{"X-Signature-Ed25519": "ff", "X-Signature-Timestamp": "1"},
+			body:    `{"type":1}`,
+			prov:    "discord",
+			ev:      "1",
+			sig:     "ff",
+		},
+		{
+			name:    "linear",
+			headers: map[string]string{"Linear-Signature": "ab", "Linear-Event": "Issue"},
+			body:    `{"type":"Issue","action":"create"}`,
+			prov:    "linear",
+			ev:      "Issue",
+			sig:     "ab",
+		},
+		{
+			name:    "paddle",
+			headers: map[string]string{"Paddle-Signature": "ts=1;h1=ab"},
+			body:    `{"event_type":"transaction.completed"}`,
+			prov:    "paddle",
+			ev:      "transaction.completed",
+			sig:     "ts=1;h1=ab",
+		},
+		{
+			name:    "lemonsqueezy",
+			headers: map[string]string{"X-Event-Name": "order_created", "X-Signature": "ab"},
+			body:    `{"meta":{"event_name":"order_created"}}`,
+			prov:    "lemonsqueezy",
+			ev:      "order_created",
+			sig:     "ab",
+		},
+		{
+			name:    "sendgrid",
+			headers: map[string]string{"X-Twilio-Email-Event-Webhook-Signature": "MEUC"},
+			body:    `[{"event":"delivered","email":"a@example.com"}]`,
+			prov:    "sendgrid",
+			ev:      "delivered",
+			sig:     "MEUC",
+		},
+		{
+			name:    "pagerduty",
+			headers: map[string]string{"X-PagerDuty-Signature": "v1=ab"},
+			body:    `{"event":{"event_type":"incident.triggered"}}`,
+			prov:    "pagerduty",
+			ev:      "incident.triggered",
+			sig:     "v1=ab",
+		},
+		{
+			name:    "mailgun",
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    `{"signature":{"timestamp":"1","token":"t","signature":"ab"},"event-data":{"event":"delivered"}}`,
+			prov:    "mailgun",
+			ev:      "delivered",
+			sig:     "ab",
+		},
 	}
-	if sig != "abc123" {
-		t.Fatalf("sig=%q", sig)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			prov, ev, sig := Detect(h, []byte(tt.body))
+			if prov != tt.prov || ev != tt.ev || sig != tt.sig {
+				t.Fatalf("Detect = (%q, %q, %q), want (%q, %q, %q)", prov, ev, sig, tt.prov, tt.ev, tt.sig)
+			}
+		})
 	}
 }
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDir returns ~/.hooktm/providers.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".hooktm", "providers")
+}
+
+// LoadDir reads every *.yaml / *.yml file in dir as one provider Definition.
+// A missing directory is not an error.
+func LoadDir(dir string) ([]*Definition, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var out []*Definition
+	for _, name := range names {
+		d, err := LoadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// LoadFile reads a single provider definition.
+func LoadFile(path string) (*Definition, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Definition{}
+	if err := yaml.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("provider %s: %w", path, err)
+	}
+	if err := d.validate(); err != nil {
+		return nil, fmt.Errorf("provider %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// LoadRegistry returns the built-in registry extended with the definitions in
+// dir. User definitions are checked first and replace built-ins of the same name.
+func LoadRegistry(dir string) (*Registry, error) {
+	defs, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := Default()
+	for i := len(defs) - 1; i >= 0; i-- {
+		r.Prepend(defs[i])
+	}
+	return r, nil
+}
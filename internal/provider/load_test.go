@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRegistry_UserDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "acme.yaml"), `
+name: acme
+detect:
+  headers: [X-Acme-Signature]
+  match:
+    User-Agent: "Acme-Hooks/*"
+event_type:
+  json: [data.kind]
+signature:
+  header: X-Acme-Signature
+  scheme: hmac-sha256-hex
+`)
+	// Overrides the built-in GitHub provider.
+	writeFile(t, filepath.Join(dir, "github.yml"), `
+name: github
+detect:
+  headers: [X-GitHub-Event]
+event_type:
+  header: X-GitHub-Event
+signature:
+  header: X-Hub-Signature
+  scheme: hmac-sha256-hex
+`)
+	writeFile(t, filepath.Join(dir, "README.txt"), "ignored")
+
+	r, err := LoadRegistry(dir)
+	if err != nil {
+		t.Fatalf("LoadRegistry: %v", err)
+	}
+
+	h := http.Header{}
+	h.Set("X-Acme-Signature", "sig")
+	h.Set("User-Agent", "Acme-Hooks/2.1")
+	prov, ev, sig := r.Detect(h, []byte(`{"data":{"kind":"widget.created"}}`))
+	if prov != "acme" || ev != "widget.created" || sig != "sig" {
+		t.Fatalf("Detect = (%q, %q, %q)", prov, ev, sig)
+	}
+
+	// Header glob must match.
+	h.Set("User-Agent", "curl/8.0")
+	if prov, _, _ := r.Detect(h, nil); prov != Unknown {
+		t.Fatalf("prov=%q, want unknown", prov)
+	}
+
+	gh, ok := r.Lookup("github")
+	if !ok || gh.Scheme() != SchemeHMACSHA256Hex {
+		t.Fatalf("expected user github definition, got %+v", gh)
+	}
+	if _, ok := r.Lookup("stripe"); !ok {
+		t.Fatal("expected built-in stripe to remain registered")
+	}
+}
+
+func TestLoadDir_Missing(t *testing.T) {
+	defs, err := LoadDir(filepath.Join(t.TempDir(), "nope"))
+	if err != nil || defs != nil {
+		t.Fatalf("LoadDir = %v, %v; want nil, nil", defs, err)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no name":        "detect: {headers: [X-A]}",
+		"no detect":      "name: a",
+		"unknown scheme": "name: a\ndetect: {headers: [X-A]}\nsignature: {header: X-A, scheme: rot13}",
+		"reserved name":  "name: unknown\ndetect: {headers: [X-A]}",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "p.yaml")
+			writeFile(t, path, content)
+			if _, err := LoadFile(path); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"hooktm/internal/form"
+)
+
+// Provider recognises webhooks sent by one service.
+type Provider interface {
+	// Name is the identifier stored with captured webhooks (e.g. "stripe").
+	Name() string
+	// Match reports whether the request was sent by this provider.
+	Match(h http.Header, body []byte) bool
+	// EventType extracts the event type, or "" if unknown.
+	EventType(h http.Header, body []byte) string
+	// Signature returns the raw signature value, or "" if unsigned.
+	Signature(h http.Header, body []byte) string
+	// Scheme names the signing scheme (one of the Scheme* constants), or "".
+	Scheme() string
+}
+
+// Signing schemes understood by hooktm.
+const (
+	SchemeStripe           = "stripe"             // Stripe-Signature: t=<ts>,v1=<hex hmac-sha256 of "ts.body">
+	SchemeGitHub           = "github"             // sha256=<hex hmac-sha256 of body>
+	SchemeSlack            = "slack"              // v0=<hex hmac-sha256 of "v0:ts:body">
+	SchemeTwilio           = "twilio"             // base64 hmac-sha1 of URL + sorted form params
+	SchemeHMACSHA256Hex    = "hmac-sha256-hex"    // hex hmac-sha256 of body
+	SchemeHMACSHA256Base64 = "hmac-sha256-base64" // base64 hmac-sha256 of body (Shopify)
+	SchemePaddle           = "paddle"             // ts=<ts>;h1=<hex hmac-sha256 of "ts:body">
+	SchemeMailgun          = "mailgun"            // hex hmac-sha256 of timestamp+token
+	SchemePagerDuty        = "pagerduty"          // v1=<hex hmac-sha256 of body>
+	SchemeEd25519          = "ed25519"            // public-key signature (Discord)
+	SchemeECDSA            = "ecdsa"              // public-key signature (SendGrid)
+)
+
+var knownSchemes = map[string]bool{
+	SchemeStripe: true, SchemeGitHub: true, SchemeSlack: true, SchemeTwilio: true,
+	SchemeHMACSHA256Hex: true, SchemeHMACSHA256Base64: true, SchemePaddle: true,
+	SchemeMailgun: true, SchemePagerDuty: true, SchemeEd25519: true, SchemeECDSA: true,
+}
+
+// Definition is a declarative Provider. Built-ins are definitions too, and
+// users can add their own as YAML files (see LoadDir).
+//
+//	name: acme
+//	detect:
+//	  headers: [X-Acme-Signature]          # all must be present
+//	  match: {User-Agent: "Acme-Hooks/*"}   # header value globs
+//	  body: [data.object]                  # JSON/form paths that must exist
+//	event_type:
+//	  header: X-Acme-Event                 # tried first
+//	  json: [data.type, type]              # then the first non-empty path
+//	signature:
+//	  header: X-Acme-Signature             # or json: signature.value
+//	  scheme: hmac-sha256-hex
+type Definition struct {
+	ID      string        `yaml:"name"`
+	Detect  DetectRule    `yaml:"detect"`
+	Event   EventTypeRule `yaml:"event_type"`
+	Signing SignatureRule `yaml:"signature"`
+}
+
+type DetectRule struct {
+	Headers []string          `yaml:"headers"`
+	Match   map[string]string `yaml:"match"`
+	Body    []string          `yaml:"body"`
+}
+
+type EventTypeRule struct {
+	Header string   `yaml:"header"`
+	JSON   []string `yaml:"json"`
+}
+
+type SignatureRule struct {
+	Header string `yaml:"header"`
+	JSON   string `yaml:"json"`
+	Scheme string `yaml:"scheme"`
+}
+
+func (d *Definition) Name() string   { return d.ID }
+func (d *Definition) Scheme() string { return d.Signing.Scheme }
+
+func (d *Definition) Match(h http.Header, body []byte) bool {
+	for _, k := range d.Detect.Headers {
+		if strings.TrimSpace(h.Get(k)) == "" {
+			return false
+		}
+	}
+	for k, pattern := range d.Detect.Match {
+		ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(h.Get(k)))
+		if !ok {
+			return false
+		}
+	}
+	if len(d.Detect.Body) > 0 {
+		doc := parseBody(h, body)
+		for _, p := range d.Detect.Body {
+			if _, ok := lookupPath(doc, p); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (d *Definition) EventType(h http.Header, body []byte) string {
+	if d.Event.Header != "" {
+		if ev := strings.TrimSpace(h.Get(d.Event.Header)); ev != "" {
+			return ev
+		}
+	}
+	if len(d.Event.JSON) == 0 {
+		return ""
+	}
+	doc := parseBody(h, body)
+	for _, p := range d.Event.JSON {
+		if v, ok := lookupPath(doc, p); ok {
+			if s := scalarString(v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func (d *Definition) Signature(h http.Header, body []byte) string {
+	if d.Signing.Header != "" {
+		return h.Get(d.Signing.Header)
+	}
+	if d.Signing.JSON != "" {
+		if v, ok := lookupPath(parseBody(h, body), d.Signing.JSON); ok {
+			return scalarString(v)
+		}
+	}
+	return ""
+}
+
+// validate checks a definition loaded from user input.
+func (d *Definition) validate() error {
+	d.ID = strings.ToLower(strings.TrimSpace(d.ID))
+	if d.ID == "" {
+		return fmt.Errorf("missing name")
+	}
+	if d.ID == Unknown {
+		return fmt.Errorf("name %q is reserved", d.ID)
+	}
+	if len(d.Detect.Headers) == 0 && len(d.Detect.Match) == 0 && len(d.Detect.Body) == 0 {
+		return fmt.Errorf("%s: detect needs at least one of headers, match or body", d.ID)
+	}
+	if d.Signing.Header != "" && d.Signing.JSON != "" {
+		return fmt.Errorf("%s: signature takes either header or json, not both", d.ID)
+	}
+	if d.Signing.Scheme != "" && !knownSchemes[d.Signing.Scheme] {
+		return fmt.Errorf("%s: unknown signature scheme %q", d.ID, d.Signing.Scheme)
+	}
+	return nil
+}
+
+// parseBody decodes a JSON body, falling back to url-encoded or multipart form
+// fields so paths work for form posts (e.g. Twilio, Mailgun) too. Returns nil
+// if neither parses.
+func parseBody(h http.Header, body []byte) any {
+	if len(body) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		return v
+	}
+	if f, err := form.Parse(h.Get("Content-Type"), body); err == nil && f != nil {
+		return f.Values()
+	}
+	return nil
+}
+
+// BodyValue looks up a dotted path (optionally JSONPath-style, "$.data.type")
+// in a JSON or form body. Non-scalar values are returned as JSON, and an
+// explicit null as "null".
+func BodyValue(h http.Header, body []byte, p string) (string, bool) {
+	p = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(p), "$"), ".")
+	v, ok := lookupPath(parseBody(h, body), p)
+	if !ok {
+		return "", false
+	}
+	switch v.(type) {
+	case nil, map[string]any, []any:
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+	return scalarString(v), true
+}
+
+// lookupPath walks a dotted path such as "data.object.id" or "[0].event".
+// A field set to null is present, with a nil value.
+func lookupPath(v any, p string) (any, bool) {
+	p = strings.TrimSpace(p)
+	if p == "" || v == nil {
+		return nil, false
+	}
+	for _, seg := range splitPath(p) {
+		switch cur := v.(type) {
+		case map[string]any:
+			next, ok := cur[seg]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(cur) {
+				return nil, false
+			}
+			v = cur[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// splitPath turns "a.b[0].c" into ["a", "b", "0", "c"].
+func splitPath(p string) []string {
+	p = strings.ReplaceAll(p, "[", ".")
+	p = strings.ReplaceAll(p, "]", "")
+	var out []string
+	for _, seg := range strings.Split(p, ".") {
+		if seg != "" {
+			out = append(out, seg)
+		}
+	}
+	return out
+}
+
+func scalarString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Unknown is the provider name used when no registered provider matches.
+const Unknown = "unknown"
+
+// Registry holds providers in detection order; the first match wins.
+type Registry struct {
+	providers []Provider
+}
+
+// NewRegistry returns a registry with the given providers, in order.
+func NewRegistry(ps ...Provider) *Registry {
+	r := &Registry{}
+	for _, p := range ps {
+		r.Register(p)
+	}
+	return r
+}
+
+// Default returns a registry with the built-in providers.
+func Default() *Registry {
+	ps := make([]Provider, 0, len(builtins))
+	for i := range builtins {
+		d := builtins[i]
+		ps = append(ps, &d)
+	}
+	return NewRegistry(ps...)
+}
+
+// Register adds p to the end of the detection order. A provider with the same
+// name is replaced in place, so user definitions can override built-ins.
+func (r *Registry) Register(p Provider) {
+	for i, existing := range r.providers {
+		if strings.EqualFold(existing.Name(), p.Name()) {
+			r.providers[i] = p
+			return
+		}
+	}
+	r.providers = append(r.providers, p)
+}
+
+// Prepend adds p ahead of all other providers, replacing any with the same name.
+func (r *Registry) Prepend(p Provider) {
+	out := []Provider{p}
+	for _, existing := range r.providers {
+		if !strings.EqualFold(existing.Name(), p.Name()) {
+			out = append(out, existing)
+		}
+	}
+	r.providers = out
+}
+
+// Lookup returns the provider registered under name.
+func (r *Registry) Lookup(name string) (Provider, bool) {
+	for _, p := range r.providers {
+		if strings.EqualFold(p.Name(), name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Providers returns the registered providers in detection order.
+func (r *Registry) Providers() []Provider {
+	return append([]Provider(nil), r.providers...)
+}
+
+// Detect returns (providerName, eventType, signatureHeaderValue).
+// This is intentionally heuristic and safe: unknown is the default.
+func (r *Registry) Detect(h http.Header, body []byte) (string, string, string) {
+	for _, p := range r.providers {
+		if p.Match(h, body) {
+			return p.Name(), p.EventType(h, body), p.Signature(h, body)
+		}
+	}
+	return Unknown, "", firstNonEmpty(
+		h.Get("X-Hub-Signature-256"),
+		h.Get("X-Hub-Signature"),
+		h.Get("X-Signature"),
+		h.Get("X-Webhook-Signature"),
+	)
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"hooktm/internal/contentenc"
+	"hooktm/internal/form"
+	"hooktm/internal/provider"
+	"hooktm/internal/store"
+)
+
+// Analysis is what is derived from a request before it is stored: the
+// decoded payload, the sending provider and the text indexed for search.
+type Analysis struct {
+	// Plain is the body used for detection and signature checks: Decoded
+	// when the body was compressed, else the body as sent.
+	Plain     []byte
+	Decoded   []byte
+	Provider  string
+	EventType string
+	Signature string
+	Form      *form.Form
+	BodyText  string
+}
+
+// Analyze decodes the body and runs provider detection, form parsing and
+// text extraction. partial means only the head of the body is at hand (it
+// was spooled), which is too little to decode.
+func Analyze(providers *provider.Registry, h http.Header, body []byte, partial bool) Analysis {
+	a := Analysis{Plain: body}
+	if codings := contentenc.Codings(h); len(codings) > 0 && !partial {
+		decoded, err := contentenc.Decode(codings, body, MaxRequestBodySize)
+		if err != nil {
+			log.Printf("[hooktm] failed to decode %s body: %v", strings.Join(codings, ", "), err)
+		} else {
+			a.Plain, a.Decoded = decoded, decoded
+		}
+	}
+	if providers == nil {
+		providers = provider.Default()
+	}
+	a.Provider, a.EventType, a.Signature = providers.Detect(h, a.Plain)
+	if !partial {
+		var err error
+		if a.Form, err = form.Parse(h.Get("Content-Type"), a.Plain); err != nil {
+			log.Printf("[hooktm] failed to decode form body: %v", err)
+		}
+	}
+	a.BodyText = extractBodyText(h.Get("Content-Type"), a.Plain, a.Form)
+	return a
+}
+
+// Fill copies the analysis into p.
+func (a Analysis) Fill(p *store.InsertParams) {
+	p.DecodedBody = a.Decoded
+	p.Provider = a.Provider
+	p.EventType = a.EventType
+	p.Signature = a.Signature
+	p.BodyText = a.BodyText
+	if a.Form != nil {
+		p.Form = a.Form.Fields
+		p.Attachments = a.Form.Attachments
+	}
+}
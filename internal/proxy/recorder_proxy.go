@@ -2,57 +2,108 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"hooktm/internal/chaos"
+	"hooktm/internal/form"
+	"hooktm/internal/mock"
 	"hooktm/internal/provider"
+	"hooktm/internal/signature"
 	"hooktm/internal/store"
 	"hooktm/internal/urlutil"
 
 	nanoid "github.com/matoous/go-nanoid/v2"
 )
 
-// MaxRequestBodySize limits incoming request bodies to prevent memory exhaustion.
+// MaxRequestBodySize is the most of a request body kept in memory, and the
+// default body limit. Paths allowed larger bodies spool them to disk.
 const MaxRequestBodySize = 10 * 1024 * 1024 // 10 MB
 
+// MaxResponseBodySize caps how much of the forward target's response body is
+// recorded. The full response is still streamed back to the caller.
+const MaxResponseBodySize = 64 * 1024 // 64 KB
+
 type RecorderProxy struct {
 	target *url.URL
 	store  *store.Store
 	client *http.Client
+
+	// Providers detects the sending provider; defaults to the built-ins.
+	Providers *provider.Registry
+	// Verifier checks signatures when set; nil skips verification.
+	Verifier *signature.Verifier
+	// Mocks answers webhooks in record-only mode; nil (or no matching rule)
+	// answers 200 OK with an empty body.
+	Mocks *mock.Rules
+	// Chaos injects faults while forwarding; nil forwards every request as-is.
+	Chaos *chaos.Injector
+	// Routes picks the forward targets per webhook; the first match wins and
+	// unmatched webhooks go to the default target (or are recorded only).
+	Routes []Route
+
+	// MaxBodySize is the largest body accepted; BodyLimits overrides it per
+	// path prefix (longest prefix wins). Bodies over MaxRequestBodySize are
+	// spooled to SpoolDir (default: the OS temp dir) and stored in chunks.
+	MaxBodySize int64
+	BodyLimits  map[string]int64
+	SpoolDir    string
 }
 
 func NewRecorderProxy(target *url.URL, s *store.Store) *RecorderProxy {
 	return &RecorderProxy{
-		target: target,
-		store:  s,
-		client: &http.Client{Timeout: 60 * time.Second},
+		target:      target,
+		store:       s,
+		client:      &http.Client{Timeout: 60 * time.Second},
+		Providers:   provider.Default(),
+		MaxBodySize: MaxRequestBodySize,
 	}
 }
 
 func (p *RecorderProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 
-	// Limit request body size to prevent memory exhaustion.
-	limitedReader := io.LimitReader(r.Body, MaxRequestBodySize+1)
-	body, err := io.ReadAll(limitedReader)
+	limit := p.bodyLimit(r.URL.Path)
+	if r.ContentLength > limit {
+		log.Printf("[hooktm] request body too large: %d bytes", r.ContentLength)
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	defer func() {
+		if err := r.Body.Close(); err != nil {
+			log.Printf("[hooktm] failed to close request body: %v", err)
+		}
+	}()
+
+	// Keep up to MaxRequestBodySize in memory; the rest is spooled. Chunked
+	// bodies have no Content-Length, so a lower limit is enforced here.
+	head := min(limit, MaxRequestBodySize)
+	body, err := io.ReadAll(io.LimitReader(r.Body, head+1))
 	if err != nil {
 		log.Printf("[hooktm] failed to read request body: %v", err)
 		http.Error(w, "failed to read request body", http.StatusBadRequest)
 		return
 	}
-	if err := r.Body.Close(); err != nil {
-		log.Printf("[hooktm] failed to close request body: %v", err)
-	}
-
-	// Check if body exceeded limit.
-	if len(body) > MaxRequestBodySize {
-		log.Printf("[hooktm] request body too large: %d bytes", len(body))
-		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
-		return
+	pl := &payload{data: body}
+	if int64(len(body)) > head {
+		if limit <= MaxRequestBodySize {
+			log.Printf("[hooktm] request body too large: over %d bytes", limit)
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		pl.spool, err = newSpooledBody(p.SpoolDir, body, r.Body, limit)
+		if err != nil {
+			log.Printf("[hooktm] failed to spool request body: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		defer pl.spool.Close()
 	}
 
 	id, err := nanoid.New()
@@ -62,21 +113,91 @@ func (p *RecorderProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prov, eventType, sig := provider.Detect(r.Header, body)
-	bodyText := extractBodyText(r.Header.Get("Content-Type"), body)
+	// Compressed bodies are forwarded and stored as sent; detection, search
+	// and signature checks use the decoded payload. Only the head of a
+	// spooled body is in memory.
+	a := Analyze(p.Providers, r.Header, body, pl.spool != nil)
+	plain, prov, eventType := a.Plain, a.Provider, a.EventType
+	var sigValid *bool
+	if pl.spool == nil {
+		// Spooled bodies aren't in memory; `hooktm verify` can check them later.
+		sigValid = p.verify(r, prov, plain, now)
+	}
 
 	var statusCode *int
 	var respMS int64
+	var resp *forwardResponse
+	var mockRule string
+	var fault string
+	var deliveries []store.Delivery
+	mreq := mock.Request{
+		ID:        id,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Header:    r.Header,
+		Body:      plain,
+		Provider:  prov,
+		EventType: eventType,
+	}
 
-	if p.target != nil {
-		statusCode, respMS, err = p.forward(w, r, body)
-		if err != nil {
-			log.Printf("[hooktm] forward failed: %v", err)
-			http.Error(w, err.Error(), http.StatusBadGateway)
+	targets, primary := p.resolveTargets(r.URL.Path, prov, eventType)
+	var d chaos.Decision
+	if len(targets) > 0 {
+		d = p.Chaos.Decide(r.URL.Path)
+		fault = d.String()
+	}
+	// A spooled body streams to a single target while it is captured. In
+	// every other case it is captured first, so an oversized body gets 413.
+	if pl.spool != nil && (len(targets) != 1 || d.Drop || d.Error || d.Duplicate) {
+		if err := pl.spool.finish(); err != nil {
+			rejectSpool(w, pl.spool, err)
+			return
+		}
+	}
+
+	if len(targets) > 0 {
+		switch {
+		case d.Drop:
+			// No status: the caller never got a response.
+			log.Printf("[hooktm] chaos: dropping %s %s", r.Method, r.URL.Path)
+			dropConnection(w)
+			respMS = time.Since(now).Milliseconds()
+		case d.Error:
+			log.Printf("[hooktm] chaos: answering 502 for %s %s", r.Method, r.URL.Path)
+			http.Error(w, "hooktm: injected fault", http.StatusBadGateway)
 			sc := http.StatusBadGateway
 			statusCode = &sc
 			respMS = time.Since(now).Milliseconds()
+		default:
+			if d.Delay > 0 {
+				sleepContext(r.Context(), d.Delay)
+			}
+			resp, deliveries, err = p.fanOut(w, r, pl, targets, primary)
+			if err != nil {
+				log.Printf("[hooktm] forward failed: %v", err)
+				sc := http.StatusBadGateway
+				if pl.spool != nil && pl.spool.tooLarge {
+					sc = http.StatusRequestEntityTooLarge
+				}
+				http.Error(w, err.Error(), sc)
+				statusCode = &sc
+				respMS = time.Since(now).Milliseconds()
+			} else {
+				statusCode = &resp.statusCode
+				respMS = resp.ms
+			}
+			if d.Duplicate {
+				for _, t := range targets {
+					p.duplicate(t, r, pl)
+				}
+			}
 		}
+	} else if rule := p.Mocks.Match(mreq); rule != nil {
+		resp = p.respondMock(w, r, rule, mreq)
+		mockRule = rule.Name
+		statusCode = &resp.statusCode
+		respMS = time.Since(now).Milliseconds()
 	} else {
 		// Record-only mode: return 200 OK without forwarding.
 		w.WriteHeader(http.StatusOK)
@@ -85,35 +206,212 @@ func (p *RecorderProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		respMS = time.Since(now).Milliseconds()
 	}
 
-	if err := p.store.InsertWebhook(r.Context(), store.InsertParams{
-		ID:         id,
-		CreatedAt:  now.UnixMilli(),
+	if pl.spool != nil {
+		// Capture whatever the forward target didn't read.
+		if err := pl.spool.finish(); err != nil {
+			log.Printf("[hooktm] failed to capture request body: %v", err)
+			return
+		}
+	}
+
+	params := store.InsertParams{
+		ID:             id,
+		CreatedAt:      now.UnixMilli(),
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		Query:          r.URL.RawQuery,
+		Headers:        cloneHeader(r.Header),
+		Body:           body,
+		StatusCode:     statusCode,
+		SignatureValid: sigValid,
+		MockRule:       mockRule,
+		Fault:          fault,
+		Deliveries:     deliveries,
+		ResponseMS:     respMS,
+	}
+	a.Fill(&params)
+	if pl.spool != nil {
+		params.BodyReader = pl.spool.reader()
+		params.BodySize = pl.spool.size
+	}
+	if resp != nil {
+		params.ResponseHeaders = resp.headers
+		params.ResponseBody = resp.body
+		params.ResponseTruncated = resp.truncated
+	}
+	if err := p.store.InsertWebhook(r.Context(), params); err != nil {
+		log.Printf("[hooktm] failed to store webhook: %v", err)
+	}
+}
+
+// verify checks the request signature. Invalid signatures are logged but the
+// request is still forwarded: the app decides whether to reject it.
+func (p *RecorderProxy) verify(r *http.Request, prov string, body []byte, now time.Time) *bool {
+	if p.Verifier == nil || prov == provider.Unknown {
+		return nil
+	}
+	valid, err := p.Verifier.Verify(prov, signature.Request{
 		Method:     r.Method,
 		Path:       r.URL.Path,
 		Query:      r.URL.RawQuery,
-		Headers:    cloneHeader(r.Header),
+		Headers:    r.Header,
 		Body:       body,
-		Provider:   prov,
-		EventType:  eventType,
-		Signature:  sig,
-		StatusCode: statusCode,
-		ResponseMS: respMS,
-		BodyText:   bodyText,
-	}); err != nil {
-		log.Printf("[hooktm] failed to store webhook: %v", err)
+		ReceivedAt: now,
+		Host:       r.Host,
+	})
+	if valid != nil && !*valid {
+		log.Printf("[hooktm] invalid %s signature on %s %s: %v", prov, r.Method, r.URL.Path, err)
+	}
+	return valid
+}
+
+// respondMock answers with a mock rule's response after its delay. The delay
+// is cut short if the caller gives up, and the response is still recorded.
+func (p *RecorderProxy) respondMock(w http.ResponseWriter, r *http.Request, rule *mock.Rule, req mock.Request) *forwardResponse {
+	start := time.Now()
+	if d := rule.Response.Delay; d > 0 {
+		sleepContext(r.Context(), d)
+	}
+
+	status := rule.Response.Status
+	body, err := rule.Render(req)
+	if err != nil {
+		log.Printf("[hooktm] mock rule %q: %v", rule.Name, err)
+		status = http.StatusInternalServerError
+		body = []byte(err.Error())
+	}
+	for k, v := range rule.Response.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+	log.Printf("[hooktm] mock rule %q → %d for %s %s", rule.Name, status, r.Method, r.URL.Path)
+
+	captured := &cappedBuffer{max: MaxResponseBodySize}
+	_, _ = captured.Write(body)
+	return &forwardResponse{
+		statusCode: status,
+		ms:         time.Since(start).Milliseconds(),
+		headers:    cloneHeader(w.Header()),
+		body:       captured.Bytes(),
+		truncated:  captured.truncated,
+	}
+}
+
+// forwardResponse is what the forward target answered, as recorded.
+type forwardResponse struct {
+	statusCode int
+	ms         int64
+	headers    map[string][]string
+	body       []byte
+	truncated  bool
+}
+
+// fanOut forwards to every target concurrently. The primary target's response
+// is streamed to the caller; the others are only recorded as deliveries.
+func (p *RecorderProxy) fanOut(w http.ResponseWriter, r *http.Request, body *payload, targets []*url.URL, primary int) (*forwardResponse, []store.Delivery, error) {
+	deliveries := make([]store.Delivery, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		if i == primary {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, t *url.URL) {
+			defer wg.Done()
+			var err error
+			if _, deliveries[i], err = p.deliver(&discardWriter{}, t, r, body); err != nil {
+				log.Printf("[hooktm] forward to %s failed: %v", deliveries[i].TargetURL, err)
+			}
+		}(i, t)
+	}
+
+	resp, d, err := p.deliver(w, targets[primary], r, body)
+	d.Primary = true
+	deliveries[primary] = d
+	if len(targets) > 1 {
+		// Let the caller finish reading while the other targets answer.
+		if f, ok := w.(http.Flusher); ok && err == nil {
+			f.Flush()
+		}
+	}
+	wg.Wait()
+	return resp, deliveries, err
+}
+
+// deliver forwards to one target and describes the outcome.
+func (p *RecorderProxy) deliver(w http.ResponseWriter, target *url.URL, r *http.Request, body *payload) (*forwardResponse, store.Delivery, error) {
+	start := time.Now()
+	d := store.Delivery{TargetURL: forwardURL(target, r).String()}
+	resp, err := p.forward(w, target, r, body)
+	if err != nil {
+		d.Error = err.Error()
+		d.DurationMS = time.Since(start).Milliseconds()
+		return nil, d, err
 	}
+	d.StatusCode = &resp.statusCode
+	d.DurationMS = resp.ms
+	return resp, d, nil
 }
 
-func (p *RecorderProxy) forward(w http.ResponseWriter, r *http.Request, body []byte) (*int, int64, error) {
+func (p *RecorderProxy) forward(w http.ResponseWriter, target *url.URL, r *http.Request, body *payload) (*forwardResponse, error) {
 	start := time.Now()
 
-	outURL := *p.target
+	req, err := newForwardRequest(r.Context(), target, r, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	for k, vs := range resp.Header {
+		if isHopByHopHeader(k) {
+			continue
+		}
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Stream to the caller while keeping a capped copy for the record.
+	captured := &cappedBuffer{max: MaxResponseBodySize}
+	_, _ = io.Copy(w, io.TeeReader(resp.Body, captured))
+
+	return &forwardResponse{
+		statusCode: resp.StatusCode,
+		ms:         time.Since(start).Milliseconds(),
+		headers:    cloneHeader(resp.Header),
+		body:       captured.Bytes(),
+		truncated:  captured.truncated,
+	}, nil
+}
+
+// forwardURL joins the target with the incoming request's path and query.
+func forwardURL(target *url.URL, r *http.Request) *url.URL {
+	outURL := *target
 	outURL.Path = urlutil.SingleJoiningSlash(outURL.Path, r.URL.Path)
 	outURL.RawQuery = r.URL.RawQuery
+	return &outURL
+}
 
-	req, err := http.NewRequestWithContext(r.Context(), r.Method, outURL.String(), bytes.NewReader(body))
+// newForwardRequest builds the request sent to a forward target.
+func newForwardRequest(ctx context.Context, target *url.URL, r *http.Request, body *payload) (*http.Request, error) {
+	src, size := body.open(r.ContentLength)
+	req, err := http.NewRequestWithContext(ctx, r.Method, forwardURL(target, r).String(), src)
 	if err != nil {
-		return nil, 0, err
+		if c, ok := src.(io.Closer); ok {
+			_ = c.Close()
+		}
+		return nil, err
+	}
+	req.ContentLength = size
+	if size == 0 && body.spool == nil {
+		req.Body = http.NoBody
 	}
 
 	// Copy headers (excluding hop-by-hop).
@@ -127,30 +425,80 @@ func (p *RecorderProxy) forward(w http.ResponseWriter, r *http.Request, body []b
 		}
 	}
 	// Preserve Host for apps that rely on it.
-	req.Host = p.target.Host
+	req.Host = target.Host
+	return req, nil
+}
 
-	resp, err := p.client.Do(req)
+// duplicate delivers the request to a forward target a second time in the
+// background. The duplicate's response is only logged. Spooled bodies are
+// sent before returning, while the spool file still exists.
+func (p *RecorderProxy) duplicate(target *url.URL, r *http.Request, body *payload) {
+	req, err := newForwardRequest(context.WithoutCancel(r.Context()), target, r, body)
 	if err != nil {
-		return nil, 0, err
+		log.Printf("[hooktm] chaos: duplicate failed: %v", err)
+		return
 	}
-	defer resp.Body.Close()
-
-	for k, vs := range resp.Header {
-		if isHopByHopHeader(k) {
-			continue
+	send := func() {
+		resp, err := p.client.Do(req)
+		if err != nil {
+			log.Printf("[hooktm] chaos: duplicate %s %s failed: %v", req.Method, req.URL.Path, err)
+			return
 		}
-		for _, v := range vs {
-			w.Header().Add(k, v)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		log.Printf("[hooktm] chaos: duplicate %s %s → %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	if body.spool != nil {
+		send()
+		return
+	}
+	go send()
+}
+
+// dropConnection closes the caller's connection without a response, so the
+// provider sees a network error. Without hijacking support it answers 502.
+func dropConnection(w http.ResponseWriter) {
+	if hj, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			_ = conn.Close()
+			return
 		}
 	}
-	w.WriteHeader(resp.StatusCode)
-	_, _ = io.Copy(w, resp.Body)
+	http.Error(w, "hooktm: injected fault", http.StatusBadGateway)
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// cappedBuffer keeps the first max bytes written to it and drops the rest.
+// Writes never fail, so it is safe to use as the sink of an io.TeeReader.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
 
-	ms := time.Since(start).Milliseconds()
-	sc := resp.StatusCode
-	return &sc, ms, nil
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	b.buf.Write(p)
+	return len(p), nil
 }
 
+func (b *cappedBuffer) Bytes() []byte { return b.buf.Bytes() }
+
 func cloneHeader(h http.Header) map[string][]string {
 	out := make(map[string][]string, len(h))
 	for k, vs := range h {
@@ -171,10 +519,19 @@ func isHopByHopHeader(k string) bool {
 	}
 }
 
-func extractBodyText(contentType string, body []byte) string {
+// extractBodyText returns the text indexed for search: decoded fields for
+// forms, the raw body for JSON, XML and text, nothing for other types.
+func extractBodyText(contentType string, body []byte, frm *form.Form) string {
 	if len(body) == 0 {
 		return ""
 	}
+	if frm != nil {
+		body = []byte(frm.Text())
+		if len(body) > 200_000 {
+			body = body[:200_000]
+		}
+		return string(body)
+	}
 	if len(body) > 200_000 {
 		// cap for MVP; keep DB usable.
 		body = body[:200_000]
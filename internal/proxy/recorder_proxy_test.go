@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"hooktm/internal/contentenc"
+	"hooktm/internal/store"
+)
+
+func TestServeHTTP_DecodesForms(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	p := NewRecorderProxy(nil, s)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("subject", "Quarterly report")
+	fw, _ := mw.CreateFormFile("attachment-1", "report.csv")
+	_, _ = fw.Write([]byte("a,b\n1,2\n"))
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/inbound", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	p.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/sms", bytes.NewBufferString("Body=Hello+there&From=%2B15551234567"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	p.ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx := context.Background()
+	rows, err := s.SearchSummaries(ctx, "report.csv", 10)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("search attachment: %v %+v", err, rows)
+	}
+	wh, err := s.GetWebhook(ctx, rows[0].ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if len(wh.Form) != 1 || wh.Form[0].Value != "Quarterly report" || len(wh.Attachments) != 1 || wh.Attachments[0].Size != 8 {
+		t.Fatalf("form=%+v attachments=%+v", wh.Form, wh.Attachments)
+	}
+
+	// Decoded values are searchable ("Hello there", not "Hello+there").
+	rows, err = s.SearchSummaries(ctx, "Hello there", 10)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("search form value: %v %+v", err, rows)
+	}
+}
+
+func TestServeHTTP_DecodesCompressedBodies(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	var gotBody []byte
+	var gotEncoding string
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotEncoding = r.Header.Get("Content-Encoding")
+	}))
+	defer app.Close()
+	target, _ := url.Parse(app.URL)
+	p := NewRecorderProxy(target, s)
+
+	plain := []byte(`{"type":"invoice.paid","data":{"object":{"description":"flaky test"}}}`)
+	wire, err := contentenc.Encode([]string{"gzip"}, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/stripe", bytes.NewReader(wire))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Stripe-Signature", "t=1,v1=abc")
+	p.ServeHTTP(httptest.NewRecorder(), req)
+
+	// The target gets the bytes as sent.
+	if !bytes.Equal(gotBody, wire) || gotEncoding != "gzip" {
+		t.Fatalf("target got %d bytes, Content-Encoding %q", len(gotBody), gotEncoding)
+	}
+
+	ctx := context.Background()
+	rows, err := s.SearchSummaries(ctx, "flaky", 10)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("search decoded body: %v %+v", err, rows)
+	}
+	wh, err := s.GetWebhook(ctx, rows[0].ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if !bytes.Equal(wh.Body, wire) || !bytes.Equal(wh.DecodedBody, plain) {
+		t.Fatalf("Body=%d bytes DecodedBody=%q", len(wh.Body), wh.DecodedBody)
+	}
+	// The event type comes from the decoded JSON.
+	if wh.Provider != "stripe" || wh.EventType != "invoice.paid" {
+		t.Fatalf("provider=%q event=%q", wh.Provider, wh.EventType)
+	}
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Route sends matching webhooks to one or more forward targets. Empty match
+// fields match anything.
+type Route struct {
+	PathPrefix string
+	Provider   string
+	// EventType is a glob, as in path.Match.
+	EventType string
+
+	Targets []*url.URL
+	// Respond is the index into Targets whose response goes back to the caller.
+	Respond int
+}
+
+func (rt Route) matches(urlPath, prov, eventType string) bool {
+	if rt.PathPrefix != "" && !strings.HasPrefix(urlPath, rt.PathPrefix) {
+		return false
+	}
+	if rt.Provider != "" && !strings.EqualFold(rt.Provider, prov) {
+		return false
+	}
+	if rt.EventType != "" {
+		if ok, _ := path.Match(rt.EventType, eventType); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveTargets returns the targets for a request and the index of the one
+// that answers the caller: the first matching route, else the default target.
+// No targets means record-only.
+func (p *RecorderProxy) resolveTargets(urlPath, prov, eventType string) ([]*url.URL, int) {
+	for _, rt := range p.Routes {
+		if len(rt.Targets) > 0 && rt.matches(urlPath, prov, eventType) {
+			return rt.Targets, rt.Respond
+		}
+	}
+	if p.target != nil {
+		return []*url.URL{p.target}, 0
+	}
+	return nil, 0
+}
+
+// discardWriter receives the responses of non-primary targets: they are
+// recorded but never reach the caller.
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header {
+	if d.header == nil {
+		d.header = http.Header{}
+	}
+	return d.header
+}
+
+func (d *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardWriter) WriteHeader(int)             {}
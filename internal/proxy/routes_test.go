@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"hooktm/internal/store"
+)
+
+func mustURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return u
+}
+
+func TestResolveTargets(t *testing.T) {
+	billing := mustURL(t, "http://billing")
+	ci := mustURL(t, "http://ci")
+	fallback := mustURL(t, "http://app")
+
+	p := NewRecorderProxy(fallback, nil)
+	p.Routes = []Route{
+		{Provider: "stripe", Targets: []*url.URL{billing, ci}, Respond: 1},
+		{PathPrefix: "/github", EventType: "workflow_*", Targets: []*url.URL{ci}},
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		prov      string
+		event     string
+		want      []*url.URL
+		wantIndex int
+	}{
+		{"provider", "/anything", "stripe", "invoice.paid", []*url.URL{billing, ci}, 1},
+		{"prefix and glob", "/github/org", "github", "workflow_run", []*url.URL{ci}, 0},
+		{"glob mismatch falls back", "/github/org", "github", "push", []*url.URL{fallback}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, idx := p.resolveTargets(tt.path, tt.prov, tt.event)
+			if len(got) != len(tt.want) || idx != tt.wantIndex {
+				t.Fatalf("got %v (respond %d), want %v (respond %d)", got, idx, tt.want, tt.wantIndex)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("target %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	p.target = nil
+	if got, _ := p.resolveTargets("/x", "", ""); got != nil {
+		t.Fatalf("expected record-only, got %v", got)
+	}
+}
+
+func TestServeHTTP_FanOut(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	billing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "billing")
+	}))
+	defer billing.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer slow.Close()
+
+	p := NewRecorderProxy(nil, s)
+	p.Routes = []Route{{
+		PathPrefix: "/stripe",
+		Targets:    []*url.URL{mustURL(t, slow.URL), mustURL(t, billing.URL), mustURL(t, "http://127.0.0.1:1")},
+		Respond:    1,
+	}}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/stripe/live", "application/json", strings.NewReader(`{"type":"invoice.paid"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(b) != "billing" {
+		t.Fatalf("caller got %d %q, want the billing response", resp.StatusCode, b)
+	}
+
+	ctx := context.Background()
+	var wh store.Webhook
+	for i := 0; i < 50; i++ {
+		rows, _ := s.ListSummaries(ctx, store.ListFilter{Limit: 1})
+		if len(rows) == 1 {
+			wh, err = s.GetWebhook(ctx, rows[0].ID)
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || len(wh.Deliveries) != 3 {
+		t.Fatalf("expected 3 deliveries, got %+v (%v)", wh.Deliveries, err)
+	}
+	slowD, billingD, downD := wh.Deliveries[0], wh.Deliveries[1], wh.Deliveries[2]
+	if slowD.StatusCode == nil || *slowD.StatusCode != http.StatusAccepted || slowD.Primary {
+		t.Fatalf("unexpected slow delivery: %+v", slowD)
+	}
+	if !billingD.Primary || billingD.TargetURL != billing.URL+"/stripe/live" {
+		t.Fatalf("unexpected billing delivery: %+v", billingD)
+	}
+	if downD.StatusCode != nil || downD.Error == "" {
+		t.Fatalf("unexpected failed delivery: %+v", downD)
+	}
+	if wh.StatusCode == nil || *wh.StatusCode != http.StatusOK || string(wh.ResponseBody) != "billing" {
+		t.Fatalf("webhook should record the primary response, got %v %q", wh.StatusCode, wh.ResponseBody)
+	}
+}
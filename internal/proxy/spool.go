@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// payload is a captured request body: in memory, or spooled to disk when it
+// is larger than MaxRequestBodySize.
+type payload struct {
+	data      []byte // the whole body, or the in-memory head of a spooled one
+	spool     *spooledBody
+	streaming bool
+}
+
+// open returns a reader over the whole body and its length (-1: unknown).
+// The first open of a spool still being read streams it while it is
+// captured, with the caller's Content-Length; later opens read the file.
+func (pl *payload) open(contentLength int64) (io.Reader, int64) {
+	if pl.spool == nil {
+		return bytes.NewReader(pl.data), int64(len(pl.data))
+	}
+	if !pl.spool.done && !pl.streaming {
+		pl.streaming = true
+		return pl.spool.stream(), contentLength
+	}
+	if err := pl.spool.finish(); err != nil {
+		return errReader{err}, -1
+	}
+	return pl.spool.reader(), pl.spool.size
+}
+
+// spooledBody writes a request body to a temp file as it is read, so memory
+// use stays flat however large the body is.
+type spooledBody struct {
+	file     *os.File
+	src      io.Reader
+	size     int64
+	limit    int64
+	done     bool
+	tooLarge bool
+	// released is closed once a streaming forward request closes its body.
+	released chan struct{}
+}
+
+// newSpooledBody spools head followed by the rest of the request, failing
+// once more than limit bytes have been read.
+func newSpooledBody(dir string, head []byte, rest io.Reader, limit int64) (*spooledBody, error) {
+	f, err := os.CreateTemp(dir, "hooktm-body-*")
+	if err != nil {
+		return nil, err
+	}
+	remaining := limit - int64(len(head)) + 1
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &spooledBody{
+		file:  f,
+		src:   io.MultiReader(bytes.NewReader(head), io.LimitReader(rest, remaining)),
+		limit: limit,
+	}, nil
+}
+
+// Read reads from the request and spools what it returns.
+func (b *spooledBody) Read(p []byte) (int, error) {
+	n, err := b.src.Read(p)
+	if n > 0 {
+		if _, werr := b.file.Write(p[:n]); werr != nil {
+			return n, werr
+		}
+		b.size += int64(n)
+		if b.size > b.limit {
+			b.tooLarge = true
+			return n, errBodyTooLarge
+		}
+	}
+	if err == io.EOF {
+		b.done = true
+	}
+	return n, err
+}
+
+// stream hands the spool to one forward request as its body. The transport
+// may keep reading after the response arrives, so finish waits for Close.
+func (b *spooledBody) stream() io.ReadCloser {
+	b.released = make(chan struct{})
+	return &streamBody{spool: b}
+}
+
+type streamBody struct {
+	spool *spooledBody
+	once  sync.Once
+}
+
+func (s *streamBody) Read(p []byte) (int, error) { return s.spool.Read(p) }
+
+func (s *streamBody) Close() error {
+	s.once.Do(func() { close(s.spool.released) })
+	return nil
+}
+
+// finish spools whatever hasn't been read yet.
+func (b *spooledBody) finish() error {
+	if b.released != nil {
+		<-b.released
+	}
+	if b.done {
+		return nil
+	}
+	if b.tooLarge {
+		return errBodyTooLarge
+	}
+	if _, err := io.Copy(io.Discard, b); err != nil {
+		return err
+	}
+	b.done = true
+	return nil
+}
+
+// reader returns a fresh reader over the spooled bytes.
+func (b *spooledBody) reader() io.Reader {
+	return io.NewSectionReader(b.file, 0, b.size)
+}
+
+func (b *spooledBody) Close() error {
+	name := b.file.Name()
+	err := b.file.Close()
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// rejectSpool answers a request whose body could not be captured.
+func rejectSpool(w http.ResponseWriter, b *spooledBody, err error) {
+	if b.tooLarge {
+		log.Printf("[hooktm] request body too large: over %d bytes", b.limit)
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	log.Printf("[hooktm] failed to read request body: %v", err)
+	http.Error(w, "failed to read request body", http.StatusBadRequest)
+}
+
+// bodyLimit returns the largest body accepted on urlPath: the longest
+// matching BodyLimits prefix, else MaxBodySize.
+func (p *RecorderProxy) bodyLimit(urlPath string) int64 {
+	limit, best := p.MaxBodySize, -1
+	for prefix, n := range p.BodyLimits {
+		if strings.HasPrefix(urlPath, prefix) && len(prefix) > best {
+			limit, best = n, len(prefix)
+		}
+	}
+	if limit <= 0 {
+		return MaxRequestBodySize
+	}
+	return limit
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"hooktm/internal/store"
+)
+
+func TestServeHTTP_SpoolsLargeBodies(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	var received [sha256.Size]byte
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = sha256.Sum256(b)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer app.Close()
+	target, _ := url.Parse(app.URL)
+
+	p := NewRecorderProxy(target, s)
+	p.BodyLimits = map[string]int64{"/export": 3 * MaxRequestBodySize}
+	p.SpoolDir = t.TempDir()
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	big := bytes.Repeat([]byte("x"), MaxRequestBodySize+12345)
+	want := sha256.Sum256(big)
+
+	// Over the default limit elsewhere.
+	resp, err := http.Post(srv.URL+"/other", "application/octet-stream", bytes.NewReader(big))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status=%d, want 413", resp.StatusCode)
+	}
+
+	// Streamed to the target and captured under /export.
+	resp, err = http.Post(srv.URL+"/export/run", "application/octet-stream", bytes.NewReader(big))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || received != want {
+		t.Fatalf("status=%d, target got the body: %v", resp.StatusCode, received == want)
+	}
+
+	ctx := context.Background()
+	rows, err := s.ListSummaries(ctx, store.ListFilter{Limit: 10})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("expected one stored webhook, got %d (%v)", len(rows), err)
+	}
+	wh, err := s.GetWebhook(ctx, rows[0].ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if wh.BodySize != int64(len(big)) || wh.Body != nil {
+		t.Fatalf("BodySize=%d Body=%d bytes", wh.BodySize, len(wh.Body))
+	}
+	rc, err := s.OpenBody(ctx, wh.ID)
+	if err != nil {
+		t.Fatalf("OpenBody: %v", err)
+	}
+	stored, _ := io.ReadAll(rc)
+	if sha256.Sum256(stored) != want {
+		t.Fatalf("stored body differs (%d bytes)", len(stored))
+	}
+}
+
+func TestServeHTTP_SpoolLimitWithoutContentLength(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	p := NewRecorderProxy(nil, s)
+	p.MaxBodySize = MaxRequestBodySize + 100
+	p.SpoolDir = t.TempDir()
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	// io.MultiReader hides the length, so the request is chunked.
+	body := io.MultiReader(bytes.NewReader(bytes.Repeat([]byte("y"), MaxRequestBodySize+500)))
+	resp, err := http.Post(srv.URL+"/stream", "application/octet-stream", body)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status=%d, want 413", resp.StatusCode)
+	}
+	rows, _ := s.ListSummaries(context.Background(), store.ListFilter{Limit: 10})
+	if len(rows) != 0 {
+		t.Fatalf("oversized body was stored: %+v", rows)
+	}
+}
+
+func TestServeHTTP_SmallLimit(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+
+	p := NewRecorderProxy(nil, s)
+	p.MaxBodySize = 1024
+	p.BodyLimits = map[string]int64{"/small": 10}
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+
+	big := bytes.Repeat([]byte("z"), 100*1024)
+	for name, body := range map[string]func() io.Reader{
+		"content-length": func() io.Reader { return bytes.NewReader(big) },
+		// io.MultiReader hides the length, so the request is chunked.
+		"chunked": func() io.Reader { return io.MultiReader(bytes.NewReader(big)) },
+	} {
+		resp, err := http.Post(srv.URL+"/hooks", "application/octet-stream", body())
+		if err != nil {
+			t.Fatalf("%s: POST: %v", name, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Fatalf("%s: status=%d, want 413", name, resp.StatusCode)
+		}
+	}
+
+	// A per-path limit applies to chunked bodies too; one at the limit passes.
+	resp, err := http.Post(srv.URL+"/small", "text/plain", io.MultiReader(bytes.NewReader([]byte("01234567890"))))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status=%d, want 413", resp.StatusCode)
+	}
+	resp, err = http.Post(srv.URL+"/small", "text/plain", io.MultiReader(bytes.NewReader([]byte("0123456789"))))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status=%d, want 200", resp.StatusCode)
+	}
+
+	rows, _ := s.ListSummaries(context.Background(), store.ListFilter{Limit: 10})
+	if len(rows) != 1 {
+		t.Fatalf("expected only the body within the limit stored, got %d", len(rows))
+	}
+}
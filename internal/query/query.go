@@ -0,0 +1,251 @@
+// Package query parses the filter language used by `list --where` and the
+// TUI search box:
+//
+//	provider = stripe and body.data.object.amount > 5000 and status != 200
+//	event ~ "invoice.*" or (github and not signature = valid)
+//
+// A predicate is a field, an operator (= != > >= < <= ~) and a value; any
+// other word or quoted string is a full-text term. Adjacent expressions are
+// joined with AND; "and", "or" and "not" (any case) and parentheses combine
+// them. Which fields exist is up to the caller (see store.ListFilter.Where).
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operators in the order the lexer tries them (longest first).
+const (
+	OpEq   = "="
+	OpNe   = "!="
+	OpGe   = ">="
+	OpLe   = "<="
+	OpGt   = ">"
+	OpLt   = "<"
+	OpGlob = "~"
+)
+
+var operators = []string{OpNe, OpGe, OpLe, OpEq, OpGt, OpLt, OpGlob}
+
+// Expr is a parsed query: And, Or, Not, Compare or Text.
+type Expr interface{ expr() }
+
+type And struct{ Left, Right Expr }
+
+type Or struct{ Left, Right Expr }
+
+type Not struct{ X Expr }
+
+// Compare is a field predicate. Quoted is set when the value was a quoted
+// string, which callers treat as text even if it looks like a number.
+type Compare struct {
+	Field  string
+	Op     string
+	Value  string
+	Quoted bool
+}
+
+// Text is a full-text search term.
+type Text struct{ Term string }
+
+func (And) expr()     {}
+func (Or) expr()      {}
+func (Not) expr()     {}
+func (Compare) expr() {}
+func (Text) expr()    {}
+
+// Parse parses a query. An empty query returns nil.
+func Parse(s string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, nil
+	}
+	p := &parser{toks: toks}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("query: unexpected %s", p.toks[p.pos])
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func (t token) String() string { return fmt.Sprintf("%q", t.text) }
+
+func (t token) keyword(k string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, k)
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "("})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")"})
+			i++
+		case c == '"' || c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("query: unterminated string at offset %d", i)
+			}
+			toks = append(toks, token{tokString, b.String()})
+			i = j + 1
+		default:
+			if op := operatorAt(s[i:]); op != "" {
+				toks = append(toks, token{tokOp, op})
+				i += len(op)
+				continue
+			}
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()\"'", rune(s[j])) && operatorAt(s[j:]) == "" {
+				j++
+			}
+			toks = append(toks, token{tokWord, s[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+func operatorAt(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || !t.keyword("or") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind == tokRParen || t.keyword("or") {
+			return left, nil
+		}
+		if t.keyword("and") {
+			p.pos++
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("query: unexpected end of query")
+	}
+	if t.keyword("not") {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t, _ := p.peek()
+	p.pos++
+	switch t.kind {
+	case tokLParen:
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); !ok || t.kind != tokRParen {
+			return nil, fmt.Errorf("query: missing )")
+		}
+		p.pos++
+		return e, nil
+	case tokString:
+		return Text{t.text}, nil
+	case tokWord:
+		op, ok := p.peek()
+		if !ok || op.kind != tokOp {
+			return Text{t.text}, nil
+		}
+		p.pos++
+		v, ok := p.peek()
+		if !ok || (v.kind != tokWord && v.kind != tokString) {
+			return nil, fmt.Errorf("query: missing value after %s %s", t.text, op.text)
+		}
+		p.pos++
+		return Compare{Field: t.text, Op: op.text, Value: v.text, Quoted: v.kind == tokString}, nil
+	default:
+		return nil, fmt.Errorf("query: unexpected %s", t)
+	}
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Expr
+	}{
+		{"", nil},
+		{"provider=stripe", Compare{Field: "provider", Op: OpEq, Value: "stripe"}},
+		{
+			"provider = stripe and body.data.object.amount > 5000 and status != 200",
+			And{
+				And{
+					Compare{Field: "provider", Op: OpEq, Value: "stripe"},
+					Compare{Field: "body.data.object.amount", Op: OpGt, Value: "5000"},
+				},
+				Compare{Field: "status", Op: OpNe, Value: "200"},
+			},
+		},
+		{
+			`event ~ "invoice.*" OR (refund NOT signature = valid)`,
+			Or{
+				Compare{Field: "event", Op: OpGlob, Value: "invoice.*", Quoted: true},
+				And{Text{"refund"}, Not{Compare{Field: "signature", Op: OpEq, Value: "valid"}}},
+			},
+		},
+		{`"payment failed" latency>=250`, And{Text{"payment failed"}, Compare{Field: "latency", Op: OpGe, Value: "250"}}},
+		{`body.note = 'it\'s "quoted"'`, Compare{Field: "body.note", Op: OpEq, Value: `it's "quoted"`, Quoted: true}},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.in, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("Parse(%q)\n got  %#v\n want %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, in := range []string{
+		"status =",
+		"(provider = stripe",
+		"provider = stripe)",
+		`"unterminated`,
+		"= 200",
+		"not",
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Fatalf("Parse(%q): expected error", in)
+		}
+	}
+}
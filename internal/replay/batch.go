@@ -0,0 +1,175 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Batch replays many webhooks at once with a bounded number of workers and
+// an optional rate limit, for load-testing a handler.
+type Batch struct {
+	Engine *Engine
+	Target string
+	Patch  string
+
+	// Concurrency is the number of replays in flight (default 1).
+	Concurrency int
+	// Rate caps how many replays start per second; zero is unlimited.
+	Rate float64
+	// OnResult, when set, is called after each replay, from one goroutine
+	// at a time.
+	OnResult func(StepResult)
+}
+
+// Run replays the webhooks and returns the results in completion order,
+// with Offset set to when each one started. When ctx is cancelled no new
+// replays start, in-flight ones are abandoned and left out of the results,
+// and ctx's error is returned with what had completed.
+func (b *Batch) Run(ctx context.Context, ids []string) ([]StepResult, error) {
+	workers := b.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	var interval time.Duration
+	if b.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / b.Rate)
+	}
+
+	start := time.Now()
+	jobs := make(chan string)
+	var (
+		mu  sync.Mutex
+		out []StepResult
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				offset := time.Since(start)
+				res, err := b.Engine.ReplayByID(ctx, id, b.Target, b.Patch)
+				if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+					continue
+				}
+				if err != nil {
+					res.WebhookID = id
+				}
+				r := StepResult{Result: res, Offset: offset, Err: err}
+				mu.Lock()
+				out = append(out, r)
+				if b.OnResult != nil {
+					b.OnResult(r)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Starts are spaced by interval on a fixed schedule from start.
+	next := start
+dispatch:
+	for _, id := range ids {
+		if interval > 0 {
+			if err := sleepContext(ctx, time.Until(next)); err != nil {
+				break
+			}
+			next = next.Add(interval)
+		}
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return out, ctx.Err()
+}
+
+// Summary describes the outcome of a batch.
+type Summary struct {
+	Total int `json:"total"`
+	// Succeeded got a 2xx response, Failed another status, Errors none.
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Errors    int `json:"errors"`
+	// Retries counts the attempts after the first, over all replays.
+	Retries int `json:"retries,omitempty"`
+	// StatusCodes counts the responses by status.
+	StatusCodes map[int]int `json:"status_codes,omitempty"`
+	// DurationMS is the wall-clock time of the batch; Rate the replays per
+	// second that it achieved.
+	DurationMS int64   `json:"duration_ms"`
+	Rate       float64 `json:"rate"`
+	// Latency is over the replays that got a response.
+	Latency Latency `json:"latency_ms"`
+}
+
+// Latency percentiles in milliseconds, nearest-rank.
+type Latency struct {
+	Min  int64   `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  int64   `json:"p50"`
+	P90  int64   `json:"p90"`
+	P95  int64   `json:"p95"`
+	P99  int64   `json:"p99"`
+	Max  int64   `json:"max"`
+}
+
+// Summarize counts results and computes latency percentiles. elapsed is the
+// wall-clock duration of the batch.
+func Summarize(results []StepResult, elapsed time.Duration) Summary {
+	s := Summary{Total: len(results), DurationMS: elapsed.Milliseconds()}
+	var latencies []int64
+	for _, r := range results {
+		if len(r.Attempts) > 1 {
+			s.Retries += len(r.Attempts) - 1
+		}
+		switch {
+		case r.Err != nil:
+			s.Errors++
+			continue
+		case !r.Sent:
+			continue
+		case r.Failed():
+			s.Failed++
+		default:
+			s.Succeeded++
+		}
+		if s.StatusCodes == nil {
+			s.StatusCodes = map[int]int{}
+		}
+		s.StatusCodes[r.StatusCode]++
+		latencies = append(latencies, r.DurationMS)
+	}
+	if elapsed > 0 {
+		s.Rate = float64(len(results)) / elapsed.Seconds()
+	}
+	if len(latencies) == 0 {
+		return s
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var sum int64
+	for _, l := range latencies {
+		sum += l
+	}
+	rank := func(p float64) int64 {
+		i := int(math.Ceil(p*float64(len(latencies)))) - 1
+		return latencies[max(i, 0)]
+	}
+	s.Latency = Latency{
+		Min:  latencies[0],
+		Mean: float64(sum) / float64(len(latencies)),
+		P50:  rank(0.50),
+		P90:  rank(0.90),
+		P95:  rank(0.95),
+		P99:  rank(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+	return s
+}
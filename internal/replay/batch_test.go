@@ -0,0 +1,115 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hooktm/internal/store"
+)
+
+func insertBatch(t *testing.T, s *store.Store, n int) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("b%02d", i)
+		if err := s.InsertWebhook(context.Background(), store.InsertParams{
+			ID: ids[i], CreatedAt: int64(i + 1), Method: "POST", Path: "/" + ids[i], Headers: map[string][]string{},
+		}); err != nil {
+			t.Fatalf("InsertWebhook: %v", err)
+		}
+	}
+	return ids
+}
+
+func TestBatch_BoundsConcurrency(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ids := insertBatch(t, s, 20)
+
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/b03" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var calls int
+	b := &Batch{Engine: NewEngine(s), Target: srv.URL, Concurrency: 4, OnResult: func(StepResult) { calls++ }}
+	start := time.Now()
+	res, err := b.Run(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res) != 20 || calls != 20 {
+		t.Fatalf("got %d results, %d callbacks", len(res), calls)
+	}
+	if p := peak.Load(); p > 4 || p < 2 {
+		t.Fatalf("peak concurrency %d", p)
+	}
+	sum := Summarize(res, time.Since(start))
+	if sum.Total != 20 || sum.Succeeded != 19 || sum.Failed != 1 || sum.StatusCodes[503] != 1 || sum.Latency.P50 < 20 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+}
+
+func TestBatch_RateAndCancel(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ids := insertBatch(t, s, 5)
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	b := &Batch{Engine: NewEngine(s), Target: srv.URL, Concurrency: 5, Rate: 50}
+	res, err := b.Run(context.Background(), ids)
+	if err != nil || len(res) != 5 {
+		t.Fatalf("Run: %d results, %v", len(res), err)
+	}
+	for _, r := range res {
+		if r.Offset > 0 && r.Offset < 20*time.Millisecond && r.WebhookID != "b00" {
+			t.Fatalf("%s started at %v despite the rate limit", r.WebhookID, r.Offset)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Rate = 10
+	b.OnResult = func(StepResult) { cancel() }
+	res, err = b.Run(ctx, ids)
+	if !errors.Is(err, context.Canceled) || len(res) != 1 {
+		t.Fatalf("cancelled run: %d results, %v", len(res), err)
+	}
+}
+
+func TestSummarize_Percentiles(t *testing.T) {
+	var res []StepResult
+	for i := 1; i <= 100; i++ {
+		res = append(res, StepResult{Result: Result{Sent: true, StatusCode: 200, DurationMS: int64(i)}})
+	}
+	res = append(res, StepResult{Err: errors.New("connection refused")})
+	s := Summarize(res, 2*time.Second)
+	want := Latency{Min: 1, Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}
+	if s.Latency != want || s.Errors != 1 || s.Succeeded != 100 || s.Rate != 50.5 {
+		t.Fatalf("unexpected summary: %+v", s)
+	}
+}
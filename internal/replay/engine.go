@@ -3,32 +3,83 @@ package replay
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
+	nanoid "github.com/matoous/go-nanoid/v2"
 
+	"hooktm/internal/contentenc"
+	"hooktm/internal/form"
+	"hooktm/internal/signature"
 	"hooktm/internal/store"
 	"hooktm/internal/urlutil"
 )
 
+// MaxResponseBodySize caps how much of a replay response body is recorded.
+const MaxResponseBodySize = 64 * 1024 // 64 KB
+
 type Engine struct {
 	store *store.Store
 	HTTP  *http.Client
 
 	DryRun bool
+
+	// Signer re-signs each replay for its final body and the current time
+	// when set; otherwise the original signature headers are sent as-is.
+	Signer *signature.Signer
+
+	// Decoded sends compressed webhooks decoded, without their
+	// Content-Encoding header, instead of the bytes as captured.
+	Decoded bool
+
+	// Overrides change the method, path, query and headers sent.
+	Overrides Overrides
+
+	// Retry sends a replay again after connection errors and retryable
+	// statuses. Every attempt is recorded in the webhook's replay history.
+	Retry RetryPolicy
+	// OnAttempt, when set, is called after each attempt of a replay with
+	// retries. Batch calls it from several goroutines at once.
+	OnAttempt func(webhookID string, a Attempt)
+
+	// sleep waits between attempts; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
 }
 
 type Result struct {
 	WebhookID  string `json:"webhook_id"`
+	ReplayID   string `json:"replay_id,omitempty"`
 	URL        string `json:"url"`
 	Sent       bool   `json:"sent"`
 	StatusCode int    `json:"status_code,omitempty"`
 	DurationMS int64  `json:"duration_ms,omitempty"`
+
+	// Attempts lists every attempt when retries are enabled; the fields
+	// above describe the last one.
+	Attempts []Attempt `json:"attempts,omitempty"`
+
+	// ResponseHeader and ResponseBody are what the target answered, the
+	// body capped at MaxResponseBodySize.
+	ResponseHeader http.Header `json:"-"`
+	ResponseBody   []byte      `json:"-"`
+}
+
+// Attempt is one try at sending a replay.
+type Attempt struct {
+	N          int    `json:"attempt"`
+	ReplayID   string `json:"replay_id,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	// RetryInMS is the wait before the next attempt; zero on the last.
+	RetryInMS int64 `json:"retry_in_ms,omitempty"`
 }
 
 func NewEngine(s *store.Store) *Engine {
@@ -38,7 +89,82 @@ func NewEngine(s *store.Store) *Engine {
 	}
 }
 
-func (e *Engine) ReplayByID(ctx context.Context, id string, targetBase string, mergePatch string) (Result, error) {
+// Overrides change what a replay sends. Values may use template functions
+// ({{uuid}}, {{now}}, {{randInt}}, ...), rendered once per replay.
+type Overrides struct {
+	Method string
+	Path   string
+	// Query replaces the query string when not nil; "" removes it.
+	Query *string
+	// SetHeaders replace headers of the same name; RemoveHeaders drops
+	// headers. Both are applied before re-signing.
+	SetHeaders    map[string]string
+	RemoveHeaders []string
+}
+
+// apply returns wh with the overrides applied, leaving its headers intact.
+func (o Overrides) apply(wh store.Webhook) (store.Webhook, error) {
+	var err error
+	render := func(dst *string, v string) {
+		if err == nil && v != "" {
+			*dst, err = Render(v)
+		}
+	}
+	render(&wh.Method, strings.ToUpper(strings.TrimSpace(o.Method)))
+	render(&wh.Path, strings.TrimSpace(o.Path))
+	if o.Query != nil {
+		wh.Query = ""
+		render(&wh.Query, strings.TrimPrefix(*o.Query, "?"))
+	}
+	if err != nil {
+		return wh, err
+	}
+	if wh.Path != "" && !strings.HasPrefix(wh.Path, "/") {
+		wh.Path = "/" + wh.Path
+	}
+	if len(o.SetHeaders) == 0 && len(o.RemoveHeaders) == 0 {
+		return wh, nil
+	}
+
+	h := http.Header{}
+	for k, vs := range wh.Headers {
+		h[k] = append([]string(nil), vs...)
+	}
+	for _, k := range o.RemoveHeaders {
+		deleteHeader(h, k)
+	}
+	for k, v := range o.SetHeaders {
+		deleteHeader(h, k)
+		if v, err = Render(v); err != nil {
+			return wh, fmt.Errorf("header %s: %w", k, err)
+		}
+		h[http.CanonicalHeaderKey(k)] = []string{v}
+	}
+	wh.Headers = h
+	return wh, nil
+}
+
+// deleteHeader removes k in any case, as captured headers keep theirs.
+func deleteHeader(h http.Header, k string) {
+	for hk := range h {
+		if strings.EqualFold(hk, k) {
+			delete(h, hk)
+		}
+	}
+}
+
+// WithStore returns a copy of e that replays webhooks from s, for replaying
+// fixtures kept out of the main store.
+func (e *Engine) WithStore(s *store.Store) *Engine {
+	c := *e
+	c.store = s
+	return &c
+}
+
+// ReplayByID sends a stored webhook to targetBase. patch is a JSON merge
+// patch (RFC 7396, an object) or JSON Patch (RFC 6902, an array); it may use
+// template functions, and the rendered patch is recorded with the replay.
+func (e *Engine) ReplayByID(ctx context.Context, id string, targetBase string, patch string) (Result, error) {
 	wh, err := e.store.GetWebhook(ctx, id)
 	if err != nil {
 		return Result{}, err
@@ -47,10 +173,32 @@ func (e *Engine) ReplayByID(ctx context.Context, id string, targetBase string, m
 	if err != nil {
 		return Result{}, err
 	}
+	if wh, err = e.Overrides.apply(wh); err != nil {
+		return Result{}, err
+	}
+	if patch, err = Render(patch); err != nil {
+		return Result{}, fmt.Errorf("patch: %w", err)
+	}
 
 	body := wh.Body
-	if strings.TrimSpace(mergePatch) != "" {
-		body, err = applyMergePatchIfJSON(wh.Headers, body, []byte(mergePatch))
+	// Chunked bodies are streamed from the store unless a patch or re-signing
+	// needs the whole body in memory.
+	stream := wh.BodySize > 0 && strings.TrimSpace(patch) == "" && e.Signer == nil
+	if wh.BodySize > 0 && !stream {
+		body, err = e.store.ReadBody(ctx, wh.ID)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	// Patching and re-signing work on the decoded payload of compressed
+	// webhooks; it is compressed again unless Decoded is set.
+	compressed := wh.DecodedBody != nil
+	rewrite := strings.TrimSpace(patch) != "" || e.Signer != nil
+	if compressed && (e.Decoded || rewrite) {
+		body = wh.DecodedBody
+	}
+	if strings.TrimSpace(patch) != "" {
+		body, err = ApplyPatch(wh.Headers, body, []byte(patch))
 		if err != nil {
 			return Result{}, err
 		}
@@ -64,31 +212,188 @@ func (e *Engine) ReplayByID(ctx context.Context, id string, targetBase string, m
 		return Result{WebhookID: id, URL: u.String(), Sent: false}, nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, wh.Method, u.String(), bytes.NewReader(body))
+	var attempts []Attempt
+	for n := 1; ; n++ {
+		res, err := e.send(ctx, wh, u.String(), patch, body, stream)
+		var se *sendError
+		noResponse := errors.As(err, &se)
+		if noResponse {
+			err = se.err
+		}
+		if !e.Retry.Enabled() {
+			return res, err
+		}
+
+		a := Attempt{N: n, ReplayID: res.ReplayID, StatusCode: res.StatusCode, DurationMS: res.DurationMS}
+		var retry bool
+		switch {
+		case noResponse:
+			a.Error = err.Error()
+			retry = ctx.Err() == nil
+		case err != nil:
+			// Not sent at all (a bad signature setup, a missing body, ...):
+			// another attempt would fail the same way.
+			return res, err
+		default:
+			retry = e.Retry.RetryOn.Match(res.StatusCode)
+		}
+		retry = retry && n < e.Retry.MaxAttempts
+		var wait time.Duration
+		if retry {
+			wait = e.Retry.Delay(n)
+			a.RetryInMS = wait.Milliseconds()
+		}
+		attempts = append(attempts, a)
+		if e.OnAttempt != nil {
+			e.OnAttempt(id, a)
+		}
+		if !retry {
+			res.WebhookID, res.URL, res.Attempts = id, u.String(), attempts
+			return res, err
+		}
+
+		sleep := e.sleep
+		if sleep == nil {
+			sleep = sleepContext
+		}
+		if serr := sleep(ctx, wait); serr != nil {
+			res.WebhookID, res.URL, res.Attempts = id, u.String(), attempts
+			return res, serr
+		}
+	}
+}
+
+// sendError is a replay that was sent but got no response.
+type sendError struct{ err error }
+
+func (e *sendError) Error() string { return e.err.Error() }
+func (e *sendError) Unwrap() error { return e.err }
+
+// send makes one attempt at a replay, re-signing it for the current time,
+// and records it in the webhook's history.
+func (e *Engine) send(ctx context.Context, wh store.Webhook, target, patch string, body []byte, stream bool) (Result, error) {
+	compressed := wh.DecodedBody != nil
+	rewrite := strings.TrimSpace(patch) != "" || e.Signer != nil
+
+	var resigned map[string]string
+	if e.Signer != nil {
+		signed, err := e.Signer.Sign(wh.Provider, signature.Request{
+			Method:  wh.Method,
+			Path:    wh.Path,
+			Query:   wh.Query,
+			Headers: http.Header(wh.Headers),
+			Body:    body,
+		}, target, time.Now())
+		if err != nil {
+			return Result{}, fmt.Errorf("resign: %w", err)
+		}
+		if signed.Body != nil {
+			body = signed.Body
+		}
+		resigned = signed.Headers
+	}
+	if compressed && rewrite && !e.Decoded {
+		var err error
+		body, err = contentenc.Encode(contentenc.Codings(http.Header(wh.Headers)), body)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	// The attempt timeout covers the request only; the attempt is recorded
+	// after it ran out.
+	reqCtx := ctx
+	if e.Retry.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, e.Retry.Timeout)
+		defer cancel()
+	}
+	var reqBody io.Reader = bytes.NewReader(body)
+	if stream {
+		rc, err := e.store.OpenBody(ctx, wh.ID)
+		if err != nil {
+			return Result{}, err
+		}
+		defer rc.Close()
+		reqBody = rc
+	}
+	req, err := http.NewRequestWithContext(reqCtx, wh.Method, target, reqBody)
 	if err != nil {
 		return Result{}, err
 	}
+	if stream {
+		req.ContentLength = wh.BodySize
+	}
 	for k, vs := range wh.Headers {
+		if compressed && e.Decoded && strings.EqualFold(k, "Content-Encoding") {
+			continue
+		}
 		for _, v := range vs {
 			req.Header.Add(k, v)
 		}
 	}
+	for k, v := range resigned {
+		req.Header.Set(k, v)
+	}
 
 	start := time.Now()
 	resp, err := e.HTTP.Do(req)
 	if err != nil {
-		return Result{}, err
+		id := e.record(ctx, store.InsertReplayParams{
+			WebhookID:  wh.ID,
+			CreatedAt:  start.UnixMilli(),
+			TargetURL:  target,
+			Patch:      patch,
+			Method:     req.Method,
+			Headers:    req.Header,
+			DurationMS: time.Since(start).Milliseconds(),
+			Error:      err.Error(),
+		})
+		return Result{WebhookID: wh.ID, URL: target, ReplayID: id, DurationMS: time.Since(start).Milliseconds()}, &sendError{err}
 	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBodySize))
 	_, _ = io.Copy(io.Discard, resp.Body)
 	_ = resp.Body.Close()
 
-	return Result{
-		WebhookID:  id,
-		URL:        u.String(),
-		Sent:       true,
-		StatusCode: resp.StatusCode,
-		DurationMS: time.Since(start).Milliseconds(),
-	}, nil
+	res := Result{
+		WebhookID:      wh.ID,
+		URL:            target,
+		Sent:           true,
+		StatusCode:     resp.StatusCode,
+		DurationMS:     time.Since(start).Milliseconds(),
+		ResponseHeader: resp.Header,
+		ResponseBody:   respBody,
+	}
+	res.ReplayID = e.record(ctx, store.InsertReplayParams{
+		WebhookID:    wh.ID,
+		CreatedAt:    start.UnixMilli(),
+		TargetURL:    res.URL,
+		Patch:        patch,
+		Method:       req.Method,
+		Headers:      req.Header,
+		StatusCode:   &res.StatusCode,
+		DurationMS:   res.DurationMS,
+		ResponseBody: respBody,
+	})
+	return res, nil
+}
+
+// record stores a replay attempt in the webhook's history and returns its ID.
+// Failing to record must not fail the replay itself, so errors are only logged.
+// A canceled replay (Ctrl+C) is still recorded.
+func (e *Engine) record(ctx context.Context, p store.InsertReplayParams) string {
+	ctx = context.WithoutCancel(ctx)
+	id, err := nanoid.New()
+	if err != nil {
+		log.Printf("[hooktm] failed to generate replay ID: %v", err)
+		return ""
+	}
+	p.ID = id
+	if err := e.store.InsertReplay(ctx, p); err != nil {
+		log.Printf("[hooktm] failed to store replay: %v", err)
+		return ""
+	}
+	return id
 }
 
 func parseBaseURL(s string) (*url.URL, error) {
@@ -109,8 +414,43 @@ func parseBaseURL(s string) (*url.URL, error) {
 	return u, nil
 }
 
-func applyMergePatchIfJSON(headers map[string][]string, body []byte, patch []byte) ([]byte, error) {
-	ct := strings.ToLower(firstHeader(headers, "Content-Type"))
+// ApplyPatch applies a JSON Patch (RFC 6902) when patch is an array, and a
+// merge patch (see ApplyMergePatch) otherwise. JSON Patches only apply to
+// JSON bodies.
+func ApplyPatch(headers map[string][]string, body []byte, patch []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(patch)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return ApplyMergePatch(headers, body, patch)
+	}
+	ops, err := jsonpatch.DecodePatch(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("json patch: %w", err)
+	}
+	if form.IsForm(firstHeader(headers, "Content-Type")) || !looksLikeJSON(body) {
+		return nil, fmt.Errorf("json patch: body is not JSON (use a merge patch for form fields)")
+	}
+	out, err := ops.Apply(body)
+	if err != nil {
+		return nil, fmt.Errorf("json patch: %w", err)
+	}
+	return out, nil
+}
+
+// ApplyMergePatch applies a JSON merge patch to a JSON body, or to the fields
+// of a url-encoded or multipart body. Other bodies are sent unchanged.
+func ApplyMergePatch(headers map[string][]string, body []byte, patch []byte) ([]byte, error) {
+	ct := firstHeader(headers, "Content-Type")
+	if form.IsForm(ct) {
+		f, err := form.Parse(ct, body)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.MergePatch(patch); err != nil {
+			return nil, err
+		}
+		return form.Encode(ct, f)
+	}
+	ct = strings.ToLower(ct)
 	if !strings.Contains(ct, "application/json") && !looksLikeJSON(body) {
 		return body, nil
 	}
@@ -1,7 +1,19 @@
 package replay
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"hooktm/internal/contentenc"
+	"hooktm/internal/provider"
+	"hooktm/internal/signature"
+	"hooktm/internal/store"
 )
 
 func TestLooksLikeJSON(t *testing.T) {
@@ -12,3 +24,313 @@ func TestLooksLikeJSON(t *testing.T) {
 		t.Fatalf("expected false")
 	}
 }
+
+func TestReplayByID_RecordsHistory(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.InsertWebhook(ctx, store.InsertParams{
+		ID:        "wh1",
+		CreatedAt: 1,
+		Method:    "POST",
+		Path:      "/hooks",
+		Headers:   map[string][]string{"Content-Type": {"application/json"}},
+		Body:      []byte(`{"amount":1}`),
+	}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusConflict)
+		_, _ = io.WriteString(w, "duplicate")
+	}))
+	defer srv.Close()
+
+	e := NewEngine(s)
+	res, err := e.ReplayByID(ctx, "wh1", srv.URL, `{"amount":2}`)
+	if err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if res.StatusCode != http.StatusConflict || res.ReplayID == "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if gotBody != `{"amount":2}` {
+		t.Fatalf("body=%q", gotBody)
+	}
+
+	rows, err := s.ListReplays(ctx, "wh1", 10)
+	if err != nil {
+		t.Fatalf("ListReplays: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 replay, got %d", len(rows))
+	}
+	r := rows[0]
+	if r.ID != res.ReplayID || r.Patch != `{"amount":2}` || string(r.ResponseBody) != "duplicate" || *r.StatusCode != http.StatusConflict {
+		t.Fatalf("unexpected replay: %+v", r)
+	}
+
+	// Dry runs are not recorded.
+	e.DryRun = true
+	if _, err := e.ReplayByID(ctx, "wh1", srv.URL, ""); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	rows, _ = s.ListReplays(ctx, "wh1", 10)
+	if len(rows) != 1 {
+		t.Fatalf("dry run was recorded: %+v", rows)
+	}
+}
+
+func TestReplayByID_Resign(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.InsertWebhook(ctx, store.InsertParams{
+		ID:        "wh1",
+		CreatedAt: 1,
+		Method:    "POST",
+		Path:      "/hooks",
+		Provider:  "stripe",
+		Headers: map[string][]string{
+			"Content-Type":     {"application/json"},
+			"Stripe-Signature": {"t=1,v1=stale"},
+		},
+		Body: []byte(`{"amount":1}`),
+	}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	secrets := map[string]string{"stripe": "whsec_test"}
+	verifier := &signature.Verifier{Providers: provider.Default(), Secrets: secrets}
+	var valid *bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		valid, _ = verifier.Verify("stripe", signature.Request{Headers: r.Header, Body: b, ReceivedAt: time.Now()})
+	}))
+	defer srv.Close()
+
+	e := NewEngine(s)
+	e.Signer = &signature.Signer{Providers: provider.Default(), Secrets: secrets}
+	if _, err := e.ReplayByID(ctx, "wh1", srv.URL, `{"amount":2}`); err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if valid == nil || !*valid {
+		t.Fatalf("re-signed replay did not verify: %v", valid)
+	}
+
+	e.Signer.Secrets = nil
+	if _, err := e.ReplayByID(ctx, "wh1", srv.URL, ""); !errors.Is(err, signature.ErrNoSecret) {
+		t.Fatalf("err=%v, want ErrNoSecret", err)
+	}
+}
+
+func TestReplayByID_ChunkedBody(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	big := bytes.Repeat([]byte("x"), 3<<20)
+	if err := s.InsertWebhook(ctx, store.InsertParams{
+		ID:         "big",
+		CreatedAt:  1,
+		Method:     "PUT",
+		Path:       "/upload",
+		Headers:    map[string][]string{"Content-Type": {"application/octet-stream"}},
+		BodyReader: bytes.NewReader(big),
+		BodySize:   int64(len(big)),
+	}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	var gotLen int64
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLen = r.ContentLength
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	if _, err := NewEngine(s).ReplayByID(ctx, "big", srv.URL, ""); err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if gotLen != int64(len(big)) || !bytes.Equal(gotBody, big) {
+		t.Fatalf("Content-Length=%d, got %d bytes", gotLen, len(gotBody))
+	}
+}
+
+func TestApplyMergePatch_Form(t *testing.T) {
+	h := map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}}
+	got, err := ApplyMergePatch(h, []byte("Body=Hello&From=%2B1555&To=%2B1666"), []byte(`{"Body":"Bye now","To":null}`))
+	if err != nil {
+		t.Fatalf("ApplyMergePatch: %v", err)
+	}
+	if string(got) != "Body=Bye+now&From=%2B1555" {
+		t.Fatalf("body=%s", got)
+	}
+
+	if _, err := ApplyMergePatch(h, []byte("Body=Hello"), []byte(`{"Body":{"a":1}}`)); err == nil {
+		t.Fatalf("expected error for nested value")
+	}
+}
+
+func TestApplyPatch_JSONPatch(t *testing.T) {
+	h := map[string][]string{"Content-Type": {"application/json"}}
+	body := []byte(`{"items":[{"qty":1},{"qty":2}],"coupon":null}`)
+	got, err := ApplyPatch(h, body, []byte(`[{"op":"replace","path":"/items/1/qty","value":5},{"op":"remove","path":"/coupon"}]`))
+	if err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if string(got) != `{"items":[{"qty":1},{"qty":5}]}` {
+		t.Fatalf("body=%s", got)
+	}
+
+	// Objects are still merge patches.
+	if got, err = ApplyPatch(h, body, []byte(`{"coupon":"x"}`)); err != nil || !bytes.Contains(got, []byte(`"coupon":"x"`)) {
+		t.Fatalf("merge patch: %s, %v", got, err)
+	}
+
+	if _, err := ApplyPatch(h, body, []byte(`[{"op":"test","path":"/items/0/qty","value":2}]`)); err == nil {
+		t.Fatalf("expected failed test op")
+	}
+	form := map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}}
+	if _, err := ApplyPatch(form, []byte("a=1"), []byte(`[{"op":"remove","path":"/a"}]`)); err == nil {
+		t.Fatalf("expected error for form body")
+	}
+}
+
+func TestReplayByID_Overrides(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.InsertWebhook(ctx, store.InsertParams{
+		ID:        "wh1",
+		CreatedAt: 1,
+		Method:    "POST",
+		Path:      "/hooks",
+		Query:     "a=1",
+		Headers: map[string][]string{
+			"Content-Type":    {"application/json"},
+			"x-forwarded-for": {"10.0.0.1"},
+			"Idempotency-Key": {"old"},
+		},
+		Body: []byte(`{"id":"evt_1"}`),
+	}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	var got *http.Request
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, gotBody = r, string(b)
+	}))
+	defer srv.Close()
+
+	e := NewEngine(s)
+	q := "b={{randInt 1 1}}"
+	e.Overrides = Overrides{
+		Method:        "put",
+		Path:          "v2/hooks",
+		Query:         &q,
+		SetHeaders:    map[string]string{"idempotency-key": "{{uuid}}"},
+		RemoveHeaders: []string{"X-Forwarded-For"},
+	}
+	if _, err := e.ReplayByID(ctx, "wh1", srv.URL, `{"id":"evt_{{randHex 8}}"}`); err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if got.Method != "PUT" || got.URL.Path != "/v2/hooks" || got.URL.RawQuery != "b=1" {
+		t.Fatalf("request: %s %s", got.Method, got.URL)
+	}
+	if key := got.Header.Get("Idempotency-Key"); len(key) != 36 || got.Header.Get("X-Forwarded-For") != "" {
+		t.Fatalf("headers: %v", got.Header)
+	}
+	if len(gotBody) != len(`{"id":"evt_12345678"}`) || gotBody == `{"id":"evt_1"}` {
+		t.Fatalf("body=%s", gotBody)
+	}
+
+	// The replay records the rendered patch and the request it sent.
+	rows, _ := s.ListReplays(ctx, "wh1", 1)
+	if len(rows) != 1 || rows[0].Patch != gotBody {
+		t.Fatalf("recorded patch: %+v", rows)
+	}
+	sent := http.Header(rows[0].Headers)
+	if rows[0].Method != "PUT" || sent.Get("Idempotency-Key") != got.Header.Get("Idempotency-Key") || sent.Get("X-Forwarded-For") != "" {
+		t.Fatalf("recorded request: %s %v", rows[0].Method, rows[0].Headers)
+	}
+}
+
+func TestReplayByID_CompressedBody(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	plain := []byte(`{"amount":1}`)
+	wire, _ := contentenc.Encode([]string{"gzip"}, plain)
+	if err := s.InsertWebhook(ctx, store.InsertParams{
+		ID:          "gz",
+		CreatedAt:   1,
+		Method:      "POST",
+		Path:        "/hooks",
+		Headers:     map[string][]string{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+		Body:        wire,
+		DecodedBody: plain,
+	}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	var gotBody []byte
+	var gotEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotEncoding = r.Header.Get("Content-Encoding")
+	}))
+	defer srv.Close()
+
+	// As captured.
+	e := NewEngine(s)
+	if _, err := e.ReplayByID(ctx, "gz", srv.URL, ""); err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if !bytes.Equal(gotBody, wire) || gotEncoding != "gzip" {
+		t.Fatalf("got %q (Content-Encoding %q), want wire bytes", gotBody, gotEncoding)
+	}
+
+	// Patched, then compressed again.
+	if _, err := e.ReplayByID(ctx, "gz", srv.URL, `{"amount":2}`); err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	dec, err := contentenc.Decode([]string{"gzip"}, gotBody, 1<<20)
+	if err != nil || string(dec) != `{"amount":2}` || gotEncoding != "gzip" {
+		t.Fatalf("decoded %q (%v), Content-Encoding %q", dec, err, gotEncoding)
+	}
+
+	// Decoded.
+	e.Decoded = true
+	if _, err := e.ReplayByID(ctx, "gz", srv.URL, ""); err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if string(gotBody) != string(plain) || gotEncoding != "" {
+		t.Fatalf("got %q (Content-Encoding %q), want decoded body", gotBody, gotEncoding)
+	}
+}
@@ -0,0 +1,184 @@
+package replay
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy sends a replay again after a connection error or a retryable
+// status, the way providers redeliver webhooks. The zero value makes a
+// single attempt.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt; 0 or 1 never retries.
+	MaxAttempts int
+	// Initial is the delay before the first retry. Each later delay is
+	// Multiplier times the previous one (1 keeps it constant), capped at
+	// Max when set.
+	Initial    time.Duration
+	Multiplier float64
+	Max        time.Duration
+	// Jitter spreads each delay randomly by up to this fraction either
+	// way: 0.2 waits between 80% and 120% of it.
+	Jitter float64
+	// RetryOn are the statuses worth another attempt. Connection errors
+	// and timeouts are always retried.
+	RetryOn StatusSet
+	// Timeout limits each attempt when set, on top of the HTTP client's.
+	Timeout time.Duration
+}
+
+// Enabled reports whether the policy ever retries.
+func (p RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Delay returns the wait before retry n (1 for the first retry), jitter
+// included.
+func (p RetryPolicy) Delay(n int) time.Duration {
+	m := p.Multiplier
+	if m <= 0 {
+		m = 2
+	}
+	d := float64(p.Initial) * math.Pow(m, float64(n-1))
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// Scaled returns the policy with its delays and timeout divided by speed,
+// to run a provider's schedule in minutes instead of days.
+func (p RetryPolicy) Scaled(speed float64) RetryPolicy {
+	if speed <= 0 || speed == 1 {
+		return p
+	}
+	scale := func(d time.Duration) time.Duration { return time.Duration(float64(d) / speed) }
+	p.Initial, p.Max, p.Timeout = scale(p.Initial), scale(p.Max), scale(p.Timeout)
+	return p
+}
+
+var retryPresets = map[string]RetryPolicy{
+	"none": {},
+	// default suits local testing: a few quick retries on throttling and
+	// server errors.
+	"default": {
+		MaxAttempts: 5,
+		Initial:     time.Second,
+		Multiplier:  2,
+		Max:         30 * time.Second,
+		Jitter:      0.2,
+		RetryOn:     StatusSet{{408, 408}, {429, 429}, {500, 599}},
+	},
+	// Stripe retries any non-2xx response for up to three days in live
+	// mode, with exponential backoff. It doesn't publish the exact delays;
+	// these double from a minute up to 12 hours, about 2.7 days in all.
+	"stripe": {
+		MaxAttempts: 15,
+		Initial:     time.Minute,
+		Multiplier:  2,
+		Max:         12 * time.Hour,
+		Jitter:      0.1,
+		RetryOn:     StatusSet{{100, 199}, {300, 599}},
+	},
+	// GitHub doesn't redeliver failed deliveries on its own (they are
+	// redelivered by hand or through the API), and gives up on a delivery
+	// that isn't answered within 10 seconds.
+	"github": {
+		MaxAttempts: 1,
+		Timeout:     10 * time.Second,
+	},
+}
+
+// RetryPresets returns the names of the built-in policies.
+func RetryPresets() []string {
+	names := make([]string, 0, len(retryPresets))
+	for name := range retryPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RetryPreset returns a built-in policy by name.
+func RetryPreset(name string) (RetryPolicy, error) {
+	p, ok := retryPresets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return RetryPolicy{}, fmt.Errorf("unknown retry preset %q (use %s)", name, strings.Join(RetryPresets(), ", "))
+	}
+	p.RetryOn = append(StatusSet(nil), p.RetryOn...)
+	return p, nil
+}
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min, Max int
+}
+
+// StatusSet matches HTTP status codes.
+type StatusSet []StatusRange
+
+// ParseStatusSet parses a comma-separated list of codes (429), classes
+// (5xx) and ranges (500-504).
+func ParseStatusSet(s string) (StatusSet, error) {
+	var set StatusSet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		var r StatusRange
+		var err error
+		switch lo, hi, isRange := strings.Cut(part, "-"); {
+		case len(part) == 3 && strings.HasSuffix(part, "xx"):
+			var class int
+			class, err = strconv.Atoi(part[:1])
+			r = StatusRange{class * 100, class*100 + 99}
+		case isRange:
+			r.Min, err = strconv.Atoi(lo)
+			if err == nil {
+				r.Max, err = strconv.Atoi(hi)
+			}
+		default:
+			r.Min, err = strconv.Atoi(part)
+			r.Max = r.Min
+		}
+		if err != nil || r.Min < 100 || r.Max > 599 || r.Min > r.Max {
+			return nil, fmt.Errorf("invalid status %q (use e.g. 5xx, 429 or 500-504)", part)
+		}
+		set = append(set, r)
+	}
+	return set, nil
+}
+
+// Match reports whether code is in the set.
+func (s StatusSet) Match(code int) bool {
+	for _, r := range s {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+func (s StatusSet) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		switch {
+		case r.Min == r.Max:
+			parts[i] = strconv.Itoa(r.Min)
+		case r.Min%100 == 0 && r.Max == r.Min+99:
+			parts[i] = fmt.Sprintf("%dxx", r.Min/100)
+		default:
+			parts[i] = fmt.Sprintf("%d-%d", r.Min, r.Max)
+		}
+	}
+	return strings.Join(parts, ",")
+}
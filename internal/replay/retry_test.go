@@ -0,0 +1,173 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"hooktm/internal/store"
+)
+
+func TestParseStatusSet(t *testing.T) {
+	set, err := ParseStatusSet("5xx, 429,400-404")
+	if err != nil {
+		t.Fatalf("ParseStatusSet: %v", err)
+	}
+	for code, want := range map[int]bool{500: true, 599: true, 429: true, 402: true, 404: true, 405: false, 200: false} {
+		if set.Match(code) != want {
+			t.Errorf("Match(%d) = %v", code, !want)
+		}
+	}
+	if set.String() != "5xx,429,400-404" {
+		t.Errorf("String() = %q", set.String())
+	}
+	for _, bad := range []string{"6xx", "99", "504-500", "abc"} {
+		if _, err := ParseStatusSet(bad); err == nil {
+			t.Errorf("ParseStatusSet(%q): expected error", bad)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 6, Initial: time.Second, Multiplier: 3, Max: 20 * time.Second}
+	want := []time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 20 * time.Second}
+	for i, w := range want {
+		if got := p.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(1); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", d)
+		}
+	}
+	if s := p.Scaled(1000); s.Initial != time.Millisecond || s.Max != 20*time.Millisecond {
+		t.Errorf("Scaled: %+v", s)
+	}
+}
+
+func TestRetryPreset(t *testing.T) {
+	p, err := RetryPreset("Stripe")
+	if err != nil {
+		t.Fatalf("RetryPreset: %v", err)
+	}
+	var total time.Duration
+	for n := 1; n < p.MaxAttempts; n++ {
+		total += p.Delay(n)
+	}
+	// Stripe gives up after about three days.
+	if total < 60*time.Hour || total > 80*time.Hour || !p.RetryOn.Match(302) || p.RetryOn.Match(204) {
+		t.Errorf("stripe preset: %v over %d attempts, retry on %s", total, p.MaxAttempts, p.RetryOn)
+	}
+	if p, _ := RetryPreset("github"); p.Enabled() || p.Timeout != 10*time.Second {
+		t.Errorf("github preset: %+v", p)
+	}
+	if _, err := RetryPreset("sqs"); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+}
+
+func TestReplayByID_Retries(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	if err := s.InsertWebhook(ctx, store.InsertParams{ID: "r1", CreatedAt: 1, Method: "POST", Path: "/hooks", Headers: map[string][]string{}}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// Drop the connection without a response.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+		case 3:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	var seen []Attempt
+	e := NewEngine(s)
+	e.Retry = RetryPolicy{MaxAttempts: 5, Initial: time.Second, RetryOn: StatusSet{{500, 599}}}
+	e.OnAttempt = func(id string, a Attempt) { seen = append(seen, a) }
+	e.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	// 400 isn't retryable, so the third attempt is the last.
+	res, err := e.ReplayByID(ctx, "r1", srv.URL, "")
+	if err != nil {
+		t.Fatalf("ReplayByID: %v", err)
+	}
+	if res.StatusCode != 400 || len(res.Attempts) != 3 || len(seen) != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.Attempts[0].StatusCode != 503 || res.Attempts[1].Error == "" || res.Attempts[2].RetryInMS != 0 {
+		t.Fatalf("unexpected attempts: %+v", res.Attempts)
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Fatalf("waits: %v", waits)
+	}
+	history, err := s.ListReplays(ctx, "r1", 10)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("history: %d rows, %v", len(history), err)
+	}
+
+	// A connection error on the last attempt is the replay's error.
+	srv.Close()
+	e.Retry.MaxAttempts = 2
+	res, err = e.ReplayByID(ctx, "r1", srv.URL, "")
+	if err == nil || len(res.Attempts) != 2 || res.Sent {
+		t.Fatalf("expected final connection error, got %+v, %v", res, err)
+	}
+}
+
+func TestReplayByID_TimedOutAttemptsAreRecorded(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	if err := s.InsertWebhook(ctx, store.InsertParams{ID: "slow", CreatedAt: 1, Method: "POST", Path: "/hooks", Headers: map[string][]string{}}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(300 * time.Millisecond):
+		}
+	}))
+	defer srv.Close()
+
+	e := NewEngine(s)
+	e.Retry = RetryPolicy{MaxAttempts: 2, Initial: time.Millisecond, Timeout: 50 * time.Millisecond}
+	e.sleep = func(context.Context, time.Duration) error { return nil }
+	res, err := e.ReplayByID(ctx, "slow", srv.URL, "")
+	if err == nil || len(res.Attempts) != 2 {
+		t.Fatalf("expected two timed out attempts, got %+v, %v", res, err)
+	}
+	for _, a := range res.Attempts {
+		if a.ReplayID == "" {
+			t.Fatalf("attempt not recorded: %+v", a)
+		}
+	}
+	history, err := s.ListReplays(ctx, "slow", 10)
+	if err != nil || len(history) != 2 || history[0].Error == "" {
+		t.Fatalf("history: %+v, %v", history, err)
+	}
+}
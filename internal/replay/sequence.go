@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Step is one webhook of a sequence and when it was captured.
+type Step struct {
+	WebhookID string
+	CreatedAt int64 // Unix ms
+}
+
+// StepResult is the outcome of one step of a sequence.
+type StepResult struct {
+	Result
+	// Offset is when the step was sent, relative to the first one, as
+	// scheduled from the original spacing.
+	Offset time.Duration
+	Err    error
+}
+
+// Failed reports whether the step failed to send or got a non-2xx response.
+func (r StepResult) Failed() bool {
+	return r.Err != nil || (r.Sent && (r.StatusCode < 200 || r.StatusCode >= 300))
+}
+
+// Sequence replays webhooks in the order they were captured, keeping the
+// time between them divided by Speed.
+type Sequence struct {
+	Engine *Engine
+	Target string
+	Patch  string
+
+	// Speed divides the original gaps: 10 replays ten times faster. Zero
+	// sends each webhook as soon as the previous one is answered.
+	Speed float64
+	// StopOnFailure ends the run at the first step that failed: one for
+	// which Failed returns true, StepResult.Failed when Failed is nil.
+	StopOnFailure bool
+	Failed        func(StepResult) bool
+	// OnStep, when set, is called after each step.
+	OnStep func(StepResult)
+
+	// sleep waits for d; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Run replays steps oldest first. Each step is due at its original offset
+// from the first one, scaled by Speed, counted from the start of the run: a
+// slow response eats into the next gap rather than pushing back the rest of
+// the sequence. Dry runs don't wait. When ctx is canceled Run returns the
+// steps done so far with ctx's error.
+func (s *Sequence) Run(ctx context.Context, steps []Step) ([]StepResult, error) {
+	steps = append([]Step(nil), steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].CreatedAt < steps[j].CreatedAt })
+	sleep := s.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	start := time.Now()
+	var out []StepResult
+	for _, st := range steps {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		var offset time.Duration
+		if s.Speed > 0 && len(out) > 0 {
+			gap := time.Duration(st.CreatedAt-steps[0].CreatedAt) * time.Millisecond
+			offset = time.Duration(float64(gap) / s.Speed)
+			if !s.Engine.DryRun {
+				if err := sleep(ctx, time.Until(start.Add(offset))); err != nil {
+					return out, err
+				}
+			}
+		}
+		res, err := s.Engine.ReplayByID(ctx, st.WebhookID, s.Target, s.Patch)
+		if err != nil {
+			res.WebhookID = st.WebhookID
+		}
+		r := StepResult{Result: res, Offset: offset, Err: err}
+		out = append(out, r)
+		if s.OnStep != nil {
+			s.OnStep(r)
+		}
+		failed := StepResult.Failed
+		if s.Failed != nil {
+			failed = s.Failed
+		}
+		if s.StopOnFailure && failed(r) {
+			break
+		}
+	}
+	return out, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"hooktm/internal/store"
+)
+
+func TestSequence_OriginalOrderAndSpacing(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	steps := []Step{{"c", 4000}, {"a", 1000}, {"b", 2000}}
+	for _, st := range steps {
+		if err := s.InsertWebhook(ctx, store.InsertParams{ID: st.WebhookID, CreatedAt: st.CreatedAt, Method: "POST", Path: "/" + st.WebhookID, Headers: map[string][]string{}}); err != nil {
+			t.Fatalf("InsertWebhook: %v", err)
+		}
+	}
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/b" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	var waits int
+	seq := &Sequence{
+		Engine: NewEngine(s),
+		Target: srv.URL,
+		Speed:  10,
+		sleep: func(context.Context, time.Duration) error {
+			waits++
+			return nil
+		},
+	}
+	res, err := seq.Run(ctx, steps)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(got) != 3 || got[0] != "/a" || got[1] != "/b" || got[2] != "/c" {
+		t.Fatalf("sent %v", got)
+	}
+	want := []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond}
+	for i, r := range res {
+		if r.Offset != want[i] {
+			t.Fatalf("step %d offset %v, want %v", i, r.Offset, want[i])
+		}
+	}
+	if waits != 2 || !res[1].Failed() || res[2].Failed() {
+		t.Fatalf("waits=%d results=%+v", waits, res)
+	}
+
+	// Stopping at the failing step; without delays nothing waits.
+	got, waits = nil, 0
+	seq.Speed, seq.StopOnFailure = 0, true
+	res, err = seq.Run(ctx, steps)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res) != 2 || len(got) != 2 || waits != 0 {
+		t.Fatalf("stop on failure: sent %v, results %+v, waits %d", got, res, waits)
+	}
+}
+
+func TestSequence_CanceledWithoutDelay(t *testing.T) {
+	s, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	steps := []Step{{"a", 1000}, {"b", 2000}, {"c", 3000}}
+	for _, st := range steps {
+		if err := s.InsertWebhook(ctx, store.InsertParams{ID: st.WebhookID, CreatedAt: st.CreatedAt, Method: "POST", Path: "/" + st.WebhookID, Headers: map[string][]string{}}); err != nil {
+			t.Fatalf("InsertWebhook: %v", err)
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Interrupted after the first step: the rest are neither sent nor
+	// reported, and Run says it didn't finish.
+	seq := &Sequence{Engine: NewEngine(s), Target: srv.URL, OnStep: func(StepResult) { cancel() }}
+	res, err := seq.Run(ctx, steps)
+	if !errors.Is(err, context.Canceled) || len(res) != 1 || res[0].Err != nil {
+		t.Fatalf("Run: %+v, %v", res, err)
+	}
+}
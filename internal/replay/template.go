@@ -0,0 +1,92 @@
+package replay
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"text/template"
+	"time"
+
+	nanoid "github.com/matoous/go-nanoid/v2"
+)
+
+// templateFuncs are the functions available in patches and overrides:
+//
+//	{{uuid}}              random UUID (v4)
+//	{{nanoid}}            random 21-character ID
+//	{{now}}               current time, RFC 3339; {{now "2006-01-02"}} for a Go layout
+//	{{unix}}              current Unix time in seconds; {{unixMilli}} in milliseconds
+//	{{randInt}}           random integer in [0, 2^31); {{randInt 1 100}} in [1, 100]
+//	{{randHex 16}}        16 random hex characters
+var templateFuncs = template.FuncMap{
+	"uuid":   newUUID,
+	"nanoid": func() (string, error) { return nanoid.New() },
+	"now": func(layout ...string) string {
+		if len(layout) > 0 {
+			return time.Now().UTC().Format(layout[0])
+		}
+		return time.Now().UTC().Format(time.RFC3339)
+	},
+	"unix":      func() int64 { return time.Now().Unix() },
+	"unixMilli": func() int64 { return time.Now().UnixMilli() },
+	"randInt":   randInt,
+	"randHex": func(n int) (string, error) {
+		if n < 1 || n > 1024 {
+			return "", fmt.Errorf("randHex: length %d out of range", n)
+		}
+		b := make([]byte, (n+1)/2)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b)[:n], nil
+	},
+}
+
+// Render expands template functions in s. Strings without "{{" are returned
+// unchanged, so captured values pass through as they are.
+func Render(s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t, err := template.New("").Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, nil); err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	return b.String(), nil
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+func randInt(bounds ...int) (int64, error) {
+	lo, hi := int64(0), int64(1<<31-1)
+	switch len(bounds) {
+	case 0:
+	case 2:
+		lo, hi = int64(bounds[0]), int64(bounds[1])
+	default:
+		return 0, fmt.Errorf("randInt takes no arguments or a min and max")
+	}
+	if hi < lo {
+		return 0, fmt.Errorf("randInt: max %d below min %d", hi, lo)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(hi-lo+1))
+	if err != nil {
+		return 0, err
+	}
+	return lo + n.Int64(), nil
+}
@@ -0,0 +1,47 @@
+package replay
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	if got, err := Render(`{"id":"evt_1"}`); err != nil || got != `{"id":"evt_1"}` {
+		t.Fatalf("plain string: %q, %v", got, err)
+	}
+
+	got, err := Render(`{{uuid}}`)
+	if err != nil {
+		t.Fatalf("uuid: %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(got) {
+		t.Errorf("uuid = %q", got)
+	}
+	if again, _ := Render(`{{uuid}}`); again == got {
+		t.Errorf("uuid repeated: %q", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		got, err := Render(`{{randInt 5 7}}`)
+		if err != nil {
+			t.Fatalf("randInt: %v", err)
+		}
+		if n, _ := strconv.Atoi(got); n < 5 || n > 7 {
+			t.Fatalf("randInt 5 7 = %q", got)
+		}
+	}
+	if got, _ := Render(`{{randHex 5}}`); !regexp.MustCompile(`^[0-9a-f]{5}$`).MatchString(got) {
+		t.Errorf("randHex 5 = %q", got)
+	}
+	if got, _ := Render(`{{now "2006"}}`); got != time.Now().UTC().Format("2006") {
+		t.Errorf("now = %q", got)
+	}
+
+	for _, bad := range []string{`{{uuid`, `{{nope}}`, `{{randInt 9 1}}`, `{{randInt 1}}`, `{{randHex 0}}`} {
+		if _, err := Render(bad); err == nil {
+			t.Errorf("Render(%q): expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"hooktm/internal/provider"
+)
+
+// Signer produces fresh signatures for replays, so they pass the same checks
+// the provider's original delivery did.
+type Signer struct {
+	Providers *provider.Registry
+	Secrets   map[string]string
+}
+
+// Signed holds the headers to set on the outgoing request, and the body to
+// send when the scheme signs inside the body (Mailgun).
+type Signed struct {
+	Headers map[string]string
+	Body    []byte
+}
+
+// Sign signs req.Body for providerName at time now. targetURL is the URL the
+// replay is sent to; schemes that sign the URL (Twilio) use it.
+func (s *Signer) Sign(providerName string, req Request, targetURL string, now time.Time) (Signed, error) {
+	p, ok := s.Providers.Lookup(providerName)
+	if !ok || p.Scheme() == "" {
+		return Signed{}, fmt.Errorf("%w for provider %q", ErrUnsupportedScheme, providerName)
+	}
+	secret := strings.TrimSpace(s.Secrets[p.Name()])
+	if secret == "" {
+		return Signed{}, fmt.Errorf("%w for %s", ErrNoSecret, p.Name())
+	}
+	def, ok := p.(*provider.Definition)
+	if !ok || (def.Signing.Header == "" && p.Scheme() != provider.SchemeMailgun) {
+		return Signed{}, fmt.Errorf("%w: %s has no signature header", ErrUnsupportedScheme, p.Name())
+	}
+	return sign(p.Scheme(), secret, def.Signing.Header, req, targetURL, now)
+}
+
+func sign(scheme, secret, header string, req Request, targetURL string, now time.Time) (Signed, error) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := req.Body
+	switch scheme {
+	case provider.SchemeStripe:
+		mac := sha256Sum(secret, []byte(ts+"."+string(body)))
+		return signedHeader(header, "t="+ts+",v1="+hex.EncodeToString(mac)), nil
+	case provider.SchemeGitHub:
+		return signedHeader(header, "sha256="+hex.EncodeToString(sha256Sum(secret, body))), nil
+	case provider.SchemeHMACSHA256Hex:
+		// Keep the sha256= prefix if the original value used one.
+		prefix := ""
+		if strings.HasPrefix(req.Headers.Get(header), "sha256=") {
+			prefix = "sha256="
+		}
+		return signedHeader(header, prefix+hex.EncodeToString(sha256Sum(secret, body))), nil
+	case provider.SchemeHMACSHA256Base64:
+		return signedHeader(header, base64.StdEncoding.EncodeToString(sha256Sum(secret, body))), nil
+	case provider.SchemeSlack:
+		mac := sha256Sum(secret, []byte("v0:"+ts+":"+string(body)))
+		s := signedHeader(header, "v0="+hex.EncodeToString(mac))
+		s.Headers["X-Slack-Request-Timestamp"] = ts
+		return s, nil
+	case provider.SchemePaddle:
+		mac := sha256Sum(secret, []byte(ts+":"+string(body)))
+		return signedHeader(header, "ts="+ts+";h1="+hex.EncodeToString(mac)), nil
+	case provider.SchemePagerDuty:
+		return signedHeader(header, "v1="+hex.EncodeToString(sha256Sum(secret, body))), nil
+	case provider.SchemeTwilio:
+		return signTwilio(secret, header, req, targetURL)
+	case provider.SchemeMailgun:
+		return signMailgun(secret, body, ts)
+	default:
+		// Public-key schemes (Discord, SendGrid) need the provider's private key.
+		return Signed{}, fmt.Errorf("%w: can't re-sign %q", ErrUnsupportedScheme, scheme)
+	}
+}
+
+func signedHeader(header, value string) Signed {
+	return Signed{Headers: map[string]string{header: value}}
+}
+
+func signTwilio(secret, header string, req Request, targetURL string) (Signed, error) {
+	var b strings.Builder
+	b.WriteString(targetURL)
+	if strings.Contains(strings.ToLower(req.Headers.Get("Content-Type")), "application/x-www-form-urlencoded") {
+		params, err := url.ParseQuery(string(req.Body))
+		if err != nil {
+			return Signed{}, err
+		}
+		keys := make([]string, 0, len(params))
+		for k := range params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			vs := append([]string(nil), params[k]...)
+			sort.Strings(vs)
+			for _, v := range vs {
+				b.WriteString(k)
+				b.WriteString(v)
+			}
+		}
+	}
+	m := hmac.New(sha1.New, []byte(secret))
+	m.Write([]byte(b.String()))
+	return signedHeader(header, base64.StdEncoding.EncodeToString(m.Sum(nil))), nil
+}
+
+// signMailgun refreshes the signature object inside the JSON body.
+func signMailgun(secret string, body []byte, ts string) (Signed, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return Signed{}, fmt.Errorf("mailgun body: %w", err)
+	}
+	sig, _ := doc["signature"].(map[string]any)
+	if sig == nil {
+		sig = map[string]any{}
+	}
+	token, _ := sig["token"].(string)
+	sig["timestamp"] = ts
+	sig["signature"] = hex.EncodeToString(sha256Sum(secret, []byte(ts+token)))
+	doc["signature"] = sig
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return Signed{}, err
+	}
+	return Signed{Body: out}, nil
+}
@@ -0,0 +1,90 @@
+package signature
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"hooktm/internal/provider"
+)
+
+func TestSign_RoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		prov    string
+		headers map[string]string
+		body    string
+	}{
+		{"stripe", map[string]string{"Stripe-Signature": "t=1,v1=old"}, `{"type":"invoice.paid"}`},
+		{"github", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=old"}, `{"a":1}`},
+		{"shopify", map[string]string{"X-Shopify-Hmac-SHA256": "old"}, `{"a":1}`},
+		{"slack", map[string]string{"X-Slack-Signature": "v0=old", "X-Slack-Request-Timestamp": "1"}, `{"a":1}`},
+		{"linear", map[string]string{"Linear-Signature": "old"}, `{"a":1}`},
+		{"paddle", map[string]string{"Paddle-Signature": "ts=1;h1=old"}, `{"a":1}`},
+		{"pagerduty", map[string]string{"X-PagerDuty-Signature": "v1=old"}, `{"a":1}`},
+		{"mailgun", nil, `{"signature":{"timestamp":"1","token":"tok","signature":"old"},"event-data":{"event":"delivered"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prov, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			req := Request{Headers: h, Body: []byte(tt.body)}
+			secrets := map[string]string{tt.prov: "s3cret"}
+
+			signer := &Signer{Providers: provider.Default(), Secrets: secrets}
+			signed, err := signer.Sign(tt.prov, req, "", now)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			for k, v := range signed.Headers {
+				req.Headers.Set(k, v)
+			}
+			if signed.Body != nil {
+				req.Body = signed.Body
+			}
+			req.ReceivedAt = now
+
+			valid, err := newVerifier(secrets).Verify(tt.prov, req)
+			if valid == nil || !*valid {
+				t.Fatalf("re-signed request does not verify: %v (%v)", valid, err)
+			}
+		})
+	}
+}
+
+func TestSign_Twilio(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.Set("X-Twilio-Signature", "old")
+	req := Request{Method: "POST", Path: "/twilio", Headers: h, Body: []byte("CallSid=CA123&Digits=1")}
+
+	signer := &Signer{Providers: provider.Default(), Secrets: map[string]string{"twilio": "authtoken"}}
+	signed, err := signer.Sign("twilio", req, "https://example.ngrok.app/twilio", time.Now())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	req.Headers.Set("X-Twilio-Signature", signed.Headers["X-Twilio-Signature"])
+
+	v := newVerifier(map[string]string{"twilio": "authtoken"})
+	v.PublicURL = "https://example.ngrok.app"
+	valid, err := v.Verify("twilio", req)
+	if valid == nil || !*valid {
+		t.Fatalf("expected valid, got %v (%v)", valid, err)
+	}
+}
+
+func TestSign_Errors(t *testing.T) {
+	signer := &Signer{Providers: provider.Default(), Secrets: map[string]string{"discord": "pubkey"}}
+	if _, err := signer.Sign("stripe", Request{}, "", time.Now()); !errors.Is(err, ErrNoSecret) {
+		t.Fatalf("err=%v, want ErrNoSecret", err)
+	}
+	if _, err := signer.Sign("discord", Request{}, "", time.Now()); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("err=%v, want ErrUnsupportedScheme", err)
+	}
+	if _, err := signer.Sign("unknown", Request{}, "", time.Now()); !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("err=%v, want ErrUnsupportedScheme", err)
+	}
+}
@@ -0,0 +1,386 @@
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"hooktm/internal/provider"
+)
+
+// DefaultTolerance is how far a signed timestamp may be from the time the
+// webhook was received (Stripe and Slack both use five minutes).
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrNoSecret          = errors.New("no secret configured")
+	ErrUnsupportedScheme = errors.New("signature scheme not supported")
+	ErrMissingSignature  = errors.New("signature missing")
+	ErrMismatch          = errors.New("signature mismatch")
+	ErrTimestamp         = errors.New("timestamp outside tolerance")
+	ErrUnknownURL        = errors.New("unknown public URL (set public_url)")
+)
+
+// Request is a captured webhook as seen by the verifier.
+type Request struct {
+	Method     string
+	Path       string
+	Query      string
+	Headers    http.Header
+	Body       []byte
+	ReceivedAt time.Time
+
+	// Host is the Host header of the original request (Go strips it from
+	// Headers). Only needed for Twilio, whose signature covers the full URL.
+	Host string
+}
+
+// Verifier checks webhook signatures with per-provider secrets.
+type Verifier struct {
+	Providers *provider.Registry
+	Secrets   map[string]string
+	Tolerance time.Duration
+
+	// PublicURL is the externally visible base URL the provider calls
+	// (e.g. a tunnel). Used for Twilio; falls back to the request host.
+	PublicURL string
+}
+
+// Verify checks the signature of a webhook detected as providerName.
+// It returns nil with an explanatory error when the signature can't be
+// checked (no secret, unsupported scheme, no URL for Twilio), and false with the reason when it
+// is invalid.
+func (v *Verifier) Verify(providerName string, req Request) (*bool, error) {
+	p, ok := v.Providers.Lookup(providerName)
+	if !ok || p.Scheme() == "" {
+		return nil, ErrUnsupportedScheme
+	}
+	secret := strings.TrimSpace(v.Secrets[p.Name()])
+	if secret == "" {
+		return nil, fmt.Errorf("%w for %s", ErrNoSecret, p.Name())
+	}
+	sig := strings.TrimSpace(p.Signature(req.Headers, req.Body))
+	if sig == "" {
+		return boolPtr(false), ErrMissingSignature
+	}
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	err := Check(p.Scheme(), secret, sig, req, tolerance, v.candidateURLs(req))
+	switch {
+	case err == nil:
+		return boolPtr(true), nil
+	case errors.Is(err, ErrUnsupportedScheme), errors.Is(err, ErrUnknownURL):
+		return nil, err
+	default:
+		return boolPtr(false), err
+	}
+}
+
+func (v *Verifier) candidateURLs(req Request) []string {
+	pathQuery := req.Path
+	if q := strings.TrimPrefix(req.Query, "?"); q != "" {
+		pathQuery += "?" + q
+	}
+	if base := strings.TrimRight(strings.TrimSpace(v.PublicURL), "/"); base != "" {
+		return []string{base + pathQuery}
+	}
+	host := firstNonEmpty(req.Headers.Get("X-Forwarded-Host"), req.Host)
+	if host == "" {
+		return nil
+	}
+	if proto := req.Headers.Get("X-Forwarded-Proto"); proto != "" {
+		return []string{proto + "://" + host + pathQuery}
+	}
+	return []string{"https://" + host + pathQuery, "http://" + host + pathQuery}
+}
+
+// Check verifies sig against req using the given scheme. urls are candidate
+// public URLs, only used by schemes that sign the URL.
+func Check(scheme, secret, sig string, req Request, tolerance time.Duration, urls []string) error {
+	switch scheme {
+	case provider.SchemeStripe:
+		return checkStripe(secret, sig, req, tolerance)
+	case provider.SchemeGitHub, provider.SchemeHMACSHA256Hex:
+		return checkHexHMAC(sha256Sum(secret, req.Body), strings.TrimPrefix(sig, "sha256="))
+	case provider.SchemeHMACSHA256Base64:
+		return checkBase64HMAC(sha256Sum(secret, req.Body), sig)
+	case provider.SchemeSlack:
+		return checkSlack(secret, sig, req, tolerance)
+	case provider.SchemeTwilio:
+		return checkTwilio(secret, sig, req, urls)
+	case provider.SchemePaddle:
+		return checkPaddle(secret, sig, req, tolerance)
+	case provider.SchemePagerDuty:
+		return checkPagerDuty(secret, sig, req)
+	case provider.SchemeMailgun:
+		return checkMailgun(secret, sig, req)
+	case provider.SchemeEd25519:
+		return checkEd25519(secret, sig, req)
+	case provider.SchemeECDSA:
+		return checkECDSA(secret, sig, req)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedScheme, scheme)
+	}
+}
+
+// checkStripe: Stripe-Signature: t=<unix>,v1=<hex>[,v1=<hex>...]
+func checkStripe(secret, sig string, req Request, tolerance time.Duration) error {
+	var (
+		ts  string
+		v1s []string
+	)
+	for _, part := range strings.Split(sig, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			v1s = append(v1s, v)
+		}
+	}
+	if ts == "" || len(v1s) == 0 {
+		return fmt.Errorf("%w: malformed Stripe-Signature", ErrMismatch)
+	}
+	if err := checkTimestamp(ts, req.ReceivedAt, tolerance); err != nil {
+		return err
+	}
+	mac := sha256Sum(secret, []byte(ts+"."+string(req.Body)))
+	for _, v1 := range v1s {
+		if checkHexHMAC(mac, v1) == nil {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+// checkSlack: X-Slack-Signature: v0=<hex hmac of "v0:<ts>:<body>">
+func checkSlack(secret, sig string, req Request, tolerance time.Duration) error {
+	ts := req.Headers.Get("X-Slack-Request-Timestamp")
+	if ts == "" {
+		return fmt.Errorf("%w: missing X-Slack-Request-Timestamp", ErrMismatch)
+	}
+	if err := checkTimestamp(ts, req.ReceivedAt, tolerance); err != nil {
+		return err
+	}
+	mac := sha256Sum(secret, []byte("v0:"+ts+":"+string(req.Body)))
+	return checkHexHMAC(mac, strings.TrimPrefix(sig, "v0="))
+}
+
+// checkTwilio: base64 HMAC-SHA1 over the full URL followed by the sorted POST
+// parameters. JSON bodies are signed via a bodySHA256 query parameter instead.
+func checkTwilio(secret, sig string, req Request, urls []string) error {
+	if len(urls) == 0 {
+		return ErrUnknownURL
+	}
+	var params url.Values
+	if strings.Contains(strings.ToLower(req.Headers.Get("Content-Type")), "application/x-www-form-urlencoded") {
+		var err error
+		if params, err = url.ParseQuery(string(req.Body)); err != nil {
+			return fmt.Errorf("%w: %v", ErrMismatch, err)
+		}
+	}
+	for _, u := range urls {
+		payload := u
+		if params != nil {
+			keys := make([]string, 0, len(params))
+			for k := range params {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			var b strings.Builder
+			b.WriteString(u)
+			for _, k := range keys {
+				vs := append([]string(nil), params[k]...)
+				sort.Strings(vs)
+				for _, v := range vs {
+					b.WriteString(k)
+					b.WriteString(v)
+				}
+			}
+			payload = b.String()
+		} else if parsed, err := url.Parse(u); err == nil {
+			if want := parsed.Query().Get("bodySHA256"); want != "" {
+				sum := sha256.Sum256(req.Body)
+				if !strings.EqualFold(hex.EncodeToString(sum[:]), want) {
+					continue
+				}
+			}
+		}
+		m := hmac.New(sha1.New, []byte(secret))
+		m.Write([]byte(payload))
+		if checkBase64HMAC(m.Sum(nil), sig) == nil {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+// checkPaddle: Paddle-Signature: ts=<unix>;h1=<hex hmac of "<ts>:<body>">
+func checkPaddle(secret, sig string, req Request, tolerance time.Duration) error {
+	var ts string
+	var h1s []string
+	for _, part := range strings.Split(sig, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "ts":
+			ts = v
+		case "h1":
+			h1s = append(h1s, v)
+		}
+	}
+	if ts == "" || len(h1s) == 0 {
+		return fmt.Errorf("%w: malformed Paddle-Signature", ErrMismatch)
+	}
+	if err := checkTimestamp(ts, req.ReceivedAt, tolerance); err != nil {
+		return err
+	}
+	mac := sha256Sum(secret, []byte(ts+":"+string(req.Body)))
+	for _, h1 := range h1s {
+		if checkHexHMAC(mac, h1) == nil {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+// checkPagerDuty: X-PagerDuty-Signature: v1=<hex>[,v1=<hex>...]
+func checkPagerDuty(secret, sig string, req Request) error {
+	mac := sha256Sum(secret, req.Body)
+	for _, part := range strings.Split(sig, ",") {
+		if checkHexHMAC(mac, strings.TrimPrefix(strings.TrimSpace(part), "v1=")) == nil {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+// checkMailgun: hex HMAC-SHA256 of timestamp+token from the body's signature object.
+func checkMailgun(secret, sig string, req Request) error {
+	var body struct {
+		Signature struct {
+			Timestamp string `json:"timestamp"`
+			Token     string `json:"token"`
+		} `json:"signature"`
+	}
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		return fmt.Errorf("%w: %v", ErrMismatch, err)
+	}
+	mac := sha256Sum(secret, []byte(body.Signature.Timestamp+body.Signature.Token))
+	return checkHexHMAC(mac, sig)
+}
+
+// checkEd25519: Discord signs timestamp+body; secret is the hex public key.
+func checkEd25519(publicKey, sig string, req Request) error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: invalid ed25519 public key", ErrMismatch)
+	}
+	raw, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrMismatch
+	}
+	msg := append([]byte(req.Headers.Get("X-Signature-Timestamp")), req.Body...)
+	if !ed25519.Verify(ed25519.PublicKey(key), msg, raw) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// checkECDSA: SendGrid signs timestamp+body; secret is the base64 DER public key.
+func checkECDSA(publicKey, sig string, req Request) error {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return fmt.Errorf("%w: invalid ecdsa public key", ErrMismatch)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return fmt.Errorf("%w: invalid ecdsa public key", ErrMismatch)
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: not an ecdsa public key", ErrMismatch)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return ErrMismatch
+	}
+	ts := req.Headers.Get("X-Twilio-Email-Event-Webhook-Timestamp")
+	sum := sha256.Sum256(append([]byte(ts), req.Body...))
+	if !ecdsa.VerifyASN1(key, sum[:], raw) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func checkTimestamp(ts string, at time.Time, tolerance time.Duration) error {
+	sec, err := strconv.ParseInt(strings.TrimSpace(ts), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp %q", ErrTimestamp, ts)
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	d := at.Sub(time.Unix(sec, 0))
+	if d < 0 {
+		d = -d
+	}
+	if d > tolerance {
+		return fmt.Errorf("%w: signed %s from receipt", ErrTimestamp, d.Round(time.Second))
+	}
+	return nil
+}
+
+func sha256Sum(secret string, msg []byte) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(msg)
+	return m.Sum(nil)
+}
+
+func checkHexHMAC(mac []byte, sig string) error {
+	got, err := hex.DecodeString(strings.TrimSpace(sig))
+	if err != nil || !hmac.Equal(mac, got) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func checkBase64HMAC(mac []byte, sig string) error {
+	got, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil || !hmac.Equal(mac, got) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func firstNonEmpty(xs ...string) string {
+	for _, x := range xs {
+		if strings.TrimSpace(x) != "" {
+			return x
+		}
+	}
+	return ""
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,172 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"hooktm/internal/provider"
+)
+
+func hexMAC(secret, msg string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(msg))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func b64MAC(secret, msg string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(msg))
+	return base64.StdEncoding.EncodeToString(m.Sum(nil))
+}
+
+func newVerifier(secrets map[string]string) *Verifier {
+	return &Verifier{Providers: provider.Default(), Secrets: secrets}
+}
+
+func TestVerify_Stripe(t *testing.T) {
+	body := `{"type":"invoice.paid"}`
+	received := time.Unix(1700000000, 0)
+	ts := fmt.Sprint(received.Unix() - 30)
+	good := "t=" + ts + ",v1=" + hexMAC("whsec_test", ts+"."+body)
+
+	v := newVerifier(map[string]string{"stripe": "whsec_test"})
+	tests := []struct {
+		name   string
+		header string
+		at     time.Time
+		want   bool
+		err    error
+	}{
+		{"valid", good, received, true, nil},
+		{"rotated secret adds second v1", good + ",v1=deadbeef", received, true, nil},
+		{"wrong signature", "t=" + ts + ",v1=deadbeef", received, false, ErrMismatch},
+		{"outside tolerance", good, received.Add(time.Hour), false, ErrTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("Stripe-Signature", tt.header)
+			valid, err := v.Verify("stripe", Request{Headers: h, Body: []byte(body), ReceivedAt: tt.at})
+			if valid == nil || *valid != tt.want {
+				t.Fatalf("valid=%v, want %v (err=%v)", valid, tt.want, err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("err=%v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerify_GitHubShopifySlack(t *testing.T) {
+	body := `{"a":1}`
+	now := time.Unix(1700000000, 0)
+	ts := fmt.Sprint(now.Unix())
+
+	tests := []struct {
+		prov    string
+		headers map[string]string
+	}{
+		{"github", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + hexMAC("s3cret", body)}},
+		{"shopify", map[string]string{"X-Shopify-Hmac-SHA256": b64MAC("s3cret", body)}},
+		{"slack", map[string]string{"X-Slack-Signature": "v0=" + hexMAC("s3cret", "v0:"+ts+":"+body), "X-Slack-Request-Timestamp": ts}},
+		{"linear", map[string]string{"Linear-Signature": hexMAC("s3cret", body)}},
+		{"pagerduty", map[string]string{"X-PagerDuty-Signature": "v1=00ff,v1=" + hexMAC("s3cret", body)}},
+		{"paddle", map[string]string{"Paddle-Signature": "ts=" + ts + ";h1=" + hexMAC("s3cret", ts+":"+body)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prov, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			req := Request{Headers: h, Body: []byte(body), ReceivedAt: now}
+
+			valid, err := newVerifier(map[string]string{tt.prov: "s3cret"}).Verify(tt.prov, req)
+			if valid == nil || !*valid {
+				t.Fatalf("expected valid, got %v (%v)", valid, err)
+			}
+			valid, _ = newVerifier(map[string]string{tt.prov: "wrong"}).Verify(tt.prov, req)
+			if valid == nil || *valid {
+				t.Fatalf("expected invalid with wrong secret, got %v", valid)
+			}
+		})
+	}
+}
+
+func TestVerify_TwilioForm(t *testing.T) {
+	body := "To=%2B18005551212&CallSid=CA123&From=%2B12349013030&Digits=1234"
+	// URL followed by params sorted by name, each as name+value.
+	signed := "https://example.ngrok.app/twilio?x=1" + "CallSidCA123" + "Digits1234" + "From+12349013030" + "To+18005551212"
+	m := hmac.New(sha1.New, []byte("authtoken"))
+	m.Write([]byte(signed))
+	sig := base64.StdEncoding.EncodeToString(m.Sum(nil))
+
+	h := http.Header{}
+	h.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.Set("X-Twilio-Signature", sig)
+	req := Request{Method: "POST", Path: "/twilio", Query: "x=1", Headers: h, Body: []byte(body), Host: "example.ngrok.app"}
+
+	// Host-derived candidates (https is tried first).
+	valid, err := newVerifier(map[string]string{"twilio": "authtoken"}).Verify("twilio", req)
+	if valid == nil || !*valid {
+		t.Fatalf("expected valid, got %v (%v)", valid, err)
+	}
+
+	// An explicit public URL wins over the host.
+	v := newVerifier(map[string]string{"twilio": "authtoken"})
+	v.PublicURL = "https://other.example.com"
+	valid, _ = v.Verify("twilio", req)
+	if valid == nil || *valid {
+		t.Fatalf("expected invalid for different public URL, got %v", valid)
+	}
+
+	// Without a host or public URL the signature can't be checked.
+	req.Host = ""
+	valid, err = newVerifier(map[string]string{"twilio": "authtoken"}).Verify("twilio", req)
+	if valid != nil || !errors.Is(err, ErrUnknownURL) {
+		t.Fatalf("expected unverified, got %v (%v)", valid, err)
+	}
+}
+
+func TestVerify_Ed25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	body := `{"type":1}`
+	h := http.Header{}
+	h.Set("X-Signature-Timestamp", "1700000000")
+	h.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(priv, []byte("1700000000"+body))))
+
+	valid, err := newVerifier(map[string]string{"discord": hex.EncodeToString(pub)}).Verify("discord", Request{Headers: h, Body: []byte(body)})
+	if valid == nil || !*valid {
+		t.Fatalf("expected valid, got %v (%v)", valid, err)
+	}
+}
+
+func TestVerify_Unverifiable(t *testing.T) {
+	h := http.Header{}
+	h.Set("Stripe-Signature", "t=1,v1=ab")
+
+	valid, err := newVerifier(nil).Verify("stripe", Request{Headers: h})
+	if valid != nil || !errors.Is(err, ErrNoSecret) {
+		t.Fatalf("got %v, %v; want nil, ErrNoSecret", valid, err)
+	}
+	valid, err = newVerifier(map[string]string{"unknown": "x"}).Verify("unknown", Request{Headers: h})
+	if valid != nil || !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("got %v, %v; want nil, ErrUnsupportedScheme", valid, err)
+	}
+	valid, err = newVerifier(map[string]string{"stripe": "x"}).Verify("stripe", Request{Headers: http.Header{}})
+	if valid == nil || *valid || !errors.Is(err, ErrMissingSignature) {
+		t.Fatalf("got %v, %v; want false, ErrMissingSignature", valid, err)
+	}
+}
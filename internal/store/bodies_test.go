@@ -0,0 +1,76 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestInsertAndOpen_ChunkedBody(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	big := bytes.Repeat([]byte("0123456789abcdef"), (2*bodyChunkSize+100)/16)
+	err = s.InsertWebhook(ctx, InsertParams{
+		ID:         "big",
+		CreatedAt:  1,
+		Method:     "POST",
+		Path:       "/export",
+		Headers:    map[string][]string{},
+		Body:       []byte("ignored"),
+		BodyReader: bytes.NewReader(big),
+		BodySize:   int64(len(big)),
+	})
+	if err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+	_ = s.InsertWebhook(ctx, InsertParams{ID: "small", CreatedAt: 2, Method: "POST", Path: "/a", Headers: map[string][]string{}, Body: []byte("hi")})
+
+	wh, err := s.GetWebhook(ctx, "big")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if wh.Body != nil || wh.BodySize != int64(len(big)) {
+		t.Fatalf("Body=%d bytes BodySize=%d", len(wh.Body), wh.BodySize)
+	}
+
+	rc, err := s.OpenBody(ctx, "big")
+	if err != nil {
+		t.Fatalf("OpenBody: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	_ = rc.Close()
+	if err != nil || !bytes.Equal(got, big) {
+		t.Fatalf("read %d bytes (err=%v), want %d", len(got), err, len(big))
+	}
+
+	// Chunked bodies hash the same as in-memory ones.
+	if id, err := s.FindByHash(ctx, ContentHash("POST", "/export", "", big)); err != nil || id != "big" {
+		t.Fatalf("FindByHash = %q, %v", id, err)
+	}
+
+	rc, err = s.OpenBody(ctx, "small")
+	if err != nil {
+		t.Fatalf("OpenBody: %v", err)
+	}
+	got, _ = io.ReadAll(rc)
+	if string(got) != "hi" {
+		t.Fatalf("small body=%q", got)
+	}
+	if _, err := s.OpenBody(ctx, "missing"); err == nil {
+		t.Fatal("expected not found")
+	}
+
+	if err := s.DeleteWebhook(ctx, "big"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM webhook_body_chunks`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("chunks not cascaded: n=%d err=%v", n, err)
+	}
+}
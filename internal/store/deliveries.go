@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Delivery is the outcome of forwarding a captured webhook to one target.
+type Delivery struct {
+	TargetURL string `json:"target_url"`
+	// Primary marks the target whose response went back to the caller.
+	Primary    bool   `json:"primary,omitempty"`
+	StatusCode *int   `json:"status_code,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+func insertDeliveries(ctx context.Context, tx *sql.Tx, webhookID string, ds []Delivery) error {
+	for i, d := range ds {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_deliveries (
+  webhook_id, position, target_url, is_primary,
+  status_code, duration_ms, error
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+`, webhookID, i, d.TargetURL, d.Primary, d.StatusCode, d.DurationMS, nullIfEmpty(d.Error)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) listDeliveries(ctx context.Context, webhookID string) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT target_url, is_primary, status_code, duration_ms, error
+FROM webhook_deliveries
+WHERE webhook_id = ?
+ORDER BY position
+`, strings.TrimSpace(webhookID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Delivery
+	for rows.Next() {
+		var (
+			d   Delivery
+			ms  sql.NullInt64
+			msg sql.NullString
+		)
+		if err := rows.Scan(&d.TargetURL, &d.Primary, &d.StatusCode, &ms, &msg); err != nil {
+			return nil, err
+		}
+		d.DurationMS = ms.Int64
+		d.Error = msg.String
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,286 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A dump is NDJSON: a DumpHeader line, then one line per webhook followed by
+// one per replay of it. Webhook lines are Webhook's JSON plus "type":
+// "webhook", with the full body even when it is stored in chunks (body_size
+// is kept, so restore chunks it again); replay lines are Replay's JSON plus
+// "type": "replay". []byte fields, the body included, are base64. Fields are
+// only ever added to a version; readers ignore the ones they don't know.
+const (
+	DumpFormat  = "hooktm-dump"
+	DumpVersion = 1
+)
+
+// DumpHeader is the first line of a dump.
+type DumpHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+type dumpWebhook struct {
+	Type string `json:"type"`
+	Webhook
+}
+
+type dumpReplay struct {
+	Type string `json:"type"`
+	Replay
+}
+
+// DumpStats counts the records in a dump.
+type DumpStats struct {
+	Webhooks int
+	Replays  int
+}
+
+// Dump writes the webhooks with the given IDs, oldest first, and their
+// replays. A nil ids dumps the whole store.
+func (s *Store) Dump(ctx context.Context, w io.Writer, ids []string) (DumpStats, error) {
+	var stats DumpStats
+	if ids == nil {
+		var err error
+		if ids, err = s.allWebhookIDs(ctx); err != nil {
+			return stats, err
+		}
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(DumpHeader{Format: DumpFormat, Version: DumpVersion}); err != nil {
+		return stats, err
+	}
+	for _, id := range ids {
+		wh, err := s.GetWebhook(ctx, id)
+		if err != nil {
+			return stats, err
+		}
+		if wh.BodySize > 0 {
+			if wh.Body, err = s.ReadBody(ctx, id); err != nil {
+				return stats, err
+			}
+		}
+		if err := enc.Encode(dumpWebhook{Type: "webhook", Webhook: wh}); err != nil {
+			return stats, err
+		}
+		stats.Webhooks++
+
+		replays, err := s.allReplays(ctx, id)
+		if err != nil {
+			return stats, err
+		}
+		for _, r := range replays {
+			if err := enc.Encode(dumpReplay{Type: "replay", Replay: r}); err != nil {
+				return stats, err
+			}
+			stats.Replays++
+		}
+	}
+	return stats, bw.Flush()
+}
+
+func (s *Store) allWebhookIDs(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// allReplays returns every replay of a webhook, oldest first.
+func (s *Store) allReplays(ctx context.Context, webhookID string) ([]Replay, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+replayColumns+`
+FROM replays
+WHERE webhook_id = ?
+ORDER BY created_at, rowid
+`, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Replay
+	for rows.Next() {
+		r, err := scanReplay(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// RestoreMode decides what happens to webhooks already in the store.
+type RestoreMode int
+
+const (
+	// RestoreMerge adds the webhooks and replays whose ID isn't taken and
+	// leaves the rest of the store alone.
+	RestoreMerge RestoreMode = iota
+	// RestoreReplace empties the store first, so it ends up holding exactly
+	// the dump.
+	RestoreReplace
+)
+
+// RestoreStats counts what a restore did.
+type RestoreStats struct {
+	Webhooks int
+	Replays  int
+	// Skipped counts records whose ID was already taken (merge only).
+	Skipped int
+}
+
+// Restore loads a dump in a single transaction: on error the store is left
+// as it was. Either mode is idempotent, since records are matched by ID.
+func (s *Store) Restore(ctx context.Context, r io.Reader, mode RestoreMode) (RestoreStats, error) {
+	var stats RestoreStats
+	dec := json.NewDecoder(r)
+	var h DumpHeader
+	if err := dec.Decode(&h); err != nil {
+		return stats, fmt.Errorf("read dump header: %w", err)
+	}
+	if h.Format != DumpFormat {
+		return stats, fmt.Errorf("not a hooktm dump")
+	}
+	if h.Version < 1 || h.Version > DumpVersion {
+		return stats, fmt.Errorf("unsupported dump version %d (this hooktm reads up to %d)", h.Version, DumpVersion)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if mode == RestoreReplace {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM webhooks`); err != nil {
+			return stats, err
+		}
+	}
+
+	for line := 2; ; line++ {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("record %d: %w", line, err)
+		}
+		var rec struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return stats, fmt.Errorf("record %d: %w", line, err)
+		}
+		var table string
+		switch rec.Type {
+		case "webhook":
+			table = "webhooks"
+		case "replay":
+			table = "replays"
+		default:
+			// Written by a newer hooktm: nothing this version can store.
+			continue
+		}
+		if mode == RestoreMerge {
+			taken, err := idExists(ctx, tx, table, rec.ID)
+			if err != nil {
+				return stats, err
+			}
+			if taken {
+				stats.Skipped++
+				continue
+			}
+		}
+
+		if rec.Type == "webhook" {
+			var wh Webhook
+			if err := json.Unmarshal(raw, &wh); err != nil {
+				return stats, fmt.Errorf("record %d: %w", line, err)
+			}
+			if err := insertWebhook(ctx, tx, webhookParams(wh)); err != nil {
+				return stats, fmt.Errorf("webhook %s: %w", wh.ID, err)
+			}
+			stats.Webhooks++
+			continue
+		}
+		var rp Replay
+		if err := json.Unmarshal(raw, &rp); err != nil {
+			return stats, fmt.Errorf("record %d: %w", line, err)
+		}
+		if ok, err := idExists(ctx, tx, "webhooks", rp.WebhookID); err != nil {
+			return stats, err
+		} else if !ok {
+			return stats, fmt.Errorf("replay %s: webhook %s is not in the dump or the store", rp.ID, rp.WebhookID)
+		}
+		if err := insertReplay(ctx, tx, InsertReplayParams(rp)); err != nil {
+			return stats, fmt.Errorf("replay %s: %w", rp.ID, err)
+		}
+		stats.Replays++
+	}
+	return stats, tx.Commit()
+}
+
+func idExists(ctx context.Context, tx *sql.Tx, table, id string) (bool, error) {
+	var n int
+	// table is one of two constants, never user input.
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table+` WHERE id = ?`, strings.TrimSpace(id)).Scan(&n)
+	return n > 0, err
+}
+
+// webhookParams turns a webhook back into the insert that stores it.
+func webhookParams(wh Webhook) InsertParams {
+	p := InsertParams{
+		ID:                wh.ID,
+		CreatedAt:         wh.CreatedAt,
+		Method:            wh.Method,
+		Path:              wh.Path,
+		Query:             wh.Query,
+		Headers:           wh.Headers,
+		Body:              wh.Body,
+		DecodedBody:       wh.DecodedBody,
+		Provider:          wh.Provider,
+		EventType:         wh.EventType,
+		Signature:         wh.Signature,
+		SignatureValid:    wh.SignatureValid,
+		MockRule:          wh.MockRule,
+		Fault:             wh.Fault,
+		StatusCode:        wh.StatusCode,
+		ResponseMS:        wh.ResponseMS,
+		BodyText:          wh.BodyText,
+		ResponseHeaders:   wh.ResponseHeaders,
+		ResponseBody:      wh.ResponseBody,
+		ResponseTruncated: wh.ResponseTruncated,
+		Deliveries:        wh.Deliveries,
+		Form:              wh.Form,
+		Attachments:       wh.Attachments,
+	}
+	if wh.BodySize > 0 {
+		p.BodyReader = bytes.NewReader(wh.Body)
+		p.BodySize = int64(len(wh.Body))
+	}
+	if p.Headers == nil {
+		p.Headers = map[string][]string{}
+	}
+	return p
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hooktm/internal/form"
+)
+
+func openTemp(t *testing.T, name string) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestDumpAndRestore_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openTemp(t, "src.db")
+	valid := true
+	big := bytes.Repeat([]byte("x"), bodyChunkSize+10)
+	inserts := []InsertParams{
+		{
+			ID: "wh1", CreatedAt: 1, Method: "POST", Path: "/form", Query: "a=1",
+			Headers:        map[string][]string{"Content-Type": {"application/x-www-form-urlencoded"}},
+			Body:           []byte("Body=hi"),
+			Provider:       "twilio",
+			SignatureValid: &valid,
+			StatusCode:     ptr(200),
+			ResponseMS:     5,
+			BodyText:       "Body=hi",
+			ResponseBody:   []byte("ok"),
+			Deliveries:     []Delivery{{TargetURL: "http://a", Primary: true, StatusCode: ptr(200)}},
+			Form:           []form.Field{{Name: "Body", Value: "hi"}},
+		},
+		{ID: "wh2", CreatedAt: 2, Method: "PUT", Path: "/big", Headers: map[string][]string{}, BodyReader: bytes.NewReader(big), BodySize: int64(len(big))},
+	}
+	for _, p := range inserts {
+		if err := src.InsertWebhook(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.InsertReplay(ctx, InsertReplayParams{ID: "r1", WebhookID: "wh1", CreatedAt: 3, TargetURL: "http://a/form", Patch: `{"Body":"x"}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	stats, err := src.Dump(ctx, &buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (DumpStats{Webhooks: 2, Replays: 1}) || !strings.HasPrefix(buf.String(), `{"format":"hooktm-dump","version":1}`+"\n") {
+		t.Fatalf("stats=%+v dump:\n%.300s", stats, buf.String())
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 4 {
+		t.Fatalf("dump has %d lines", n)
+	}
+
+	dst := openTemp(t, "dst.db")
+	res, err := dst.Restore(ctx, bytes.NewReader(buf.Bytes()), RestoreMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (RestoreStats{Webhooks: 2, Replays: 1}) {
+		t.Fatalf("restore: %+v", res)
+	}
+	for _, id := range []string{"wh1", "wh2"} {
+		want, _ := src.GetWebhook(ctx, id)
+		got, err := dst.GetWebhook(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s:\n got %+v\nwant %+v", id, got, want)
+		}
+	}
+	if body, _ := dst.ReadBody(ctx, "wh2"); !bytes.Equal(body, big) {
+		t.Fatalf("chunked body: %d bytes", len(body))
+	}
+	if r, err := dst.GetReplay(ctx, "r1"); err != nil || r.Patch != `{"Body":"x"}` {
+		t.Fatalf("replay: %+v, %v", r, err)
+	}
+
+	// Restoring again changes nothing.
+	res, err = dst.Restore(ctx, bytes.NewReader(buf.Bytes()), RestoreMerge)
+	if err != nil || res != (RestoreStats{Skipped: 3}) {
+		t.Fatalf("second merge: %+v, %v", res, err)
+	}
+}
+
+func TestRestore_Replace(t *testing.T) {
+	ctx := context.Background()
+	s := openTemp(t, "hooks.db")
+	_ = s.InsertWebhook(ctx, InsertParams{ID: "keep", CreatedAt: 1, Method: "POST", Path: "/a", Headers: map[string][]string{}})
+
+	var buf bytes.Buffer
+	if _, err := s.Dump(ctx, &buf, []string{"keep"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.InsertWebhook(ctx, InsertParams{ID: "extra", CreatedAt: 2, Method: "POST", Path: "/b", Headers: map[string][]string{}})
+
+	res, err := s.Restore(ctx, bytes.NewReader(buf.Bytes()), RestoreReplace)
+	if err != nil || res.Webhooks != 1 {
+		t.Fatalf("replace: %+v, %v", res, err)
+	}
+	rows, _ := s.ListSummaries(ctx, ListFilter{})
+	if len(rows) != 1 || rows[0].ID != "keep" {
+		t.Fatalf("rows after replace: %+v", rows)
+	}
+}
+
+func TestRestore_RejectsUnknownVersions(t *testing.T) {
+	s := openTemp(t, "hooks.db")
+	for _, in := range []string{
+		`{"format":"hooktm-dump","version":99}`,
+		`{"format":"something-else","version":1}`,
+		`not json`,
+	} {
+		if _, err := s.Restore(context.Background(), strings.NewReader(in), RestoreMerge); err == nil {
+			t.Fatalf("expected error for %s", in)
+		}
+	}
+}
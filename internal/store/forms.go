@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"hooktm/internal/form"
+)
+
+func insertForm(ctx context.Context, tx *sql.Tx, webhookID string, fields []form.Field, atts []form.Attachment) error {
+	for i, f := range fields {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_form_fields (webhook_id, position, name, value)
+VALUES (?, ?, ?, ?)
+`, webhookID, i, f.Name, f.Value); err != nil {
+			return err
+		}
+	}
+	for i, a := range atts {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_attachments (webhook_id, position, field, filename, content_type, size)
+VALUES (?, ?, ?, ?, ?, ?)
+`, webhookID, i, a.Field, a.Filename, nullIfEmpty(a.ContentType), a.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) listForm(ctx context.Context, webhookID string) ([]form.Field, []form.Attachment, error) {
+	webhookID = strings.TrimSpace(webhookID)
+	rows, err := s.db.QueryContext(ctx, `
+SELECT name, value FROM webhook_form_fields WHERE webhook_id = ? ORDER BY position
+`, webhookID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields []form.Field
+	for rows.Next() {
+		var f form.Field
+		if err := rows.Scan(&f.Name, &f.Value); err != nil {
+			_ = rows.Close()
+			return nil, nil, err
+		}
+		fields = append(fields, f)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+SELECT field, filename, content_type, size FROM webhook_attachments WHERE webhook_id = ? ORDER BY position
+`, webhookID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var atts []form.Attachment
+	for rows.Next() {
+		var (
+			a  form.Attachment
+			ct sql.NullString
+		)
+		if err := rows.Scan(&a.Field, &a.Filename, &ct, &a.Size); err != nil {
+			return nil, nil, err
+		}
+		a.ContentType = ct.String
+		atts = append(atts, a)
+	}
+	return fields, atts, rows.Err()
+}
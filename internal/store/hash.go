@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// ContentHash identifies a request by method, path, query and body as sent.
+// Headers are left out: redeliveries of one payload differ only there.
+func ContentHash(method, path, query string, body []byte) string {
+	h := newContentHash(method, path, query)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newContentHash(method, path, query string) hash.Hash {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", strings.ToUpper(method), path, strings.TrimPrefix(query, "?"))
+	return h
+}
+
+// FindByHash returns the ID of a webhook with the given content hash, or ""
+// when there is none.
+func (s *Store) FindByHash(ctx context.Context, contentHash string) (string, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM webhooks WHERE content_hash = ? LIMIT 1`, contentHash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// fillContentHashes hashes webhooks stored before content_hash existed.
+func fillContentHashes(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+SELECT id, method, path, query, body, body_size FROM webhooks WHERE content_hash IS NULL
+`)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[string]string)
+	var chunked []string
+	for rows.Next() {
+		var (
+			id, method, path string
+			query            sql.NullString
+			body             []byte
+			size             sql.NullInt64
+		)
+		if err := rows.Scan(&id, &method, &path, &query, &body, &size); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if size.Valid {
+			chunked = append(chunked, id)
+			continue
+		}
+		hashes[id] = ContentHash(method, path, query.String, body)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range chunked {
+		var method, path string
+		var query sql.NullString
+		if err := tx.QueryRowContext(ctx, `SELECT method, path, query FROM webhooks WHERE id = ?`, id).Scan(&method, &path, &query); err != nil {
+			return err
+		}
+		h := newContentHash(method, path, query.String)
+		chunks, err := tx.QueryContext(ctx, `SELECT data FROM webhook_body_chunks WHERE webhook_id = ? ORDER BY seq`, id)
+		if err != nil {
+			return err
+		}
+		for chunks.Next() {
+			var data []byte
+			if err := chunks.Scan(&data); err != nil {
+				_ = chunks.Close()
+				return err
+			}
+			h.Write(data)
+		}
+		_ = chunks.Close()
+		if err := chunks.Err(); err != nil {
+			return err
+		}
+		hashes[id] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	for id, sum := range hashes {
+		if _, err := tx.ExecContext(ctx, `UPDATE webhooks SET content_hash = ? WHERE id = ?`, sum, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HeaderMatch selects webhooks by a request header. Names are matched
+// case-insensitively; an empty Value matches any value, and a Value
+// containing * is a glob.
+type HeaderMatch struct {
+	Name  string
+	Value string
+}
+
+// ParseHeaderMatch parses "Name=Value" or a bare "Name".
+func ParseHeaderMatch(s string) (HeaderMatch, error) {
+	name, value, _ := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return HeaderMatch{}, fmt.Errorf("invalid header filter %q (use Name=Value)", s)
+	}
+	return HeaderMatch{Name: name, Value: strings.TrimSpace(value)}, nil
+}
+
+func (m HeaderMatch) String() string {
+	if m.Value == "" {
+		return m.Name
+	}
+	return m.Name + "=" + m.Value
+}
+
+// headerCondition is a condition on the webhooks table for one header match.
+func headerCondition(m HeaderMatch) (string, []any) {
+	cond := "EXISTS (SELECT 1 FROM webhook_headers h WHERE h.webhook_id = webhooks.id AND h.name = ?"
+	args := []any{strings.ToLower(m.Name)}
+	switch {
+	case m.Value == "":
+	case strings.Contains(m.Value, "*"):
+		cond += " AND h.value GLOB ?"
+		args = append(args, m.Value)
+	default:
+		cond += " AND h.value = ?"
+		args = append(args, m.Value)
+	}
+	return cond + ")", args
+}
+
+// insertHeaders indexes the request headers, one row per value, with
+// lower-case names in sorted order.
+func insertHeaders(ctx context.Context, tx *sql.Tx, webhookID string, headers map[string][]string) error {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pos := 0
+	for _, name := range names {
+		for _, v := range headers[name] {
+			if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_headers (webhook_id, position, name, value)
+VALUES (?, ?, ?, ?)
+`, webhookID, pos, strings.ToLower(name), v); err != nil {
+				return err
+			}
+			pos++
+		}
+	}
+	return nil
+}
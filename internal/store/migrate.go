@@ -6,8 +6,27 @@ import (
 	"fmt"
 )
 
-func (s *Store) migrate(ctx context.Context) error {
-	const schema = `
+// migration is a single forward-only schema step. Versions are tracked in
+// PRAGMA user_version; each step runs in its own transaction together with
+// the version bump, so a failed step leaves the database untouched.
+type migration struct {
+	version int
+	name    string
+	up      string
+	// fill, when set, runs after up in the same transaction, for data
+	// changes SQL alone can't make.
+	fill func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations must stay ordered by version. Never edit a released step; add a
+// new one instead.
+var migrations = []migration{
+	{
+		// v0.1 schema. Uses IF NOT EXISTS because databases created before
+		// versioning already contain these objects at user_version 0.
+		version: 1,
+		name:    "initial schema",
+		up: `
 CREATE TABLE IF NOT EXISTS webhooks (
     id           TEXT PRIMARY KEY,
     created_at   INTEGER NOT NULL,
@@ -49,16 +68,248 @@ CREATE TRIGGER IF NOT EXISTS webhooks_au AFTER UPDATE ON webhooks BEGIN
   INSERT INTO webhooks_fts(webhooks_fts, rowid, body_text) VALUES('delete', old.rowid, old.body_text);
   INSERT INTO webhooks_fts(rowid, body_text) VALUES (new.rowid, new.body_text);
 END;
-`
-	if _, err := s.db.ExecContext(ctx, schema); err != nil {
+`,
+	},
+	{
+		// Response returned by the forward target, captured by the proxy.
+		// IF NOT EXISTS: unversioned development builds created this table too.
+		version: 2,
+		name:    "webhook responses",
+		up: `
+CREATE TABLE IF NOT EXISTS webhook_responses (
+    webhook_id   TEXT PRIMARY KEY REFERENCES webhooks(id) ON DELETE CASCADE,
+    headers      TEXT NOT NULL,
+    body         BLOB,
+    truncated    INTEGER NOT NULL DEFAULT 0
+);
+`,
+	},
+	{
+		version: 3,
+		name:    "replay history",
+		up: `
+CREATE TABLE replays (
+    id             TEXT PRIMARY KEY,
+    webhook_id     TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    created_at     INTEGER NOT NULL,
+
+    target_url     TEXT NOT NULL,
+    patch          TEXT,
+
+    status_code    INTEGER,
+    duration_ms    INTEGER,
+    response_body  BLOB,
+    error          TEXT
+);
+
+CREATE INDEX idx_replays_webhook ON replays(webhook_id, created_at DESC);
+`,
+	},
+	{
+		// NULL: not verified (no secret or unsupported scheme); 0/1: result.
+		version: 4,
+		name:    "signature verification",
+		up: `
+ALTER TABLE webhooks ADD COLUMN signature_valid INTEGER;
+`,
+	},
+	{
+		// Name of the mock rule that answered in record-only mode.
+		version: 5,
+		name:    "mock rules",
+		up: `
+ALTER TABLE webhooks ADD COLUMN mock_rule TEXT;
+`,
+	},
+	{
+		// Faults injected while forwarding, e.g. "latency+duplicate".
+		version: 6,
+		name:    "fault injection",
+		up: `
+ALTER TABLE webhooks ADD COLUMN fault TEXT;
+`,
+	},
+	{
+		// One row per forward target a webhook was delivered to.
+		version: 7,
+		name:    "webhook deliveries",
+		up: `
+CREATE TABLE webhook_deliveries (
+    webhook_id   TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    position     INTEGER NOT NULL,
+    target_url   TEXT NOT NULL,
+    is_primary   INTEGER NOT NULL DEFAULT 0,
+    status_code  INTEGER,
+    duration_ms  INTEGER,
+    error        TEXT,
+    PRIMARY KEY (webhook_id, position)
+);
+`,
+	},
+	{
+		// Bodies too large to keep in memory are stored in chunks; body_size
+		// is set (and webhooks.body is NULL) for those.
+		version: 8,
+		name:    "chunked bodies",
+		up: `
+ALTER TABLE webhooks ADD COLUMN body_size INTEGER;
+
+CREATE TABLE webhook_body_chunks (
+    webhook_id   TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    seq          INTEGER NOT NULL,
+    data         BLOB NOT NULL,
+    PRIMARY KEY (webhook_id, seq)
+);
+`,
+	},
+	{
+		// Decoded url-encoded and multipart bodies. Attachments keep metadata
+		// only; the content stays in the raw body.
+		version: 9,
+		name:    "form fields",
+		up: `
+CREATE TABLE webhook_form_fields (
+    webhook_id   TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    position     INTEGER NOT NULL,
+    name         TEXT NOT NULL,
+    value        TEXT NOT NULL,
+    PRIMARY KEY (webhook_id, position)
+);
+
+CREATE TABLE webhook_attachments (
+    webhook_id   TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    position     INTEGER NOT NULL,
+    field        TEXT NOT NULL,
+    filename     TEXT NOT NULL,
+    content_type TEXT,
+    size         INTEGER NOT NULL,
+    PRIMARY KEY (webhook_id, position)
+);
+`,
+	},
+	{
+		// Bodies sent with a Content-Encoding keep their wire bytes in body;
+		// decoded_body holds the decompressed payload.
+		version: 10,
+		name:    "decoded bodies",
+		up: `
+ALTER TABLE webhooks ADD COLUMN decoded_body BLOB;
+`,
+	},
+	{
+		// Listings page on (created_at, id); the id breaks ties between
+		// webhooks captured in the same millisecond.
+		version: 11,
+		name:    "list cursor index",
+		up: `
+DROP INDEX IF EXISTS idx_webhooks_created;
+CREATE INDEX idx_webhooks_created_id ON webhooks(created_at DESC, id DESC);
+`,
+	},
+	{
+		// Request headers, one row per value with a lower-case name, so
+		// webhooks can be found by delivery ID, idempotency key and the like.
+		// Existing webhooks are indexed from their headers JSON.
+		version: 12,
+		name:    "header index",
+		up: `
+CREATE TABLE webhook_headers (
+    webhook_id   TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    position     INTEGER NOT NULL,
+    name         TEXT NOT NULL,
+    value        TEXT NOT NULL,
+    PRIMARY KEY (webhook_id, position)
+);
+
+CREATE INDEX idx_webhook_headers_name_value ON webhook_headers(name, value);
+
+INSERT INTO webhook_headers (webhook_id, position, name, value)
+SELECT w.id, row_number() OVER (PARTITION BY w.id ORDER BY h.key, v.key) - 1, lower(h.key), v.value
+FROM webhooks w, json_each(w.headers) h, json_each(h.value) v
+WHERE json_valid(w.headers) AND h.type = 'array';
+`,
+	},
+	{
+		// SHA-256 of method, path, query and body (see ContentHash), used to
+		// skip duplicates on import.
+		version: 13,
+		name:    "content hash",
+		up: `
+ALTER TABLE webhooks ADD COLUMN content_hash TEXT;
+CREATE INDEX idx_webhooks_content_hash ON webhooks(content_hash);
+`,
+		fill: fillContentHashes,
+	},
+	{
+		// The method and headers a replay sent, which overrides and
+		// re-signing can change. NULL for older replays, which sent the
+		// webhook's own.
+		version: 14,
+		name:    "replay request",
+		up: `
+ALTER TABLE replays ADD COLUMN method TEXT;
+ALTER TABLE replays ADD COLUMN headers TEXT;
+`,
+	},
+}
+
+// SchemaVersion is the schema version this binary migrates databases to.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func (s *Store) migrate(ctx context.Context) error {
+	current, err := userVersion(ctx, s.db)
+	if err != nil {
 		return fmt.Errorf("migrate: %w", err)
 	}
+	if latest := SchemaVersion(); current > latest {
+		return fmt.Errorf("migrate: database schema version %d is newer than this binary supports (%d); upgrade hooktm", current, latest)
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, s.db, m); err != nil {
+			return fmt.Errorf("migrate to v%d (%s): %w", m.version, m.name, err)
+		}
+	}
 	if err := ping(ctx, s.db); err != nil {
 		return err
 	}
 	return nil
 }
 
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, m.up); err != nil {
+		return err
+	}
+	if m.fill != nil {
+		if err := m.fill(ctx, tx); err != nil {
+			return err
+		}
+	}
+	// PRAGMA doesn't accept bound parameters; version is a trusted int.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func userVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var v int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
 func ping(ctx context.Context, db *sql.DB) error {
 	return db.PingContext(ctx)
 }
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openFixture creates a database file from a SQL fixture without running
+// migrations, the way an older hooktm binary would have left it on disk.
+func openFixture(t *testing.T, fixture string) string {
+	t.Helper()
+	sqlText, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "hooks.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(string(sqlText)); err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	return path
+}
+
+func TestMigrate_UpgradesV01Database(t *testing.T) {
+	path := openFixture(t, "v0.1.sql")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	ctx := context.Background()
+
+	v, err := userVersion(ctx, s.db)
+	if err != nil {
+		t.Fatalf("userVersion: %v", err)
+	}
+	if v != SchemaVersion() {
+		t.Fatalf("user_version=%d, want %d", v, SchemaVersion())
+	}
+
+	// Existing history survives the upgrade.
+	wh, err := s.GetWebhook(ctx, "legacy1")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if wh.Provider != "stripe" || wh.EventType != "invoice.paid" || wh.ResponseBody != nil {
+		t.Fatalf("unexpected webhook: %+v", wh)
+	}
+	rows, err := s.SearchSummaries(ctx, "refs", 10)
+	if err != nil {
+		t.Fatalf("SearchSummaries: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ID != "legacy2" {
+		t.Fatalf("unexpected search rows: %+v", rows)
+	}
+
+	// Headers of existing webhooks are indexed.
+	rows, err = s.ListSummaries(ctx, ListFilter{Headers: []HeaderMatch{{Name: "X-GitHub-Event", Value: "push"}}})
+	if err != nil {
+		t.Fatalf("ListSummaries by header: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ID != "legacy2" {
+		t.Fatalf("unexpected header rows: %+v", rows)
+	}
+
+	// Existing webhooks are hashed for import de-duplication.
+	if id, err := s.FindByHash(ctx, ContentHash(wh.Method, wh.Path, wh.Query, wh.Body)); err != nil || id != "legacy1" {
+		t.Fatalf("FindByHash = %q, %v", id, err)
+	}
+
+	// New tables are usable.
+	if err := s.InsertWebhook(ctx, InsertParams{
+		ID:           "new1",
+		Method:       "POST",
+		Path:         "/c",
+		Headers:      map[string][]string{},
+		ResponseBody: []byte("ok"),
+	}); err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	// Reopening an up-to-date database is a no-op.
+	s.Close()
+	s, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.GetWebhook(ctx, "new1"); err != nil {
+		t.Fatalf("GetWebhook after reopen: %v", err)
+	}
+}
+
+func TestMigrate_RefusesNewerDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := s.db.Exec(`PRAGMA user_version = 9999`); err != nil {
+		t.Fatalf("set user_version: %v", err)
+	}
+	s.Close()
+
+	_, err = Open(path)
+	if err == nil {
+		t.Fatal("expected error opening a newer database")
+	}
+	if !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMigrations_Ordered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("migration %d (%s) has version %d, want %d", i, m.name, m.version, i+1)
+		}
+	}
+}
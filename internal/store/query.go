@@ -0,0 +1,247 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"hooktm/internal/query"
+)
+
+type fieldKind int
+
+const (
+	fieldText fieldKind = iota
+	fieldNumber
+	fieldTime
+	fieldSignature
+)
+
+// queryFields maps query field names to webhooks columns.
+var queryFields = map[string]struct {
+	column string
+	kind   fieldKind
+}{
+	"id":          {"id", fieldText},
+	"method":      {"method", fieldText},
+	"path":        {"path", fieldText},
+	"query":       {"query", fieldText},
+	"provider":    {"provider", fieldText},
+	"event":       {"event_type", fieldText},
+	"event_type":  {"event_type", fieldText},
+	"fault":       {"fault", fieldText},
+	"mock":        {"mock_rule", fieldText},
+	"status":      {"status_code", fieldNumber},
+	"latency":     {"response_ms", fieldNumber},
+	"response_ms": {"response_ms", fieldNumber},
+	"created":     {"created_at", fieldTime},
+	"signature":   {"signature_valid", fieldSignature},
+}
+
+// queryBody is the request body as JSON text, or NULL when it isn't JSON, so
+// json_extract never fails on binary or form bodies.
+const queryBody = `CASE WHEN json_valid(CAST(COALESCE(decoded_body, body) AS TEXT)) THEN CAST(COALESCE(decoded_body, body) AS TEXT) END`
+
+var sqlOps = map[string]string{
+	query.OpEq:   "IS",
+	query.OpNe:   "IS NOT",
+	query.OpGt:   ">",
+	query.OpGe:   ">=",
+	query.OpLt:   "<",
+	query.OpLe:   "<=",
+	query.OpGlob: "GLOB",
+}
+
+// whereQuery compiles a parsed query into a condition on the webhooks table.
+// Relative times ("created > 7d") count back from now.
+func whereQuery(e query.Expr, now time.Time) (string, []any, error) {
+	switch e := e.(type) {
+	case query.And:
+		return joinQuery(e.Left, e.Right, "AND", now)
+	case query.Or:
+		return joinQuery(e.Left, e.Right, "OR", now)
+	case query.Not:
+		cond, args, err := whereQuery(e.X, now)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + cond, args, nil
+	case query.Text:
+		return "(rowid IN (SELECT rowid FROM webhooks_fts WHERE webhooks_fts MATCH ?))", []any{sanitizeFTSQuery(e.Term)}, nil
+	case query.Compare:
+		return compareQuery(e, now)
+	default:
+		return "", nil, fmt.Errorf("query: unsupported expression %T", e)
+	}
+}
+
+func joinQuery(l, r query.Expr, op string, now time.Time) (string, []any, error) {
+	lc, la, err := whereQuery(l, now)
+	if err != nil {
+		return "", nil, err
+	}
+	rc, ra, err := whereQuery(r, now)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + lc + " " + op + " " + rc + ")", append(la, ra...), nil
+}
+
+func compareQuery(c query.Compare, now time.Time) (string, []any, error) {
+	op := sqlOps[c.Op]
+	name := strings.ToLower(c.Field)
+	switch {
+	case strings.HasPrefix(name, "body.") || strings.HasPrefix(c.Field, "$"):
+		p := strings.TrimPrefix(strings.TrimPrefix(c.Field, "$"), ".")
+		if strings.HasPrefix(name, "body.") {
+			p = c.Field[len("body."):]
+		}
+		return predicate("json_extract("+queryBody+", ?)", op), []any{jsonPath(p), jsonValue(c)}, nil
+	case strings.HasPrefix(name, "form."):
+		cond, args := namedValueQuery("webhook_form_fields", c.Field[len("form."):], c, op)
+		return cond, args, nil
+	case strings.HasPrefix(name, "header."):
+		cond, args := namedValueQuery("webhook_headers", strings.ToLower(c.Field[len("header."):]), c, op)
+		return cond, args, nil
+	}
+
+	f, ok := queryFields[name]
+	if !ok {
+		return "", nil, fmt.Errorf("query: unknown field %q (use body.<path>, form.<name> or header.<name>)", c.Field)
+	}
+	var v any = c.Value
+	switch f.kind {
+	case fieldText:
+		if name == "method" {
+			v = strings.ToUpper(c.Value)
+		}
+	case fieldNumber:
+		if c.Op == query.OpGlob {
+			// status ~ 5*
+			return predicate("CAST("+f.column+" AS TEXT)", op), []any{c.Value}, nil
+		}
+		n, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("query: %s needs a number, got %q", c.Field, c.Value)
+		}
+		v = n
+	case fieldTime:
+		t, err := queryTime(c.Value, now)
+		if err != nil {
+			return "", nil, err
+		}
+		v = t.UnixMilli()
+	case fieldSignature:
+		if c.Op != query.OpEq && c.Op != query.OpNe {
+			return "", nil, fmt.Errorf("query: signature only supports = and !=")
+		}
+		switch strings.ToLower(c.Value) {
+		case "valid":
+			v = 1
+		case "invalid":
+			v = 0
+		case "none", "unverified":
+			v = nil
+		default:
+			return "", nil, fmt.Errorf("query: signature is valid, invalid or none, got %q", c.Value)
+		}
+	}
+	return predicate(f.column, op), []any{v}, nil
+}
+
+// namedValueQuery matches rows of a (webhook_id, name, value) table: form
+// fields and headers. != matches webhooks without such a value. Numbers
+// compare numerically, only with values that look like numbers: CAST turns
+// any other text into 0.
+func namedValueQuery(table, name string, c query.Compare, op string) (string, []any) {
+	col, v := "nv.value", any(c.Value)
+	if n, ok := number(c); ok && c.Op != query.OpGlob {
+		col, v = "nv.value GLOB '*[0-9]*' AND nv.value NOT GLOB '*[^0-9.eE+-]*' AND CAST(nv.value AS REAL)", n
+	}
+	sub := "SELECT 1 FROM " + table + " nv WHERE nv.webhook_id = webhooks.id AND nv.name = ? AND " + col
+	if c.Op == query.OpNe {
+		return "NOT EXISTS (" + sub + " IS ?)", []any{name, v}
+	}
+	return "EXISTS (" + sub + " " + op + " ?)", []any{name, v}
+}
+
+// predicate renders "expr op ?". IS and IS NOT handle NULL themselves; the
+// other operators treat NULL as false so NOT works as expected.
+func predicate(expr, op string) string {
+	if op == "IS" || op == "IS NOT" {
+		return "(" + expr + " " + op + " ?)"
+	}
+	return "COALESCE(" + expr + " " + op + " ?, 0)"
+}
+
+// jsonValue types an unquoted value the way json_extract returns it.
+func jsonValue(c query.Compare) any {
+	if !c.Quoted {
+		switch c.Value {
+		case "true":
+			return 1
+		case "false":
+			return 0
+		case "null":
+			return nil
+		}
+	}
+	if n, ok := number(c); ok && c.Op != query.OpGlob {
+		return n
+	}
+	return c.Value
+}
+
+func number(c query.Compare) (any, bool) {
+	if c.Quoted {
+		return nil, false
+	}
+	if n, err := strconv.ParseInt(c.Value, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(c.Value, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+var jsonPathSegment = regexp.MustCompile(`\[(\d+)\]|[^.\[\]]+`)
+var jsonIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPath turns "data.items[0].id" into the SQLite path "$.data.items[0].id",
+// quoting keys that aren't plain identifiers.
+func jsonPath(p string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, m := range jsonPathSegment.FindAllStringSubmatch(p, -1) {
+		switch {
+		case m[1] != "":
+			b.WriteString("[" + m[1] + "]")
+		case jsonIdent.MatchString(m[0]):
+			b.WriteString("." + m[0])
+		default:
+			b.WriteString(`."` + strings.ReplaceAll(m[0], `"`, `\"`) + `"`)
+		}
+	}
+	return b.String()
+}
+
+// queryTime parses RFC 3339 times, dates (YYYY-MM-DD, local time) and
+// relative ages such as 30m, 2h or 7d.
+func queryTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.Atoi(s[:max(len(s)-1, 0)]); err == nil && len(s) > 1 {
+		unit := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}[s[len(s)-1]]
+		if unit > 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("query: invalid time %q (use YYYY-MM-DD, RFC 3339 or an age like 7d)", s)
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"hooktm/internal/form"
+)
+
+func TestListSummaries_Where(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	valid := true
+	for _, p := range []InsertParams{
+		{ID: "big", CreatedAt: now.Add(-time.Hour).UnixMilli(), Provider: "stripe", EventType: "invoice.paid", StatusCode: ptr(500), SignatureValid: &valid,
+			Body: []byte(`{"type":"invoice.paid","data":{"object":{"amount":9000,"customer-id":"cus_1"}}}`), BodyText: "invoice paid"},
+		{ID: "small", CreatedAt: now.Add(-2 * time.Hour).UnixMilli(), Provider: "stripe", EventType: "invoice.created", StatusCode: ptr(200),
+			Body: []byte(`{"type":"invoice.created","data":{"object":{"amount":100}}}`), BodyText: "invoice created"},
+		{ID: "gh", CreatedAt: now.Add(-10 * 24 * time.Hour).UnixMilli(), Provider: "github", EventType: "push",
+			Body: []byte(`not json`), BodyText: "refs/heads/main"},
+		{ID: "sms", CreatedAt: now.UnixMilli(), Provider: "twilio", StatusCode: ptr(200), Method: "PUT",
+			Body: []byte("Body=STOP&NumMedia=2"), Form: []form.Field{{Name: "Body", Value: "STOP"}, {Name: "NumMedia", Value: "2"}}},
+	} {
+		if p.Method == "" {
+			p.Method = "POST"
+		}
+		p.Path = "/hooks/" + p.Provider
+		p.Headers = map[string][]string{}
+		if err := s.InsertWebhook(ctx, p); err != nil {
+			t.Fatalf("InsertWebhook: %v", err)
+		}
+	}
+
+	cases := []struct {
+		where string
+		want  []string
+	}{
+		{"provider = stripe and body.data.object.amount > 5000 and status != 200", []string{"big"}},
+		{"status != 200", []string{"big", "gh"}},
+		{"status ~ 2*", []string{"sms", "small"}},
+		{"event ~ invoice.*", []string{"big", "small"}},
+		{`body.data.object.customer-id = "cus_1"`, []string{"big"}},
+		{"$.data.object.amount <= 100", []string{"small"}},
+		{"not body.data.object.amount > 5000", []string{"sms", "small", "gh"}},
+		{"signature = valid or provider = github", []string{"big", "gh"}},
+		{"signature = none and provider = stripe", []string{"small"}},
+		{"created > 1d", []string{"sms", "big", "small"}},
+		{"invoice and not paid", []string{"small"}},
+		{"(provider = github or provider = twilio) method = put", []string{"sms"}},
+		{"form.Body = STOP and form.NumMedia >= 2", []string{"sms"}},
+		{"form.Body != STOP and path ~ /hooks/*", []string{"big", "small", "gh"}},
+	}
+	for _, tc := range cases {
+		rows, err := s.ListSummaries(ctx, ListFilter{Where: tc.where, Limit: 10})
+		if err != nil {
+			t.Fatalf("%q: %v", tc.where, err)
+		}
+		var got []string
+		for _, r := range rows {
+			got = append(got, r.ID)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%q: got %v, want %v", tc.where, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%q: got %v, want %v", tc.where, got, tc.want)
+			}
+		}
+	}
+
+	for _, bad := range []string{"color = red", "status > lots", "signature > valid", "created > yesterday", "status ="} {
+		if _, err := s.ListSummaries(ctx, ListFilter{Where: bad}); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"data.object.amount": "$.data.object.amount",
+		"items[0].id":        "$.items[0].id",
+		"meta.x-request-id":  `$.meta."x-request-id"`,
+	} {
+		if got := jsonPath(in); got != want {
+			t.Fatalf("jsonPath(%q)=%q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestListPage_SearchFiltersAndCursor(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	// 25 matching webhooks, several sharing a timestamp, plus noise.
+	for i := 0; i < 30; i++ {
+		p := InsertParams{
+			ID:         fmt.Sprintf("wh%02d", i),
+			CreatedAt:  int64(1000 + i/3),
+			Method:     "POST",
+			Path:       "/hooks",
+			Headers:    map[string][]string{},
+			Provider:   "stripe",
+			StatusCode: ptr(200),
+			BodyText:   "payment succeeded",
+		}
+		switch {
+		case i >= 25 && i%2 == 0:
+			p.Provider = "github"
+		case i >= 25:
+			p.BodyText = "refund"
+		}
+		if err := s.InsertWebhook(ctx, p); err != nil {
+			t.Fatalf("InsertWebhook: %v", err)
+		}
+	}
+
+	var (
+		seen  []string
+		after *Cursor
+		pages int
+	)
+	for {
+		page, err := s.ListPage(ctx, ListFilter{Search: "payment", Provider: "stripe", StatusCode: ptr(200), Limit: 10, After: after})
+		if err != nil {
+			t.Fatalf("ListPage: %v", err)
+		}
+		pages++
+		for _, r := range page.Rows {
+			seen = append(seen, r.ID)
+		}
+		if page.Next == nil {
+			break
+		}
+		c, err := ParseCursor(page.Next.String())
+		if err != nil || *c != *page.Next {
+			t.Fatalf("cursor round trip: %v %v", c, err)
+		}
+		after = c
+	}
+	if pages != 3 || len(seen) != 25 {
+		t.Fatalf("pages=%d rows=%d: %v", pages, len(seen), seen)
+	}
+	for i, id := range seen {
+		if want := fmt.Sprintf("wh%02d", 24-i); id != want {
+			t.Fatalf("row %d = %s, want %s (%v)", i, id, want, seen)
+		}
+	}
+
+	// An exact final page has no next cursor.
+	page, err := s.ListPage(ctx, ListFilter{Search: "payment", Provider: "stripe", Limit: 25})
+	if err != nil || len(page.Rows) != 25 || page.Next != nil {
+		t.Fatalf("rows=%d next=%v err=%v", len(page.Rows), page.Next, err)
+	}
+
+	for _, bad := range []string{"", "abc", "12", "x.id"} {
+		if _, err := ParseCursor(bad); err == nil {
+			t.Fatalf("ParseCursor(%q): expected error", bad)
+		}
+	}
+}
+
+func TestListAndDelete_Headers(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	for _, p := range []InsertParams{
+		{ID: "gh1", Headers: map[string][]string{"X-Github-Delivery": {"d-1"}, "User-Agent": {"GitHub-Hookshot/abc"}, "X-Retry": {"2"}}},
+		{ID: "gh2", Headers: map[string][]string{"X-Github-Delivery": {"d-2"}, "User-Agent": {"GitHub-Hookshot/abc"}, "X-Retry": {"none"}}},
+		{ID: "curl", Headers: map[string][]string{"User-Agent": {"curl/8.4.0"}, "Idempotency-Key": {"k1"}}},
+	} {
+		p.Method, p.Path = "POST", "/hooks"
+		if err := s.InsertWebhook(ctx, p); err != nil {
+			t.Fatalf("InsertWebhook(%s): %v", p.ID, err)
+		}
+	}
+
+	ids := func(f ListFilter) []string {
+		t.Helper()
+		rows, err := s.ListSummaries(ctx, f)
+		if err != nil {
+			t.Fatalf("ListSummaries(%+v): %v", f, err)
+		}
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.ID)
+		}
+		sort.Strings(out)
+		return out
+	}
+	cases := []struct {
+		f    ListFilter
+		want []string
+	}{
+		{ListFilter{Headers: []HeaderMatch{{Name: "x-github-delivery", Value: "d-2"}}}, []string{"gh2"}},
+		{ListFilter{Headers: []HeaderMatch{{Name: "User-Agent", Value: "GitHub-Hookshot/*"}}}, []string{"gh1", "gh2"}},
+		{ListFilter{Headers: []HeaderMatch{{Name: "Idempotency-Key"}}}, []string{"curl"}},
+		{ListFilter{Headers: []HeaderMatch{{Name: "user-agent", Value: "curl/*"}, {Name: "idempotency-key", Value: "k2"}}}, nil},
+		{ListFilter{Where: "header.X-GitHub-Delivery = d-1"}, []string{"gh1"}},
+		{ListFilter{Where: "header.x-github-delivery != d-1"}, []string{"curl", "gh2"}},
+		// Text that isn't a number doesn't compare as 0.
+		{ListFilter{Where: "header.x-retry = 0"}, nil},
+		{ListFilter{Where: "header.x-retry >= 2.0"}, []string{"gh1"}},
+		{ListFilter{Where: "header.x-retry != 2"}, []string{"curl", "gh2"}},
+	}
+	for _, tc := range cases {
+		if got := ids(tc.f); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%+v: got %v, want %v", tc.f, got, tc.want)
+		}
+	}
+
+	n, err := s.DeleteByFilter(ctx, DeleteFilter{Headers: []HeaderMatch{{Name: "user-agent", Value: "curl/*"}}})
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteByFilter: n=%d err=%v", n, err)
+	}
+	if got := ids(ListFilter{}); !reflect.DeepEqual(got, []string{"gh1", "gh2"}) {
+		t.Fatalf("after delete: %v", got)
+	}
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Replay is one recorded attempt to re-send a captured webhook.
+type Replay struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+	CreatedAt int64  `json:"created_at"`
+
+	TargetURL string `json:"target_url"`
+	Patch     string `json:"patch,omitempty"`
+	// Method and Headers are what the replay sent; empty for replays
+	// recorded before they were stored.
+	Method  string              `json:"method,omitempty"`
+	Headers map[string][]string `json:"headers,omitempty"`
+
+	StatusCode   *int   `json:"status_code,omitempty"`
+	DurationMS   int64  `json:"duration_ms"`
+	ResponseBody []byte `json:"response_body,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type InsertReplayParams struct {
+	ID        string
+	WebhookID string
+	CreatedAt int64
+
+	TargetURL string
+	Patch     string
+	Method    string
+	Headers   map[string][]string
+
+	StatusCode   *int
+	DurationMS   int64
+	ResponseBody []byte
+	Error        string
+}
+
+func (s *Store) InsertReplay(ctx context.Context, p InsertReplayParams) error {
+	return insertReplay(ctx, s.db, p)
+}
+
+func insertReplay(ctx context.Context, db execer, p InsertReplayParams) error {
+	if strings.TrimSpace(p.ID) == "" || strings.TrimSpace(p.WebhookID) == "" {
+		return fmt.Errorf("missing id/webhook id")
+	}
+	if strings.TrimSpace(p.TargetURL) == "" {
+		return fmt.Errorf("missing target url")
+	}
+	if p.CreatedAt == 0 {
+		p.CreatedAt = time.Now().UnixMilli()
+	}
+	var headers *string
+	if p.Headers != nil {
+		hb, err := json.Marshal(p.Headers)
+		if err != nil {
+			return err
+		}
+		h := string(hb)
+		headers = &h
+	}
+	_, err := db.ExecContext(ctx, `
+INSERT INTO replays (
+  id, webhook_id, created_at,
+  target_url, patch, method, headers,
+  status_code, duration_ms, response_body, error
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, p.ID, p.WebhookID, p.CreatedAt,
+		p.TargetURL, nullIfEmpty(p.Patch), nullIfEmpty(p.Method), headers,
+		p.StatusCode, p.DurationMS, p.ResponseBody, nullIfEmpty(p.Error),
+	)
+	return err
+}
+
+// replayColumns are the columns scanReplay reads, in order.
+const replayColumns = `id, webhook_id, created_at, target_url, patch, method, headers, status_code, duration_ms, response_body, error`
+
+// ListReplays returns the replay history of a webhook, newest first.
+func (s *Store) ListReplays(ctx context.Context, webhookID string, limit int) ([]Replay, error) {
+	webhookID = strings.TrimSpace(webhookID)
+	if webhookID == "" {
+		return nil, fmt.Errorf("empty id")
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 20
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+replayColumns+`
+FROM replays
+WHERE webhook_id = ?
+ORDER BY created_at DESC, rowid DESC
+LIMIT ?
+`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Replay
+	for rows.Next() {
+		r, err := scanReplay(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// GetReplay returns one replay by ID.
+func (s *Store) GetReplay(ctx context.Context, id string) (Replay, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return Replay{}, fmt.Errorf("empty id")
+	}
+	r, err := scanReplay(s.db.QueryRowContext(ctx, `
+SELECT `+replayColumns+`
+FROM replays
+WHERE id = ?
+`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Replay{}, fmt.Errorf("not found: %s", id)
+	}
+	return r, err
+}
+
+func scanReplay(row interface{ Scan(...any) error }) (Replay, error) {
+	var (
+		r        Replay
+		patch    sql.NullString
+		method   sql.NullString
+		headers  sql.NullString
+		errText  sql.NullString
+		duration sql.NullInt64
+	)
+	if err := row.Scan(&r.ID, &r.WebhookID, &r.CreatedAt, &r.TargetURL, &patch, &method, &headers,
+		&r.StatusCode, &duration, &r.ResponseBody, &errText); err != nil {
+		return Replay{}, err
+	}
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &r.Headers); err != nil {
+			// As with webhooks, corrupt headers shouldn't hide the replay.
+			r.Headers = map[string][]string{"_error": {err.Error()}}
+		}
+	}
+	r.Patch = patch.String
+	r.Method = method.String
+	r.Error = errText.String
+	r.DurationMS = duration.Int64
+	return r, nil
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInsertAndListReplays(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	_ = s.InsertWebhook(ctx, InsertParams{
+		ID:        "wh1",
+		CreatedAt: 1,
+		Method:    "POST",
+		Path:      "/a",
+		Headers:   map[string][]string{},
+	})
+
+	if err := s.InsertReplay(ctx, InsertReplayParams{
+		ID:           "r1",
+		WebhookID:    "wh1",
+		CreatedAt:    10,
+		TargetURL:    "http://localhost:3000/a",
+		StatusCode:   ptr(500),
+		DurationMS:   7,
+		ResponseBody: []byte("boom"),
+	}); err != nil {
+		t.Fatalf("InsertReplay: %v", err)
+	}
+	if err := s.InsertReplay(ctx, InsertReplayParams{
+		ID:        "r2",
+		WebhookID: "wh1",
+		CreatedAt: 20,
+		TargetURL: "http://localhost:3000/a",
+		Patch:     `{"a":1}`,
+		Method:    "PUT",
+		Headers:   map[string][]string{"Idempotency-Key": {"k2"}},
+		Error:     "connection refused",
+	}); err != nil {
+		t.Fatalf("InsertReplay: %v", err)
+	}
+
+	// Unknown webhooks are rejected by the foreign key.
+	if err := s.InsertReplay(ctx, InsertReplayParams{ID: "r3", WebhookID: "missing", TargetURL: "http://x"}); err == nil {
+		t.Fatal("expected error for unknown webhook")
+	}
+
+	rows, err := s.ListReplays(ctx, "wh1", 10)
+	if err != nil {
+		t.Fatalf("ListReplays: %v", err)
+	}
+	if len(rows) != 2 || rows[0].ID != "r2" || rows[1].ID != "r1" {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	if rows[0].Patch != `{"a":1}` || rows[0].Error != "connection refused" || rows[0].StatusCode != nil {
+		t.Fatalf("unexpected failed replay: %+v", rows[0])
+	}
+	if rows[0].Method != "PUT" || rows[0].Headers["Idempotency-Key"][0] != "k2" {
+		t.Fatalf("sent request not recorded: %+v", rows[0])
+	}
+	if *rows[1].StatusCode != 500 || string(rows[1].ResponseBody) != "boom" || rows[1].Method != "" || rows[1].Headers != nil {
+		t.Fatalf("unexpected replay: %+v", rows[1])
+	}
+
+	r, err := s.GetReplay(ctx, "r2")
+	if err != nil || r.WebhookID != "wh1" || r.Patch != `{"a":1}` {
+		t.Fatalf("GetReplay: %+v %v", r, err)
+	}
+	if _, err := s.GetReplay(ctx, "nope"); err == nil || err.Error() != "not found: nope" {
+		t.Fatalf("GetReplay(missing): %v", err)
+	}
+
+	// History goes away with the webhook.
+	if err := s.DeleteWebhook(ctx, "wh1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	rows, err = s.ListReplays(ctx, "wh1", 10)
+	if err != nil {
+		t.Fatalf("ListReplays: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("expected no replays, got %+v", rows)
+	}
+}
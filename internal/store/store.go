@@ -3,14 +3,20 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"hooktm/internal/form"
+	"hooktm/internal/query"
+
 	_ "modernc.org/sqlite"
 )
 
@@ -59,26 +65,62 @@ type Webhook struct {
 	Query   string              `json:"query,omitempty"`
 	Headers map[string][]string `json:"headers"`
 	Body    []byte              `json:"body"`
+	// BodySize is set for chunked bodies, which are too large to load with
+	// the webhook: Body is nil and OpenBody streams the content.
+	BodySize int64 `json:"body_size,omitempty"`
+	// DecodedBody is the decompressed body when the request had a
+	// Content-Encoding; Body keeps the bytes as sent.
+	DecodedBody []byte `json:"decoded_body,omitempty"`
 
 	Provider  string `json:"provider,omitempty"`
 	EventType string `json:"event_type,omitempty"`
 	Signature string `json:"signature,omitempty"`
 
+	// SignatureValid is nil when the signature wasn't verified.
+	SignatureValid *bool `json:"signature_valid,omitempty"`
+
+	// MockRule names the mock rule that answered in record-only mode.
+	MockRule string `json:"mock_rule,omitempty"`
+	// Fault lists the faults injected while forwarding, e.g. "latency+duplicate".
+	Fault string `json:"fault,omitempty"`
+
 	StatusCode *int  `json:"status_code,omitempty"`
 	ResponseMS int64 `json:"response_ms"`
 
+	// Response returned by the forward target (empty in record-only mode).
+	ResponseHeaders   map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody      []byte              `json:"response_body,omitempty"`
+	ResponseTruncated bool                `json:"response_truncated,omitempty"`
+
+	// Deliveries lists every forward target the webhook was sent to.
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+
+	// Form and Attachments hold the decoded fields of a url-encoded or
+	// multipart body.
+	Form        []form.Field      `json:"form,omitempty"`
+	Attachments []form.Attachment `json:"attachments,omitempty"`
+
 	BodyText string `json:"body_text,omitempty"`
 }
 
+// PlainBody returns the decoded body of compressed webhooks and Body otherwise.
+func (w Webhook) PlainBody() []byte {
+	if w.DecodedBody != nil {
+		return w.DecodedBody
+	}
+	return w.Body
+}
+
 type WebhookSummary struct {
-	ID         string `json:"id"`
-	CreatedAt  int64  `json:"created_at"`
-	Method     string `json:"method"`
-	Path       string `json:"path"`
-	Provider   string `json:"provider,omitempty"`
-	EventType  string `json:"event_type,omitempty"`
-	StatusCode *int   `json:"status_code,omitempty"`
-	ResponseMS int64  `json:"response_ms"`
+	ID             string `json:"id"`
+	CreatedAt      int64  `json:"created_at"`
+	Method         string `json:"method"`
+	Path           string `json:"path"`
+	Provider       string `json:"provider,omitempty"`
+	EventType      string `json:"event_type,omitempty"`
+	SignatureValid *bool  `json:"signature_valid,omitempty"`
+	StatusCode     *int   `json:"status_code,omitempty"`
+	ResponseMS     int64  `json:"response_ms"`
 }
 
 type InsertParams struct {
@@ -90,17 +132,48 @@ type InsertParams struct {
 	Query   string
 	Headers map[string][]string
 	Body    []byte
+	// BodyReader, when set, is stored in chunks instead of Body; BodySize is
+	// its length.
+	BodyReader io.Reader
+	BodySize   int64
+	// DecodedBody is the decompressed Body, if it was encoded.
+	DecodedBody []byte
 
-	Provider  string
-	EventType string
-	Signature string
+	Provider       string
+	EventType      string
+	Signature      string
+	SignatureValid *bool
+	MockRule       string
+	Fault          string
 
 	StatusCode *int
 	ResponseMS int64
 	BodyText   string
+
+	ResponseHeaders   map[string][]string
+	ResponseBody      []byte
+	ResponseTruncated bool
+
+	Deliveries []Delivery
+
+	Form        []form.Field
+	Attachments []form.Attachment
 }
 
 func (s *Store) InsertWebhook(ctx context.Context, p InsertParams) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := insertWebhook(ctx, tx, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertWebhook stores a webhook with its child rows in tx.
+func insertWebhook(ctx context.Context, tx *sql.Tx, p InsertParams) error {
 	if strings.TrimSpace(p.ID) == "" {
 		return fmt.Errorf("missing id")
 	}
@@ -115,30 +188,130 @@ func (s *Store) InsertWebhook(ctx context.Context, p InsertParams) error {
 		return err
 	}
 
-	_, err = s.db.ExecContext(ctx, `
+	var bodySize *int64
+	var contentHash *string
+	if p.BodyReader != nil {
+		bodySize = &p.BodySize
+		p.Body = nil
+	} else {
+		sum := ContentHash(p.Method, p.Path, p.Query, p.Body)
+		contentHash = &sum
+	}
+
+	_, err = tx.ExecContext(ctx, `
 INSERT INTO webhooks (
   id, created_at,
-  method, path, query, headers, body,
-  provider, event_type, signature,
+  method, path, query, headers, body, body_size, decoded_body,
+  provider, event_type, signature, signature_valid, mock_rule, fault,
   status_code, response_ms,
-  body_text
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-`, p.ID, p.CreatedAt, p.Method, p.Path, nullIfEmpty(p.Query), string(hb), p.Body,
-		nullIfEmpty(p.Provider), nullIfEmpty(p.EventType), nullIfEmpty(p.Signature),
-		p.StatusCode, p.ResponseMS, nullIfEmpty(p.BodyText),
+  body_text, content_hash
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, p.ID, p.CreatedAt, p.Method, p.Path, nullIfEmpty(p.Query), string(hb), p.Body, bodySize, p.DecodedBody,
+		nullIfEmpty(p.Provider), nullIfEmpty(p.EventType), nullIfEmpty(p.Signature), p.SignatureValid, nullIfEmpty(p.MockRule), nullIfEmpty(p.Fault),
+		p.StatusCode, p.ResponseMS, nullIfEmpty(p.BodyText), contentHash,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if p.ResponseHeaders != nil || len(p.ResponseBody) > 0 {
+		rhb, err := json.Marshal(p.ResponseHeaders)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_responses (webhook_id, headers, body, truncated)
+VALUES (?, ?, ?, ?)
+`, p.ID, string(rhb), p.ResponseBody, p.ResponseTruncated); err != nil {
+			return err
+		}
+	}
+	if err := insertHeaders(ctx, tx, p.ID, p.Headers); err != nil {
+		return err
+	}
+	if err := insertDeliveries(ctx, tx, p.ID, p.Deliveries); err != nil {
+		return err
+	}
+	if err := insertForm(ctx, tx, p.ID, p.Form, p.Attachments); err != nil {
+		return err
+	}
+	if p.BodyReader != nil {
+		// Chunked bodies are hashed as they are stored.
+		h := newContentHash(p.Method, p.Path, p.Query)
+		if err := insertBodyChunks(ctx, tx, p.ID, io.TeeReader(p.BodyReader, h)); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE webhooks SET content_hash = ? WHERE id = ?`, hex.EncodeToString(h.Sum(nil)), p.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetSignatureValid records the outcome of a signature check (nil: not verified).
+func (s *Store) SetSignatureValid(ctx context.Context, id string, valid *bool) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE webhooks SET signature_valid = ? WHERE id = ?`, valid, strings.TrimSpace(id))
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return fmt.Errorf("not found: %s", id)
+	}
+	return nil
 }
 
 type ListFilter struct {
-	Limit      int
+	Limit int
+	// Search is full-text search over the indexed body, combined with the
+	// other filters.
+	Search string
+	// Where is a query-language filter (see package query), e.g.
+	// `provider = stripe and body.data.object.amount > 5000`.
+	Where      string
 	Provider   string
 	StatusCode *int
 	From       *time.Time // Inclusive start date
 	To         *time.Time // Inclusive end date
+	// Headers must all match (see HeaderMatch).
+	Headers []HeaderMatch
+	// After continues a listing from the previous page's Page.Next.
+	After *Cursor
+}
+
+// Cursor is a position in the newest-first listing: the created_at and id of
+// the last row of a page.
+type Cursor struct {
+	CreatedAt int64
+	ID        string
+}
+
+func (c Cursor) String() string { return fmt.Sprintf("%d.%s", c.CreatedAt, c.ID) }
+
+// ParseCursor parses the form written by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	ts, id, ok := strings.Cut(strings.TrimSpace(s), ".")
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if !ok || err != nil || id == "" {
+		return nil, fmt.Errorf("invalid cursor: %q", s)
+	}
+	return &Cursor{CreatedAt: n, ID: id}, nil
+}
+
+// Page is one page of a listing. Next is nil on the last page.
+type Page struct {
+	Rows []WebhookSummary
+	Next *Cursor
 }
 
 func (s *Store) ListSummaries(ctx context.Context, f ListFilter) ([]WebhookSummary, error) {
+	p, err := s.ListPage(ctx, f)
+	return p.Rows, err
+}
+
+// ListPage returns up to f.Limit webhooks (newest first, at most 500) matching
+// every filter in f, starting after f.After.
+func (s *Store) ListPage(ctx context.Context, f ListFilter) (Page, error) {
 	limit := f.Limit
 	if limit <= 0 || limit > 500 {
 		limit = 20
@@ -147,6 +320,11 @@ func (s *Store) ListSummaries(ctx context.Context, f ListFilter) ([]WebhookSumma
 		wheres []string
 		args   []any
 	)
+	if strings.TrimSpace(f.Search) != "" {
+		// Sanitize FTS5 query to prevent injection of special operators.
+		wheres = append(wheres, "rowid IN (SELECT rowid FROM webhooks_fts WHERE webhooks_fts MATCH ?)")
+		args = append(args, sanitizeFTSQuery(strings.TrimSpace(f.Search)))
+	}
 	if strings.TrimSpace(f.Provider) != "" {
 		wheres = append(wheres, "provider = ?")
 		args = append(args, f.Provider)
@@ -163,23 +341,45 @@ func (s *Store) ListSummaries(ctx context.Context, f ListFilter) ([]WebhookSumma
 		wheres = append(wheres, "created_at <= ?")
 		args = append(args, f.To.UnixMilli())
 	}
+	for _, m := range f.Headers {
+		cond, hargs := headerCondition(m)
+		wheres = append(wheres, cond)
+		args = append(args, hargs...)
+	}
+	if strings.TrimSpace(f.Where) != "" {
+		e, err := query.Parse(f.Where)
+		if err != nil {
+			return Page{}, err
+		}
+		cond, qargs, err := whereQuery(e, time.Now())
+		if err != nil {
+			return Page{}, err
+		}
+		wheres = append(wheres, cond)
+		args = append(args, qargs...)
+	}
+	if f.After != nil {
+		wheres = append(wheres, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, f.After.CreatedAt, f.After.CreatedAt, f.After.ID)
+	}
 	whereSQL := ""
 	if len(wheres) > 0 {
 		whereSQL = "WHERE " + strings.Join(wheres, " AND ")
 	}
 
+	// One extra row tells whether there is a next page.
 	q := fmt.Sprintf(`
-SELECT id, created_at, method, path, provider, event_type, status_code, response_ms
+SELECT id, created_at, method, path, provider, event_type, signature_valid, status_code, response_ms
 FROM webhooks
 %s
-ORDER BY created_at DESC
+ORDER BY created_at DESC, id DESC
 LIMIT ?
 `, whereSQL)
-	args = append(args, limit)
+	args = append(args, limit+1)
 
 	rows, err := s.db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, err
+		return Page{}, err
 	}
 	defer rows.Close()
 
@@ -187,14 +387,23 @@ LIMIT ?
 	for rows.Next() {
 		var r WebhookSummary
 		var prov, ev sql.NullString
-		if err := rows.Scan(&r.ID, &r.CreatedAt, &r.Method, &r.Path, &prov, &ev, &r.StatusCode, &r.ResponseMS); err != nil {
-			return nil, err
+		if err := rows.Scan(&r.ID, &r.CreatedAt, &r.Method, &r.Path, &prov, &ev, &r.SignatureValid, &r.StatusCode, &r.ResponseMS); err != nil {
+			return Page{}, err
 		}
 		r.Provider = prov.String
 		r.EventType = ev.String
 		out = append(out, r)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	page := Page{Rows: out}
+	if len(out) > limit {
+		page.Rows = out[:limit]
+		last := page.Rows[limit-1]
+		page.Next = &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return page, nil
 }
 
 func (s *Store) GetWebhook(ctx context.Context, id string) (Webhook, error) {
@@ -209,23 +418,31 @@ func (s *Store) GetWebhook(ctx context.Context, id string) (Webhook, error) {
 		prov  sql.NullString
 		ev    sql.NullString
 		sig   sql.NullString
+		mock  sql.NullString
+		fault sql.NullString
+		bsz   sql.NullInt64
 		bt    sql.NullString
+		rh    sql.NullString
+		rt    sql.NullBool
 	)
 	err := s.db.QueryRowContext(ctx, `
 SELECT
-  id, created_at,
-  method, path, query, headers, body,
-  provider, event_type, signature,
-  status_code, response_ms,
-  body_text
-FROM webhooks
-WHERE id = ?
+  w.id, w.created_at,
+  w.method, w.path, w.query, w.headers, w.body, w.body_size, w.decoded_body,
+  w.provider, w.event_type, w.signature, w.signature_valid, w.mock_rule, w.fault,
+  w.status_code, w.response_ms,
+  w.body_text,
+  r.headers, r.body, r.truncated
+FROM webhooks w
+LEFT JOIN webhook_responses r ON r.webhook_id = w.id
+WHERE w.id = ?
 `, id).Scan(
 		&wh.ID, &wh.CreatedAt,
-		&wh.Method, &wh.Path, &qry, &hJSON, &wh.Body,
-		&prov, &ev, &sig,
+		&wh.Method, &wh.Path, &qry, &hJSON, &wh.Body, &bsz, &wh.DecodedBody,
+		&prov, &ev, &sig, &wh.SignatureValid, &mock, &fault,
 		&wh.StatusCode, &wh.ResponseMS,
 		&bt,
+		&rh, &wh.ResponseBody, &rt,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return Webhook{}, fmt.Errorf("not found: %s", id)
@@ -237,49 +454,38 @@ WHERE id = ?
 	wh.Provider = prov.String
 	wh.EventType = ev.String
 	wh.Signature = sig.String
+	wh.MockRule = mock.String
+	wh.Fault = fault.String
+	wh.BodySize = bsz.Int64
 	wh.BodyText = bt.String
 	if err := json.Unmarshal([]byte(hJSON), &wh.Headers); err != nil {
 		// Don't fail hard on corrupt headers; keep usable.
 		wh.Headers = map[string][]string{"_error": {err.Error()}}
 	}
+	if rh.Valid {
+		if err := json.Unmarshal([]byte(rh.String), &wh.ResponseHeaders); err != nil {
+			wh.ResponseHeaders = map[string][]string{"_error": {err.Error()}}
+		}
+	}
+	wh.ResponseTruncated = rt.Bool
+	wh.Deliveries, err = s.listDeliveries(ctx, wh.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	wh.Form, wh.Attachments, err = s.listForm(ctx, wh.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
 	return wh, nil
 }
 
+// SearchSummaries is full-text search without other filters.
 func (s *Store) SearchSummaries(ctx context.Context, query string, limit int) ([]WebhookSummary, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return nil, fmt.Errorf("empty search query")
 	}
-	if limit <= 0 || limit > 500 {
-		limit = 20
-	}
-	// Sanitize FTS5 query to prevent injection of special operators.
-	// Wrap each word in double quotes to treat as literal text.
-	query = sanitizeFTSQuery(query)
-	rows, err := s.db.QueryContext(ctx, `
-SELECT w.id, w.created_at, w.method, w.path, w.provider, w.event_type, w.status_code, w.response_ms
-FROM webhooks_fts f
-JOIN webhooks w ON w.rowid = f.rowid
-WHERE webhooks_fts MATCH ?
-ORDER BY w.created_at DESC
-LIMIT ?
-`, query, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var out []WebhookSummary
-	for rows.Next() {
-		var r WebhookSummary
-		var prov, ev sql.NullString
-		if err := rows.Scan(&r.ID, &r.CreatedAt, &r.Method, &r.Path, &prov, &ev, &r.StatusCode, &r.ResponseMS); err != nil {
-			return nil, err
-		}
-		r.Provider = prov.String
-		r.EventType = ev.String
-		out = append(out, r)
-	}
-	return out, rows.Err()
+	return s.ListSummaries(ctx, ListFilter{Search: query, Limit: limit})
 }
 
 func (s *Store) DeleteWebhook(ctx context.Context, id string) error {
@@ -302,6 +508,7 @@ type DeleteFilter struct {
 	OlderThan  time.Duration
 	Provider   string
 	StatusCode *int
+	Headers    []HeaderMatch
 }
 
 func (s *Store) DeleteByFilter(ctx context.Context, f DeleteFilter) (int64, error) {
@@ -322,6 +529,11 @@ func (s *Store) DeleteByFilter(ctx context.Context, f DeleteFilter) (int64, erro
 		wheres = append(wheres, "status_code = ?")
 		args = append(args, *f.StatusCode)
 	}
+	for _, m := range f.Headers {
+		cond, hargs := headerCondition(m)
+		wheres = append(wheres, cond)
+		args = append(args, hargs...)
+	}
 	if len(wheres) == 0 {
 		return 0, fmt.Errorf("at least one filter required for bulk delete")
 	}
@@ -340,6 +552,11 @@ func ensureDir(path string) error {
 	return os.MkdirAll(path, 0o755)
 }
 
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 func nullIfEmpty(s string) any {
 	if strings.TrimSpace(s) == "" {
 		return nil
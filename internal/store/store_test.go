@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"hooktm/internal/form"
 )
 
 func TestInsertAndGet(t *testing.T) {
@@ -39,6 +41,92 @@ func TestInsertAndGet(t *testing.T) {
 	if wh.Provider != "stripe" || wh.Method != "POST" || wh.Path != "/hooks/stripe" {
 		t.Fatalf("unexpected webhook: %+v", wh)
 	}
+	if wh.SignatureValid != nil {
+		t.Fatalf("expected unverified signature, got %v", *wh.SignatureValid)
+	}
+
+	valid := false
+	if err := s.SetSignatureValid(ctx, "abc123", &valid); err != nil {
+		t.Fatalf("SetSignatureValid: %v", err)
+	}
+	rows, err := s.ListSummaries(ctx, ListFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSummaries: %v", err)
+	}
+	if len(rows) != 1 || rows[0].SignatureValid == nil || *rows[0].SignatureValid {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	if err := s.SetSignatureValid(ctx, "missing", &valid); err == nil {
+		t.Fatal("expected error for unknown webhook")
+	}
+}
+
+func TestInsertAndGet_Response(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	err = s.InsertWebhook(ctx, InsertParams{
+		ID:                "resp1",
+		CreatedAt:         1,
+		Method:            "POST",
+		Path:              "/a",
+		Headers:           map[string][]string{"Content-Type": {"application/json"}},
+		Body:              []byte(`{}`),
+		StatusCode:        ptr(500),
+		ResponseHeaders:   map[string][]string{"Content-Type": {"text/plain"}},
+		ResponseBody:      []byte("boom"),
+		ResponseTruncated: true,
+		MockRule:          "stripe-fails",
+		Fault:             "latency+duplicate",
+	})
+	if err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+	_ = s.InsertWebhook(ctx, InsertParams{
+		ID:        "noresp",
+		CreatedAt: 2,
+		Method:    "POST",
+		Path:      "/b",
+		Headers:   map[string][]string{},
+	})
+
+	wh, err := s.GetWebhook(ctx, "resp1")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if string(wh.ResponseBody) != "boom" || !wh.ResponseTruncated {
+		t.Fatalf("unexpected response: body=%q truncated=%v", wh.ResponseBody, wh.ResponseTruncated)
+	}
+	if got := wh.ResponseHeaders["Content-Type"]; len(got) != 1 || got[0] != "text/plain" {
+		t.Fatalf("unexpected response headers: %+v", wh.ResponseHeaders)
+	}
+	if wh.MockRule != "stripe-fails" || wh.Fault != "latency+duplicate" {
+		t.Fatalf("MockRule=%q Fault=%q", wh.MockRule, wh.Fault)
+	}
+
+	wh, err = s.GetWebhook(ctx, "noresp")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if wh.ResponseHeaders != nil || wh.ResponseBody != nil {
+		t.Fatalf("expected no response, got %+v", wh)
+	}
+
+	// Deleting the webhook removes its response too.
+	if err := s.DeleteWebhook(ctx, "resp1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_responses`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected response rows to be deleted, got %d", n)
+	}
 }
 
 func TestListAndSearch(t *testing.T) {
@@ -314,3 +402,136 @@ func TestDeleteByFilter(t *testing.T) {
 		t.Fatal("expected error for empty filter")
 	}
 }
+
+func TestInsertAndGet_Deliveries(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	err = s.InsertWebhook(ctx, InsertParams{
+		ID:         "fan1",
+		CreatedAt:  1,
+		Method:     "POST",
+		Path:       "/stripe",
+		Headers:    map[string][]string{},
+		StatusCode: ptr(200),
+		Deliveries: []Delivery{
+			{TargetURL: "http://billing/stripe", Primary: true, StatusCode: ptr(200), DurationMS: 12},
+			{TargetURL: "http://ledger/stripe", DurationMS: 30, Error: "connection refused"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	wh, err := s.GetWebhook(ctx, "fan1")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if len(wh.Deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %+v", wh.Deliveries)
+	}
+	first, second := wh.Deliveries[0], wh.Deliveries[1]
+	if !first.Primary || first.StatusCode == nil || *first.StatusCode != 200 || first.TargetURL != "http://billing/stripe" {
+		t.Fatalf("unexpected first delivery: %+v", first)
+	}
+	if second.Primary || second.StatusCode != nil || second.Error != "connection refused" || second.DurationMS != 30 {
+		t.Fatalf("unexpected second delivery: %+v", second)
+	}
+
+	if err := s.DeleteWebhook(ctx, "fan1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("deliveries not cascaded: n=%d err=%v", n, err)
+	}
+}
+
+func TestInsertAndGet_Form(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	err = s.InsertWebhook(ctx, InsertParams{
+		ID:          "inbound",
+		CreatedAt:   1,
+		Method:      "POST",
+		Path:        "/mailgun",
+		Headers:     map[string][]string{},
+		BodyText:    "subject: Quarterly invoice\nattachment-1: invoice.pdf application/pdf\n",
+		Form:        []form.Field{{Name: "subject", Value: "Quarterly invoice"}, {Name: "to", Value: "a"}, {Name: "to", Value: "b"}},
+		Attachments: []form.Attachment{{Field: "attachment-1", Filename: "invoice.pdf", ContentType: "application/pdf", Size: 1234, Data: []byte("x")}},
+	})
+	if err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	wh, err := s.GetWebhook(ctx, "inbound")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if len(wh.Form) != 3 || wh.Form[2] != (form.Field{Name: "to", Value: "b"}) {
+		t.Fatalf("unexpected form: %+v", wh.Form)
+	}
+	if len(wh.Attachments) != 1 {
+		t.Fatalf("unexpected attachments: %+v", wh.Attachments)
+	}
+	if a := wh.Attachments[0]; a.Filename != "invoice.pdf" || a.Size != 1234 || a.ContentType != "application/pdf" || a.Data != nil {
+		t.Fatalf("unexpected attachment: %+v", a)
+	}
+
+	res, err := s.SearchSummaries(ctx, "invoice.pdf", 10)
+	if err != nil || len(res) != 1 {
+		t.Fatalf("search: %v %+v", err, res)
+	}
+
+	if err := s.DeleteWebhook(ctx, "inbound"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	var n int
+	if err := s.db.QueryRow(`SELECT (SELECT COUNT(*) FROM webhook_form_fields) + (SELECT COUNT(*) FROM webhook_attachments)`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("form rows not cascaded: n=%d err=%v", n, err)
+	}
+}
+
+func TestInsertAndGet_DecodedBody(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	_ = s.InsertWebhook(ctx, InsertParams{ID: "plain", CreatedAt: 1, Method: "POST", Path: "/a", Headers: map[string][]string{}, Body: []byte("{}")})
+	err = s.InsertWebhook(ctx, InsertParams{
+		ID:          "gz",
+		CreatedAt:   2,
+		Method:      "POST",
+		Path:        "/a",
+		Headers:     map[string][]string{"Content-Encoding": {"gzip"}},
+		Body:        []byte{0x1f, 0x8b, 0x08},
+		DecodedBody: []byte(`{"id":1}`),
+	})
+	if err != nil {
+		t.Fatalf("InsertWebhook: %v", err)
+	}
+
+	wh, err := s.GetWebhook(ctx, "gz")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if len(wh.Body) != 3 || string(wh.PlainBody()) != `{"id":1}` {
+		t.Fatalf("Body=%x PlainBody=%q", wh.Body, wh.PlainBody())
+	}
+	wh, _ = s.GetWebhook(ctx, "plain")
+	if wh.DecodedBody != nil || string(wh.PlainBody()) != "{}" {
+		t.Fatalf("DecodedBody=%q PlainBody=%q", wh.DecodedBody, wh.PlainBody())
+	}
+}
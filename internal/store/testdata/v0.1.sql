@@ -0,0 +1,55 @@
+-- Database as created by hooktm v0.1 (unversioned schema, user_version 0).
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id           TEXT PRIMARY KEY,
+    created_at   INTEGER NOT NULL,
+
+    method       TEXT NOT NULL,
+    path         TEXT NOT NULL,
+    query        TEXT,
+    headers      TEXT NOT NULL,
+    body         BLOB,
+
+    provider     TEXT,
+    event_type   TEXT,
+    signature    TEXT,
+
+    status_code  INTEGER,
+    response_ms  INTEGER,
+
+    body_text    TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_created ON webhooks(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhooks_provider ON webhooks(provider);
+CREATE INDEX IF NOT EXISTS idx_webhooks_status ON webhooks(status_code);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS webhooks_fts USING fts5(
+    body_text,
+    content='webhooks',
+    content_rowid='rowid'
+);
+
+-- FTS5 external content triggers
+CREATE TRIGGER IF NOT EXISTS webhooks_ai AFTER INSERT ON webhooks BEGIN
+  INSERT INTO webhooks_fts(rowid, body_text) VALUES (new.rowid, new.body_text);
+END;
+CREATE TRIGGER IF NOT EXISTS webhooks_ad AFTER DELETE ON webhooks BEGIN
+  INSERT INTO webhooks_fts(webhooks_fts, rowid, body_text) VALUES('delete', old.rowid, old.body_text);
+END;
+CREATE TRIGGER IF NOT EXISTS webhooks_au AFTER UPDATE ON webhooks BEGIN
+  INSERT INTO webhooks_fts(webhooks_fts, rowid, body_text) VALUES('delete', old.rowid, old.body_text);
+  INSERT INTO webhooks_fts(rowid, body_text) VALUES (new.rowid, new.body_text);
+END;
+
+INSERT INTO webhooks (id, created_at, method, path, query, headers, body, provider, event_type, signature, status_code, response_ms, body_text)
+VALUES ('legacy1', 1700000000000, 'POST', '/hooks/stripe', NULL,
+        '{"Content-Type":["application/json"],"Stripe-Signature":["t=1,v1=abc"]}',
+        CAST('{"type":"invoice.paid"}' AS BLOB), 'stripe', 'invoice.paid', 't=1,v1=abc', 200, 15,
+        '{"type":"invoice.paid"}');
+
+INSERT INTO webhooks (id, created_at, method, path, query, headers, body, provider, event_type, signature, status_code, response_ms, body_text)
+VALUES ('legacy2', 1700000001000, 'POST', '/hooks/github', 'a=1',
+        '{"Content-Type":["application/json"],"X-Github-Event":["push"]}',
+        CAST('{"ref":"refs/heads/main"}' AS BLOB), 'github', 'push', NULL, 500, 30,
+        '{"ref":"refs/heads/main"}');
@@ -0,0 +1,175 @@
+package suite
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	File     string          `xml:"file,attr,omitempty"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the reports as JUnit XML, one testsuite per report.
+// Failed expectations are failures; cases that couldn't run or got no
+// response are errors.
+func WriteJUnit(w io.Writer, reports []Report) error {
+	out := junitTestSuites{Name: "hooktm"}
+	var total time.Duration
+	for _, r := range reports {
+		ts := junitTestSuite{Name: r.Suite, File: r.File, Time: seconds(r.Duration)}
+		for _, c := range r.Cases {
+			tc := junitTestCase{Name: c.Name, Classname: r.Suite, Time: seconds(c.Duration), SystemOut: summary(c)}
+			switch {
+			case c.Skipped != "":
+				tc.Skipped = &junitMessage{Message: c.Skipped}
+				ts.Skipped++
+			case c.Err != nil:
+				tc.Error = &junitMessage{Message: c.Err.Error(), Type: "error"}
+				ts.Errors++
+			case len(c.Failures) > 0:
+				lines := make([]string, len(c.Failures))
+				for i, f := range c.Failures {
+					lines[i] = f.String()
+				}
+				tc.Failure = &junitMessage{Message: lines[0], Type: "assertion", Text: strings.Join(lines, "\n")}
+				ts.Failures++
+			}
+			ts.Cases = append(ts.Cases, tc)
+		}
+		ts.Tests = len(ts.Cases)
+		out.Tests += ts.Tests
+		out.Failures += ts.Failures
+		out.Errors += ts.Errors
+		out.Skipped += ts.Skipped
+		total += r.Duration
+		out.Suites = append(out.Suites, ts)
+	}
+	out.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteTAP writes the reports as one TAP 13 stream, naming each case after
+// its suite. Failures carry a YAML diagnostic block.
+func WriteTAP(w io.Writer, reports []Report) error {
+	bw := bufio.NewWriter(w)
+	n := 0
+	for _, r := range reports {
+		n += len(r.Cases)
+	}
+	fmt.Fprintf(bw, "TAP version 13\n1..%d\n", n)
+	i := 0
+	for _, r := range reports {
+		for _, c := range r.Cases {
+			i++
+			name := tapEscape(r.Suite + ": " + c.Name)
+			switch {
+			case c.Skipped != "":
+				fmt.Fprintf(bw, "ok %d - %s # SKIP %s\n", i, name, tapEscape(c.Skipped))
+				continue
+			case c.Passed():
+				fmt.Fprintf(bw, "ok %d - %s\n", i, name)
+				continue
+			}
+			fmt.Fprintf(bw, "not ok %d - %s\n", i, name)
+			diag := map[string]any{"duration_ms": c.Duration.Milliseconds()}
+			if c.WebhookID != "" {
+				diag["webhook"] = c.WebhookID
+			}
+			if c.Result.Sent {
+				diag["status"] = c.Result.StatusCode
+			}
+			if c.Err != nil {
+				diag["message"] = c.Err.Error()
+				diag["severity"] = "error"
+			} else {
+				failures := make([]string, len(c.Failures))
+				for j, f := range c.Failures {
+					failures[j] = f.String()
+				}
+				diag["message"] = failures[0]
+				diag["severity"] = "fail"
+				diag["failures"] = failures
+			}
+			var buf strings.Builder
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if err := enc.Encode(diag); err != nil {
+				return err
+			}
+			fmt.Fprintf(bw, "  ---\n")
+			for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+				fmt.Fprintf(bw, "  %s\n", line)
+			}
+			fmt.Fprintf(bw, "  ...\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// summary describes what a case sent and got, for JUnit's system-out.
+func summary(c CaseResult) string {
+	if !c.Result.Sent {
+		return ""
+	}
+	return fmt.Sprintf("%s → %d in %dms", c.Result.URL, c.Result.StatusCode, c.Result.DurationMS)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// tapEscape keeps a description on one line and away from directives.
+func tapEscape(s string) string {
+	s = strings.NewReplacer("\n", " ", "\r", " ", "#", `\#`).Replace(s)
+	return strings.TrimSpace(s)
+}
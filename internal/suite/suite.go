@@ -0,0 +1,314 @@
+// Package suite runs declarative replay tests for `hooktm test`:
+//
+//	name: stripe
+//	target: http://localhost:3000
+//	resign: true                          # re-sign with the secrets from config
+//	cases:
+//	  - name: invoice paid is acknowledged
+//	    webhook: abc123                   # a captured webhook ID
+//	    patch: {data: {object: {amount_paid: 0}}}
+//	    expect:
+//	      status: 200
+//	      max_latency: 500ms
+//	      json: {$.received: "true"}
+//	  - name: duplicate is rejected
+//	    fixture: fixtures/invoice.http    # HAR, curl, raw HTTP or NDJSON, one request
+//	    target: http://localhost:3001
+//	    expect: {status: 409}
+//	  - name: refunds
+//	    webhook: def456
+//	    skip: not implemented yet
+//
+// Expectations are assert.Spec; a case without any passes on a 2xx. Fixture
+// paths are relative to the suite file.
+package suite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"hooktm/internal/assert"
+	"hooktm/internal/importer"
+	"hooktm/internal/replay"
+	"hooktm/internal/signature"
+	"hooktm/internal/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a parsed suite file.
+type Suite struct {
+	Name   string  `yaml:"name"`
+	Target string  `yaml:"target"`
+	Resign bool    `yaml:"resign"`
+	Cases  []*Case `yaml:"cases"`
+
+	// File is the path the suite was loaded from.
+	File string `yaml:"-"`
+}
+
+// Case is one replay and what its response must look like.
+type Case struct {
+	Name    string      `yaml:"name"`
+	Webhook string      `yaml:"webhook"`
+	Fixture string      `yaml:"fixture"`
+	Patch   any         `yaml:"patch"`
+	Target  string      `yaml:"target"`
+	Skip    string      `yaml:"skip"`
+	Expect  assert.Spec `yaml:"expect"`
+
+	patch string
+}
+
+// Load reads and validates a suite file.
+func Load(p string) (*Suite, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(b, filepath.Dir(p))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	s.File = p
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	}
+	return s, nil
+}
+
+// Parse parses suite file contents. Fixture paths are resolved against dir.
+func Parse(b []byte, dir string) (*Suite, error) {
+	var s Suite
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Cases) == 0 {
+		return nil, fmt.Errorf("no cases")
+	}
+	for i, c := range s.Cases {
+		if c == nil {
+			return nil, fmt.Errorf("case %d is empty", i+1)
+		}
+		if strings.TrimSpace(c.Name) == "" {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		c.Webhook, c.Fixture = strings.TrimSpace(c.Webhook), strings.TrimSpace(c.Fixture)
+		if (c.Webhook == "") == (c.Fixture == "") {
+			return nil, fmt.Errorf("%s: set either webhook or fixture", c.Name)
+		}
+		if c.Fixture != "" && !filepath.IsAbs(c.Fixture) {
+			c.Fixture = filepath.Join(dir, c.Fixture)
+		}
+		switch p := c.Patch.(type) {
+		case nil:
+		case string:
+			c.patch = p
+		default:
+			// Patches written as YAML mappings are sent as JSON.
+			j, err := json.Marshal(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: patch: %w", c.Name, err)
+			}
+			c.patch = string(j)
+		}
+		if c.Expect.Empty() {
+			c.Expect.Status = "2xx"
+		}
+		if err := c.Expect.Compile(); err != nil {
+			return nil, fmt.Errorf("%s: expect: %w", c.Name, err)
+		}
+	}
+	return &s, nil
+}
+
+// HasFixtures reports whether any case replays a fixture file.
+func (s *Suite) HasFixtures() bool {
+	for _, c := range s.Cases {
+		if c.Fixture != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	Name      string
+	WebhookID string
+	Result    replay.Result
+	Duration  time.Duration
+	// Failures are the expectations the response didn't meet; Err is set
+	// when the case couldn't run or got no response.
+	Failures []assert.Failure
+	Err      error
+	Skipped  string
+}
+
+// Passed reports whether the case ran and met its expectations.
+func (r CaseResult) Passed() bool {
+	return r.Skipped == "" && r.Err == nil && len(r.Failures) == 0
+}
+
+// Report is the outcome of a suite.
+type Report struct {
+	Suite    string
+	File     string
+	Cases    []CaseResult
+	Duration time.Duration
+}
+
+// Counts returns how many cases passed, failed (including errors) and were
+// skipped.
+func (r Report) Counts() (passed, failed, skipped int) {
+	for _, c := range r.Cases {
+		switch {
+		case c.Skipped != "":
+			skipped++
+		case c.Passed():
+			passed++
+		default:
+			failed++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// Runner runs suites through a replay engine.
+type Runner struct {
+	// Engine replays captured webhooks. Fixture cases are replayed by a
+	// copy of it reading from Fixtures.Store, a throwaway store.
+	Engine   *replay.Engine
+	Fixtures *importer.Importer
+	// Signer re-signs the replays of suites with resign set.
+	Signer *signature.Signer
+	// Target is used by suites and cases that set none.
+	Target string
+	// OnCase, when set, is called after each case.
+	OnCase func(CaseResult)
+
+	fixtures map[string]string // fixture path → webhook ID in Fixtures.Store
+}
+
+// Run runs the cases of s in order.
+func (r *Runner) Run(ctx context.Context, s *Suite) (Report, error) {
+	engine := r.Engine
+	if s.Resign {
+		if r.Signer == nil {
+			return Report{}, fmt.Errorf("%s: resign needs a signer", s.Name)
+		}
+		e := *engine
+		e.Signer = r.Signer
+		engine = &e
+	}
+	var fixtureEngine *replay.Engine
+	if r.Fixtures != nil {
+		fixtureEngine = engine.WithStore(r.Fixtures.Store)
+	}
+
+	rep := Report{Suite: s.Name, File: s.File}
+	start := time.Now()
+	for _, c := range s.Cases {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		res := CaseResult{Name: c.Name, WebhookID: c.Webhook, Skipped: c.Skip}
+		if c.Skip == "" {
+			caseStart := time.Now()
+			r.runCase(ctx, s, c, engine, fixtureEngine, &res)
+			res.Duration = time.Since(caseStart)
+		}
+		rep.Cases = append(rep.Cases, res)
+		if r.OnCase != nil {
+			r.OnCase(res)
+		}
+	}
+	rep.Duration = time.Since(start)
+	return rep, nil
+}
+
+func (r *Runner) runCase(ctx context.Context, s *Suite, c *Case, engine, fixtureEngine *replay.Engine, res *CaseResult) {
+	target := firstNonEmpty(c.Target, s.Target, r.Target)
+	if target == "" {
+		res.Err = fmt.Errorf("no target: set target in the suite or the case")
+		return
+	}
+	if c.Fixture != "" {
+		if fixtureEngine == nil {
+			res.Err = fmt.Errorf("fixtures need a fixture store")
+			return
+		}
+		id, err := r.fixture(ctx, c.Fixture)
+		if err != nil {
+			res.Err = err
+			return
+		}
+		res.WebhookID, engine = id, fixtureEngine
+	}
+
+	out, err := engine.ReplayByID(ctx, res.WebhookID, target, c.patch)
+	res.Result = out
+	if err != nil {
+		res.Err = err
+		return
+	}
+	if out.Sent {
+		res.Failures = c.Expect.Check(assert.FromResult(out))
+	}
+}
+
+// fixture imports a fixture file into the fixture store once and returns
+// the ID of its webhook.
+func (r *Runner) fixture(ctx context.Context, p string) (string, error) {
+	if id, ok := r.fixtures[p]; ok {
+		return id, nil
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	format := importer.Detect(p, data)
+	if format == "" || format == "db" {
+		return "", fmt.Errorf("%s: unknown fixture format (use HAR, curl, raw HTTP or NDJSON)", p)
+	}
+	webhooks, err := importer.Parse(format, data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", p, err)
+	}
+	if len(webhooks) != 1 {
+		return "", fmt.Errorf("%s: a fixture holds one request, found %d", p, len(webhooks))
+	}
+	imp, err := r.Fixtures.Import(ctx, webhooks)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", p, err)
+	}
+	var id string
+	if len(imp.IDs) > 0 {
+		id = imp.IDs[0]
+	} else {
+		// Another fixture with the same request is already in the store.
+		wh := webhooks[0]
+		if id, err = r.Fixtures.Store.FindByHash(ctx, store.ContentHash(wh.Method, wh.Path, wh.Query, wh.Body)); err != nil {
+			return "", err
+		}
+	}
+	if r.fixtures == nil {
+		r.fixtures = map[string]string{}
+	}
+	r.fixtures[p] = id
+	return id, nil
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package suite
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hooktm/internal/importer"
+	"hooktm/internal/replay"
+	"hooktm/internal/store"
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(`
+name: stripe
+target: localhost:3000
+cases:
+  - webhook: wh1
+    patch: {data: {amount: 0}}
+  - name: dup
+    fixture: fixtures/dup.http
+    patch: '{"a":1}'
+    expect: {status: 409}
+`), "/suites")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c0, c1 := s.Cases[0], s.Cases[1]
+	if c0.Name != "case-1" || c0.patch != `{"data":{"amount":0}}` || c0.Expect.Status != "2xx" {
+		t.Fatalf("case 1: %+v", c0)
+	}
+	if c1.Fixture != "/suites/fixtures/dup.http" || c1.patch != `{"a":1}` || c1.Expect.Status != "409" || !s.HasFixtures() {
+		t.Fatalf("case 2: %+v", c1)
+	}
+
+	for _, bad := range []string{
+		`cases: []`,
+		`cases: [{name: x}]`,
+		`cases: [{webhook: a, fixture: b}]`,
+		`cases: [{webhook: a, expect: {status: teapot}}]`,
+	} {
+		if _, err := Parse([]byte(bad), "."); err == nil {
+			t.Errorf("Parse(%q): expected error", bad)
+		}
+	}
+}
+
+func TestRunner(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.Open(filepath.Join(dir, "main.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	scratch, err := store.Open(filepath.Join(dir, "fixtures.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scratch.Close()
+	ctx := context.Background()
+	if err := s.InsertWebhook(ctx, store.InsertParams{
+		ID: "wh1", CreatedAt: 1, Method: "POST", Path: "/hooks",
+		Headers: map[string][]string{"Content-Type": {"application/json"}}, Body: []byte(`{"id":"evt_1"}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	fixture := "POST /hooks HTTP/1.1\nContent-Type: application/json\n\n{\"id\":\"evt_dup\"}\n"
+	if err := os.WriteFile(filepath.Join(dir, "dup.http"), []byte(fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(b), "evt_dup") {
+			w.WriteHeader(http.StatusConflict)
+		}
+		_, _ = w.Write(append([]byte(`{"received":true,"echo":`), append(b, '}')...))
+	}))
+	defer srv.Close()
+
+	suitePath := filepath.Join(dir, "suite.yaml")
+	if err := os.WriteFile(suitePath, []byte(`
+cases:
+  - name: acknowledged
+    webhook: wh1
+    patch: {amount: 5}
+    expect: {status: 200, json: {echo.amount: "5"}}
+  - name: duplicate rejected
+    fixture: dup.http
+    expect: {status: 409}
+  - name: duplicate again
+    fixture: dup.http
+    expect: {status: 200}
+  - name: missing
+    webhook: nope
+  - name: later
+    webhook: wh1
+    skip: not yet
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	su, err := Load(suitePath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if su.Name != "suite" {
+		t.Fatalf("default name %q", su.Name)
+	}
+
+	var seen int
+	r := &Runner{
+		Engine:   replay.NewEngine(s),
+		Fixtures: &importer.Importer{Store: scratch},
+		Target:   srv.URL,
+		OnCase:   func(CaseResult) { seen++ },
+	}
+	rep, err := r.Run(ctx, su)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if passed, failed, skipped := rep.Counts(); passed != 2 || failed != 2 || skipped != 1 || seen != 5 {
+		t.Fatalf("counts %d/%d/%d: %+v", passed, failed, skipped, rep.Cases)
+	}
+	if c := rep.Cases[2]; len(c.Failures) != 1 || c.Failures[0].Actual != "409" || c.WebhookID != rep.Cases[1].WebhookID {
+		t.Fatalf("duplicate again: %+v", c)
+	}
+	if c := rep.Cases[3]; c.Err == nil || !strings.Contains(c.Err.Error(), "not found") {
+		t.Fatalf("missing: %+v", c)
+	}
+	// Fixture replays stay out of the main store.
+	if history, _ := s.ListReplays(ctx, rep.Cases[1].WebhookID, 10); len(history) != 0 {
+		t.Fatalf("fixture replays recorded in the main store: %d", len(history))
+	}
+
+	var junit bytes.Buffer
+	if err := WriteJUnit(&junit, []Report{rep}); err != nil {
+		t.Fatal(err)
+	}
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(junit.Bytes(), &parsed); err != nil {
+		t.Fatalf("JUnit: %v\n%s", err, junit.String())
+	}
+	ts := parsed.Suites[0]
+	if parsed.Tests != 5 || ts.Failures != 1 || ts.Errors != 1 || ts.Skipped != 1 || ts.Cases[2].Failure == nil || ts.Cases[3].Error == nil {
+		t.Fatalf("unexpected JUnit:\n%s", junit.String())
+	}
+
+	var tap bytes.Buffer
+	if err := WriteTAP(&tap, []Report{rep}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"TAP version 13\n1..5\n",
+		"ok 1 - suite: acknowledged\n",
+		"not ok 3 - suite: duplicate again\n  ---\n",
+		"  message: 'status: expected 200, got 409'\n",
+		"ok 5 - suite: later # SKIP not yet\n",
+	} {
+		if !strings.Contains(tap.String(), want) {
+			t.Fatalf("TAP missing %q:\n%s", want, tap.String())
+		}
+	}
+}
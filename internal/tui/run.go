@@ -4,16 +4,22 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"hooktm/internal/diff"
 	"hooktm/internal/replay"
+	"hooktm/internal/signature"
 	"hooktm/internal/store"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-func Run(ctx context.Context, s *store.Store, defaultTarget string) error {
+// Run starts the UI. signer is used by the re-signing replay key (R); it may
+// be nil, in which case R reports that no secrets are configured.
+func Run(ctx context.Context, s *store.Store, defaultTarget string, signer *signature.Signer) error {
 	m := newModel(ctx, s, defaultTarget)
+	m.signer = signer
 	p := tea.NewProgram(m, tea.WithContext(ctx))
 	_, err := p.Run()
 	if err != nil {
@@ -26,13 +32,32 @@ type model struct {
 	ctx           context.Context
 	store         *store.Store
 	defaultTarget string
+	signer        *signature.Signer
 
 	rows   []store.WebhookSummary
 	sel    int
 	detail *store.Webhook
 
-	search string
-	err    error
+	// filter is the applied search; next continues the list from the last
+	// loaded page, fetched as the selection nears the end.
+	filter      string
+	next        *store.Cursor
+	loadingMore bool
+
+	// showReplays swaps the detail pane for the selected webhook's replay history.
+	showReplays bool
+	replays     []store.Replay
+
+	// marked holds up to two webhook IDs picked with space; d compares them
+	// side by side.
+	marked   []string
+	showDiff bool
+	diff     *diffLoadedMsg
+
+	// searching is set while typing a query after /.
+	searching bool
+	search    string
+	err       error
 
 	width  int
 	height int
@@ -43,7 +68,7 @@ func newModel(ctx context.Context, s *store.Store, defaultTarget string) model {
 }
 
 func (m model) Init() tea.Cmd {
-	return m.loadListCmd("")
+	return m.loadListCmd("", nil)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -52,6 +77,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width, m.height = msg.Width, msg.Height
 		return m, nil
 	case listLoadedMsg:
+		m.next = msg.next
+		if msg.more {
+			m.loadingMore = false
+			m.rows = append(m.rows, msg.rows...)
+			return m, nil
+		}
 		m.rows = msg.rows
 		if m.sel >= len(m.rows) {
 			m.sel = max(0, len(m.rows)-1)
@@ -59,15 +90,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.loadDetailCmd()
 	case detailLoadedMsg:
 		m.detail = &msg.wh
+		if m.showReplays {
+			return m, m.loadReplaysCmd()
+		}
+		return m, nil
+	case diffLoadedMsg:
+		m.diff = &msg
+		m.showDiff = true
+		return m, nil
+	case replaysLoadedMsg:
+		m.replays = msg.replays
 		return m, nil
 	case replayDoneMsg:
-		// refresh detail after replay (not stored, but UX).
+		// Replays are recorded by the engine; refresh the history view.
 		m.err = msg.err
+		if m.showReplays {
+			return m, m.loadReplaysCmd()
+		}
 		return m, nil
 	case errMsg:
 		m.err = msg.err
 		return m, nil
 	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
@@ -79,79 +126,156 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "down", "j":
 			if m.sel < len(m.rows)-1 {
 				m.sel++
+				if m.next != nil && !m.loadingMore && m.sel >= len(m.rows)-listPrefetch {
+					m.loadingMore = true
+					return m, tea.Batch(m.loadDetailCmd(), m.loadListCmd(m.filter, m.next))
+				}
 				return m, m.loadDetailCmd()
 			}
 		case "r":
-			return m, m.replaySelectedCmd()
-		case "/":
-			// Clear search prompt; collect with simple input mode via m.search as buffer.
-			m.search = ""
-			return m, nil
-		case "enter":
-			// If search buffer non-empty, apply it.
-			if strings.TrimSpace(m.search) != "" {
-				return m, m.loadListCmd(strings.TrimSpace(m.search))
+			return m, m.replaySelectedCmd(false)
+		case "R":
+			return m, m.replaySelectedCmd(true)
+		case "h":
+			m.showReplays = !m.showReplays
+			if m.showReplays {
+				return m, m.loadReplaysCmd()
 			}
-		case "backspace":
-			if len(m.search) > 0 {
-				m.search = m.search[:len(m.search)-1]
+			return m, nil
+		case " ":
+			if len(m.rows) > 0 {
+				m.marked = toggleMark(m.marked, m.rows[m.sel].ID)
 			}
 			return m, nil
-		default:
-			// naive search input: type any printable chars into search buffer.
-			if len(msg.Runes) == 1 && msg.Runes[0] >= 32 && msg.Runes[0] != 127 {
-				m.search += string(msg.Runes[0])
+		case "d":
+			if m.showDiff {
+				m.showDiff = false
+				return m, nil
+			}
+			if len(m.marked) != 2 {
+				m.err = fmt.Errorf("mark two webhooks with space to diff them")
 				return m, nil
 			}
+			m.err = nil
+			return m, m.loadDiffCmd(m.marked[0], m.marked[1])
+		case "esc":
+			m.showDiff = false
+			return m, nil
+		case "/":
+			// Every key goes to the query until Enter or Esc.
+			m.searching = true
+			m.search = ""
+			return m, nil
+		}
+	}
+	return m, nil
+}
+
+// updateSearch edits the query typed after /. Enter applies it (an empty
+// query lists everything again); Esc cancels.
+func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+		m.filter = strings.TrimSpace(m.search)
+		m.sel = 0
+		return m, m.loadListCmd(m.filter, nil)
+	case tea.KeyEsc:
+		m.searching = false
+		m.search = ""
+	case tea.KeyBackspace:
+		if len(m.search) > 0 {
+			m.search = m.search[:len(m.search)-1]
 		}
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyRunes, tea.KeySpace:
+		m.search += string(msg.Runes)
 	}
 	return m, nil
 }
 
+// toggleMark adds or removes id; marking a third webhook drops the oldest.
+func toggleMark(marked []string, id string) []string {
+	for i, mid := range marked {
+		if mid == id {
+			return append(marked[:i:i], marked[i+1:]...)
+		}
+	}
+	if len(marked) == 2 {
+		marked = marked[1:]
+	}
+	return append(marked[:len(marked):len(marked)], id)
+}
+
 func (m model) View() string {
 	title := lipgloss.NewStyle().Bold(true).Render("HookTM")
 	header := fmt.Sprintf("%s  target=%s", title, emptyTo(m.defaultTarget, "(none)"))
 	if m.err != nil {
 		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("error: "+m.err.Error())
 	}
-	if strings.TrimSpace(m.search) != "" {
-		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Render("search: "+m.search+" (Enter to apply)")
-	} else {
-		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("keys: j/k move, r replay, / search, q quit")
+	switch {
+	case m.searching:
+		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Render("search: "+m.search+"_ (Enter to apply, Esc to cancel)")
+		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(`words, or field filters: provider = stripe, status >= 500, header.user-agent ~ "curl/*"`)
+	case m.filter != "":
+		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("6")).Render("filter: "+m.filter+" (/ then Enter to clear)")
+	default:
+		header = header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("keys: j/k move, r replay, R replay re-signed, h replay history, space mark, d diff marked, / search, q quit")
+	}
+
+	if m.showDiff && m.diff != nil {
+		return header + "\n\n" + renderDiff(m.diff, max(40, m.width-2), m.height-4)
 	}
 
 	leftW := min(60, max(30, m.width/2))
 	rightW := max(20, m.width-leftW-2)
 
-	left := renderList(m.rows, m.sel, leftW, m.height-4)
-	right := renderDetail(m.detail, rightW, m.height-4)
+	left := renderList(m.rows, m.sel, m.marked, leftW, m.height-4)
+	var right string
+	if m.showReplays {
+		right = renderReplays(m.detail, m.replays, rightW, m.height-4)
+	} else {
+		right = renderDetail(m.detail, rightW, m.height-4)
+	}
 
 	return header + "\n\n" + lipgloss.JoinHorizontal(lipgloss.Top, left, "  ", right)
 }
 
-type listLoadedMsg struct{ rows []store.WebhookSummary }
+// listLoadedMsg carries a page of the list; more marks a continuation page.
+type listLoadedMsg struct {
+	rows []store.WebhookSummary
+	next *store.Cursor
+	more bool
+}
 type detailLoadedMsg struct{ wh store.Webhook }
+type replaysLoadedMsg struct{ replays []store.Replay }
+type diffLoadedMsg struct {
+	a, b store.Webhook
+	res  diff.Result
+}
 type replayDoneMsg struct{ err error }
 type errMsg struct{ err error }
 
-func (m model) loadListCmd(search string) tea.Cmd {
+// listPageSize rows are loaded at a time; the next page is fetched once the
+// selection is within listPrefetch rows of the end.
+const (
+	listPageSize = 200
+	listPrefetch = 20
+)
+
+func (m model) loadListCmd(search string, after *store.Cursor) tea.Cmd {
 	// Capture values to avoid race conditions.
 	ctx := m.ctx
 	st := m.store
 	return func() tea.Msg {
-		var (
-			rows []store.WebhookSummary
-			err  error
-		)
-		if strings.TrimSpace(search) != "" {
-			rows, err = st.SearchSummaries(ctx, search, 200)
-		} else {
-			rows, err = st.ListSummaries(ctx, store.ListFilter{Limit: 200})
-		}
+		// The search box takes the same query language as `list --where`;
+		// plain words are full-text terms.
+		page, err := st.ListPage(ctx, store.ListFilter{Limit: listPageSize, Where: search, After: after})
 		if err != nil {
 			return errMsg{err: err}
 		}
-		return listLoadedMsg{rows: rows}
+		return listLoadedMsg{rows: page.Rows, next: page.Next, more: after != nil}
 	}
 }
 
@@ -177,13 +301,62 @@ func (m model) loadDetailCmd() tea.Cmd {
 	}
 }
 
-func (m model) replaySelectedCmd() tea.Cmd {
+func (m model) loadReplaysCmd() tea.Cmd {
+	// Capture values to avoid race conditions.
+	ctx := m.ctx
+	st := m.store
+	rows := m.rows
+	sel := m.sel
+	return func() tea.Msg {
+		if len(rows) == 0 {
+			return replaysLoadedMsg{}
+		}
+		if sel >= len(rows) {
+			sel = len(rows) - 1
+		}
+		replays, err := st.ListReplays(ctx, rows[sel].ID, 50)
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return replaysLoadedMsg{replays: replays}
+	}
+}
+
+func (m model) loadDiffCmd(idA, idB string) tea.Cmd {
+	// Capture values to avoid race conditions.
+	ctx := m.ctx
+	st := m.store
+	return func() tea.Msg {
+		var (
+			whs   [2]store.Webhook
+			sides [2]diff.Side
+		)
+		for i, id := range []string{idA, idB} {
+			wh, err := st.GetWebhook(ctx, id)
+			if err != nil {
+				return errMsg{err: err}
+			}
+			body := wh.PlainBody()
+			if wh.BodySize > 0 {
+				if body, err = st.ReadBody(ctx, id); err != nil {
+					return errMsg{err: err}
+				}
+			}
+			whs[i], sides[i] = wh, diff.Side{Headers: wh.Headers, Body: body}
+		}
+		return diffLoadedMsg{a: whs[0], b: whs[1], res: diff.Compare(sides[0], sides[1])}
+	}
+}
+
+// replaySelectedCmd replays the selected row, re-signing it when resign is set.
+func (m model) replaySelectedCmd(resign bool) tea.Cmd {
 	// Capture values to avoid race conditions.
 	ctx := m.ctx
 	st := m.store
 	rows := m.rows
 	sel := m.sel
 	target := m.defaultTarget
+	signer := m.signer
 	return func() tea.Msg {
 		if len(rows) == 0 {
 			return replayDoneMsg{err: nil}
@@ -196,24 +369,35 @@ func (m model) replaySelectedCmd() tea.Cmd {
 		}
 		id := rows[sel].ID
 		engine := replay.NewEngine(st)
+		if resign {
+			if signer == nil {
+				return replayDoneMsg{err: fmt.Errorf("re-signing needs secrets in config")}
+			}
+			engine.Signer = signer
+		}
 		_, err := engine.ReplayByID(ctx, id, target, "")
 		return replayDoneMsg{err: err}
 	}
 }
 
-func renderList(rows []store.WebhookSummary, sel, w, h int) string {
+func renderList(rows []store.WebhookSummary, sel int, marked []string, w, h int) string {
 	var b strings.Builder
 	for i, r := range rows {
 		prefix := "  "
 		if i == sel {
 			prefix = "> "
 		}
+		for _, id := range marked {
+			if id == r.ID {
+				prefix = prefix[:1] + "*"
+			}
+		}
 		status := "-"
 		if r.StatusCode != nil {
 			status = fmt.Sprintf("%d", *r.StatusCode)
 		}
 		prov := emptyTo(r.Provider, "unknown")
-		line := fmt.Sprintf("%s%s %s %s [%s/%s] %dms", prefix, r.ID, r.Method, r.Path, prov, status, r.ResponseMS)
+		line := fmt.Sprintf("%s%s %s %s [%s/%s] %dms%s", prefix, r.ID, r.Method, r.Path, prov, status, r.ResponseMS, sigMark(r.SignatureValid))
 		line = truncate(line, w)
 		b.WriteString(line)
 		b.WriteString("\n")
@@ -234,8 +418,19 @@ func renderDetail(wh *store.Webhook, w, h int) string {
 	if strings.TrimSpace(wh.EventType) != "" {
 		b.WriteString(fmt.Sprintf("Event: %s\n", wh.EventType))
 	}
+	if wh.Fault != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("Fault: "+wh.Fault) + "\n")
+	}
 	if strings.TrimSpace(wh.Signature) != "" {
 		b.WriteString(fmt.Sprintf("Signature: %s\n", truncate(wh.Signature, w-12)))
+		switch {
+		case wh.SignatureValid == nil:
+			b.WriteString("  not verified\n")
+		case *wh.SignatureValid:
+			b.WriteString("  ✓ valid\n")
+		default:
+			b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("  ✗ invalid") + "\n")
+		}
 	}
 	b.WriteString("\nHeaders:\n")
 	// only show a few headers
@@ -252,15 +447,111 @@ func renderDetail(wh *store.Webhook, w, h int) string {
 	}
 body:
 	b.WriteString("\nBody:\n")
-	bodyStr := string(wh.Body)
+	bodyStr := string(wh.PlainBody())
+	switch {
+	case wh.BodySize > 0:
+		bodyStr = fmt.Sprintf("(%d bytes, stored in chunks; use `hooktm show %s`)", wh.BodySize, wh.ID)
+	case len(wh.Form) > 0 || len(wh.Attachments) > 0:
+		var fb strings.Builder
+		for _, f := range wh.Form {
+			fb.WriteString(fmt.Sprintf("  %s: %s\n", f.Name, truncate(f.Value, w-6-len(f.Name))))
+		}
+		for _, a := range wh.Attachments {
+			fb.WriteString(fmt.Sprintf("  %s: [%s, %d bytes]\n", a.Field, a.Filename, a.Size))
+		}
+		bodyStr = fb.String()
+	}
 	if len(bodyStr) > 4000 {
 		bodyStr = bodyStr[:4000] + "\n... (truncated)\n"
 	}
 	b.WriteString(bodyStr)
 
+	if wh.ResponseHeaders != nil || len(wh.ResponseBody) > 0 {
+		status := "-"
+		if wh.StatusCode != nil {
+			status = fmt.Sprintf("%d", *wh.StatusCode)
+		}
+		b.WriteString(fmt.Sprintf("\n\nResponse: %s (%dms)\n", status, wh.ResponseMS))
+		if wh.MockRule != "" {
+			b.WriteString(fmt.Sprintf("  mock rule: %s\n", truncate(wh.MockRule, w-14)))
+		}
+		if len(wh.Deliveries) > 1 {
+			for _, d := range wh.Deliveries {
+				outcome := d.Error
+				if d.StatusCode != nil {
+					outcome = fmt.Sprintf("%d", *d.StatusCode)
+				}
+				mark := " "
+				if d.Primary {
+					mark = "*"
+				}
+				b.WriteString(truncate(fmt.Sprintf("  %s %s %s %dms", mark, d.TargetURL, outcome, d.DurationMS), w) + "\n")
+			}
+		}
+		if ct := firstHeader(wh.ResponseHeaders, "Content-Type"); ct != "" {
+			b.WriteString(fmt.Sprintf("  Content-Type: %s\n", truncate(ct, w-16)))
+		}
+		respStr := string(wh.ResponseBody)
+		if len(respStr) > 2000 {
+			respStr = respStr[:2000] + "\n... (truncated)\n"
+		} else if wh.ResponseTruncated {
+			respStr += "\n... (truncated at capture)\n"
+		}
+		b.WriteString(respStr)
+	}
+
 	return lipgloss.NewStyle().Width(w).Height(h).Border(lipgloss.RoundedBorder()).Render(b.String())
 }
 
+func renderReplays(wh *store.Webhook, replays []store.Replay, w, h int) string {
+	style := lipgloss.NewStyle().Width(w).Height(h).Border(lipgloss.RoundedBorder())
+	if wh == nil || wh.ID == "" {
+		return style.Render("No webhooks captured yet.")
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Replays of %s\n\n", wh.ID))
+	if len(replays) == 0 {
+		b.WriteString("Not replayed yet. Press r to replay.\n")
+		return style.Render(b.String())
+	}
+	for _, r := range replays {
+		status := "-"
+		if r.StatusCode != nil {
+			status = fmt.Sprintf("%d", *r.StatusCode)
+		}
+		ts := time.UnixMilli(r.CreatedAt).Local().Format("2006-01-02 15:04:05")
+		target := r.TargetURL
+		if r.Method != "" && r.Method != wh.Method {
+			target = r.Method + " " + target
+		}
+		b.WriteString(truncate(fmt.Sprintf("%s [%s] %dms → %s", ts, status, r.DurationMS, target), w))
+		b.WriteString("\n")
+		if r.Patch != "" {
+			b.WriteString(truncate("  patch: "+r.Patch, w))
+			b.WriteString("\n")
+		}
+		if r.Error != "" {
+			b.WriteString(truncate("  error: "+r.Error, w))
+			b.WriteString("\n")
+		} else if len(r.ResponseBody) > 0 {
+			b.WriteString(truncate("  body: "+strings.Join(strings.Fields(string(r.ResponseBody)), " "), w))
+			b.WriteString("\n")
+		}
+	}
+	return style.Render(b.String())
+}
+
+func sigMark(valid *bool) string {
+	switch {
+	case valid == nil:
+		return ""
+	case *valid:
+		return " ✓"
+	default:
+		return " ✗"
+	}
+}
+
 func truncate(s string, maxW int) string {
 	if maxW <= 0 {
 		return ""
@@ -275,9 +566,68 @@ func truncate(s string, maxW int) string {
 	return string(rs[:maxW-1]) + "…"
 }
 
+func firstHeader(h map[string][]string, k string) string {
+	for hk, vs := range h {
+		if strings.EqualFold(hk, k) && len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	return ""
+}
+
 func emptyTo(s, v string) string {
 	if strings.TrimSpace(s) == "" {
 		return v
 	}
 	return s
 }
+
+// renderDiff shows the changes between two marked webhooks side by side:
+// old values on the left, new values on the right.
+func renderDiff(d *diffLoadedMsg, w, h int) string {
+	colW := max(10, (w-3)/2)
+	var left, right []string
+	add := func(l, r string) {
+		left = append(left, truncate(l, colW))
+		right = append(right, truncate(r, colW))
+	}
+	add(fmt.Sprintf("A %s %s %s", d.a.ID, d.a.Method, d.a.Path), fmt.Sprintf("B %s %s %s", d.b.ID, d.b.Method, d.b.Path))
+	add(emptyTo(strings.TrimSpace(d.a.Provider+" "+d.a.EventType), "unknown"), emptyTo(strings.TrimSpace(d.b.Provider+" "+d.b.EventType), "unknown"))
+	for _, sec := range []struct {
+		title   string
+		changes []diff.Change
+	}{{"Headers", d.res.Headers}, {"Body", d.res.Body}} {
+		add("", "")
+		add(sec.title+":", sec.title+":")
+		if len(sec.changes) == 0 {
+			add("  (no differences)", "  (no differences)")
+		}
+		for _, c := range sec.changes {
+			path := emptyTo(c.Path, "(body)")
+			switch c.Kind {
+			case diff.Added:
+				add("", "+ "+path+": "+diff.Format(c.New))
+			case diff.Removed:
+				add("- "+path+": "+diff.Format(c.Old), "")
+			default:
+				add("~ "+path+": "+diff.Format(c.Old), "~ "+path+": "+diff.Format(c.New))
+			}
+		}
+	}
+	if len(left) > h {
+		left, right = left[:h], right[:h]
+	}
+
+	old := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	cur := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	for i := range left {
+		if strings.HasPrefix(left[i], "- ") || strings.HasPrefix(left[i], "~ ") {
+			left[i] = old.Render(left[i])
+		}
+		if strings.HasPrefix(right[i], "+ ") || strings.HasPrefix(right[i], "~ ") {
+			right[i] = cur.Render(right[i])
+		}
+	}
+	box := lipgloss.NewStyle().Width(colW).Height(h).Border(lipgloss.RoundedBorder())
+	return lipgloss.JoinHorizontal(lipgloss.Top, box.Render(strings.Join(left, "\n")), " ", box.Render(strings.Join(right, "\n")))
+}